@@ -0,0 +1,17 @@
+// Cliente mínimo para la API /api/v1 con token Bearer.
+// El token se obtiene en login.html y se guarda en localStorage.
+const API_HOST = `${window.location.origin}/api/v1`;
+const TOKEN_KEY = 'carrito_api_token';
+
+async function apiFetch(url, options = {}) {
+    const token = localStorage.getItem(TOKEN_KEY);
+    const headers = { ...(options.headers || {}) };
+    if (token) headers['Authorization'] = `Bearer ${token}`;
+
+    const response = await fetch(url, { ...options, headers });
+    if (response.status === 401) {
+        localStorage.removeItem(TOKEN_KEY);
+        window.location.href = 'login.html';
+    }
+    return response;
+}
@@ -1,4 +1,4 @@
-// La constante API_HOST se define en el HTML
+// API_HOST y apiFetch se definen en js/api.js
 const PRODUCTS_URL = `${API_HOST}/products`;
 const PRODUCT_URL = `${API_HOST}/product`;
 
@@ -22,7 +22,7 @@ async function handleCreation(e, url, data, msgElement) {
     if (data.total !== undefined) data.total = String(data.total);
 
     try {
-        const response = await fetch(url, {
+        const response = await apiFetch(url, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify(data),
@@ -56,7 +56,7 @@ productForm.addEventListener('submit', (e) => {
 
 async function fetchEntities(url) {
     try {
-        const response = await fetch(url);
+        const response = await apiFetch(url);
         if (!response.ok) throw new Error(`Error HTTP: ${response.status}`);
         const data = await response.json();
         return Array.isArray(data) ? data : [];
@@ -74,7 +74,7 @@ async function loadAndRenderEntities() {
 
 async function deleteProduct(productId) {
     try {
-        const response = await fetch(`${PRODUCT_URL}/${productId}`, {
+        const response = await apiFetch(`${PRODUCT_URL}/${productId}`, {
             method: 'DELETE',       
         });
 
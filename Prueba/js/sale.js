@@ -1,4 +1,4 @@
-// La constante API_HOST se define en el HTML
+// API_HOST y apiFetch se definen en js/api.js
 const SALES_URL = `${API_HOST}/sales`;
 const SALE_URL = `${API_HOST}/sale`;
 const PRODUCT_URL = `${API_HOST}/products`;
@@ -16,20 +16,23 @@ function displayMessage(element, msg, isSuccess) {
 async function handleCreation(e, url, data, msgElement) {
     e.preventDefault();
 
-    if (data.cantidad !== undefined) data.cantidad = parseInt(data.cantidad);
-    if (data.id_producto !== undefined) data.id_producto = parseInt(data.id_producto);
     if (data.id_usuario !== undefined) data.id_usuario = parseInt(data.id_usuario);
-    if (data.fecha !== undefined) data.fecha = new Date(data.fecha);
-    
+    if (data.items !== undefined) {
+        data.items = data.items.map(i => ({
+            id_producto: parseInt(i.id_producto),
+            cantidad: parseInt(i.cantidad),
+        }));
+    }
+
     try {
-        const response = await fetch(url, {
+        const response = await apiFetch(url, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify(data),
         });
 
         if (response.status === 201) {
-            displayMessage(msgElement, 'Producto creado correctamente!', true);
+            displayMessage(msgElement, 'Pedido creado correctamente!', true);
             e.target.reset();
             await loadAndRenderEntities();
         } else {
@@ -43,19 +46,20 @@ async function handleCreation(e, url, data, msgElement) {
 }
 
 saleForm.addEventListener('submit', (e) => {
+    // El precio y el total los calcula el servidor a partir del producto
     const data = {
-        id_producto: document.getElementById('select-product').value,
         id_usuario: document.getElementById('buyer-Select').value,
-        cantidad: document.getElementById('productStock').value,
-        fecha: document.getElementById('purchaseDate').value,
-        total: document.getElementById('totalAmount').textContent.replace('$', ''),
+        items: [{
+            id_producto: document.getElementById('select-product').value,
+            cantidad: document.getElementById('productStock').value,
+        }],
     };
     handleCreation(e, SALES_URL, data, saleMessage);
 });
 
 async function fetchEntities(url) {
     try {
-        const response = await fetch(url);
+        const response = await apiFetch(url);
         if (!response.ok) throw new Error(`Error HTTP: ${response.status}`);
         const data = await response.json();
         return Array.isArray(data) ? data : [];
@@ -66,24 +70,25 @@ async function fetchEntities(url) {
 }
 
 async function loadAndRenderEntities() {
-    salesList.innerHTML = '<p>Cargando ventas...</p>';
+    salesList.innerHTML = '<p>Cargando pedidos...</p>';
     const sales = await fetchEntities(SALES_URL);
     renderSales(sales);
 }
 
-async function deleteSale(saleId) {
+// DELETE cancela el pedido: devuelve stock y, si estaba pagado, el dinero
+async function cancelSale(saleId) {
     try {
-        const response = await fetch(`${SALE_URL}/${saleId}`, {
+        const response = await apiFetch(`${SALE_URL}/${saleId}`, {
             method: 'DELETE',
         });
 
         if (response.ok) {
             await loadAndRenderEntities();
         } else {
-            console.error(`Error al eliminar venta con ID ${saleId}:`, await response.text());
+            console.error(`Error al cancelar pedido con ID ${saleId}:`, await response.text());
         }
     } catch (error) {
-        console.error('Error de eliminación:', error);
+        console.error('Error de cancelación:', error);
     }
 }
 
@@ -91,7 +96,7 @@ function renderSales(sales) {
     const list = Array.isArray(sales) ? sales : [];
     salesList.innerHTML = '';
     if (list.length === 0) {
-        salesList.innerHTML = '<p>No hay ventas registradas.</p>';
+        salesList.innerHTML = '<p>No hay pedidos registrados.</p>';
         return;
     }
     
@@ -101,20 +106,24 @@ function renderSales(sales) {
         item.innerHTML = `
             <div class="entity-info">
                 <div class="key-data">
-                    <strong>ID: ${v.id_venta}-</strong>
-                    <strong>Producto: ${v.id_producto}</strong>
+                    <strong>Pedido: ${v.id_pedido}-</strong>
+                    <strong>${(v.items || []).map(i => `${i.nombre_producto} x${i.cantidad}`).join(', ')}</strong>
                 </div>
                 <div class="secondary-data">
                     <p>id_usuario: ${v.id_usuario}</p>
+                    <p>estado: ${v.estado}</p>
                     <p>total: ${v.total}</p>
+                    <p>reembolsado: ${v.total_reembolsado}</p>
                 </div>
             </div>
-            <button class="delete-btn">Eliminar</button>
+            ${['pendiente', 'esperando_pago', 'pagado'].includes(v.estado) ? '<button class="delete-btn">Cancelar</button>' : ''}
         `;
-        const deleteButton = item.querySelector('.delete-btn');
-        deleteButton.addEventListener('click', () => {
-            deleteSale(v.id_venta);
-        });
+        const cancelButton = item.querySelector('.delete-btn');
+        if (cancelButton) {
+            cancelButton.addEventListener('click', () => {
+                cancelSale(v.id_pedido);
+            });
+        }
 
         salesList.appendChild(item);
     });
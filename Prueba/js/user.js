@@ -1,4 +1,4 @@
-// La constante API_HOST se define en el HTML
+// API_HOST y apiFetch se definen en js/api.js
 const USERS_URL = `${API_HOST}/users`;
 const USER_URL = `${API_HOST}/user`;
 
@@ -15,7 +15,7 @@ async function handleCreation(e, url, data, msgElement) {
     e.preventDefault();
 
     try {
-        const response = await fetch(url, {
+        const response = await apiFetch(url, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify(data),
@@ -46,7 +46,7 @@ userForm.addEventListener('submit', (e) => {
 
 async function fetchEntities(url) {
     try {
-        const response = await fetch(url);
+        const response = await apiFetch(url);
         if (!response.ok) throw new Error(`Error HTTP: ${response.status}`);
         const data = await response.json();
         return Array.isArray(data) ? data : [];
@@ -64,7 +64,7 @@ async function loadAndRenderEntities() {
 
 async function deleteUser(userId) {
     try {
-        const response = await fetch(`${USER_URL}/${userId}`, {
+        const response = await apiFetch(`${USER_URL}/${userId}`, {
             method: 'DELETE',
         });
 
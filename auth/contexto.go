@@ -0,0 +1,27 @@
+// Package auth guarda en el contexto de la request los datos de la sesión
+// (usuario actual) para que handlers y vistas los consulten sin re-leer la cookie.
+package auth
+
+import (
+	"context"
+
+	sqlc "carrito.com/db/sqlc"
+)
+
+type claveContexto int
+
+const (
+	claveUsuario claveContexto = iota
+	claveCSRF
+)
+
+// ConUsuario devuelve un contexto hijo con el usuario autenticado
+func ConUsuario(ctx context.Context, usuario sqlc.Usuario) context.Context {
+	return context.WithValue(ctx, claveUsuario, usuario)
+}
+
+// UsuarioActual obtiene el usuario que cargó el middleware de autenticación
+func UsuarioActual(ctx context.Context) (sqlc.Usuario, bool) {
+	usuario, ok := ctx.Value(claveUsuario).(sqlc.Usuario)
+	return usuario, ok
+}
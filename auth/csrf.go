@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+)
+
+const (
+	// CSRFHeader es el header que HTMX envía gracias al hx-headers del layout
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFCampo es el nombre del campo oculto para formularios sin HTMX
+	CSRFCampo = "csrf_token"
+)
+
+// ConCSRF guarda en el contexto el token CSRF de la sesión actual
+func ConCSRF(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, claveCSRF, token)
+}
+
+// TokenCSRF devuelve el token CSRF de la sesión, o "" si no hay sesión
+func TokenCSRF(ctx context.Context) string {
+	token, _ := ctx.Value(claveCSRF).(string)
+	return token
+}
+
+// CSRFValido compara en tiempo constante el token recibido con el de la sesión
+func CSRFValido(ctx context.Context, enviado string) bool {
+	esperado := TokenCSRF(ctx)
+	if esperado == "" || enviado == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(esperado), []byte(enviado)) == 1
+}
+
+// CSRFHeaders arma el JSON para el atributo hx-headers del <body>
+func CSRFHeaders(ctx context.Context) string {
+	token := TokenCSRF(ctx)
+	if token == "" {
+		return "{}"
+	}
+	b, _ := json.Marshal(map[string]string{CSRFHeader: token})
+	return string(b)
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "golang.org/x/crypto/bcrypt"
+
+// PasswordMinLen es el largo mínimo aceptado al registrar o cambiar la contraseña
+const PasswordMinLen = 8
+
+// hashFicticio se usa para comparar cuando el email no existe, de modo que
+// el login tarde lo mismo haya o no usuario.
+var hashFicticio, _ = bcrypt.GenerateFromPassword([]byte("carrito-hash-ficticio"), bcrypt.DefaultCost)
+
+// HashPassword genera el hash bcrypt que se guarda en usuario.password_hash
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// VerificarPassword compara en tiempo constante la password con el hash guardado.
+// Un hash vacío (usuarios sin contraseña) nunca es válido.
+func VerificarPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(hashFicticio, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
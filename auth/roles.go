@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+
+	sqlc "carrito.com/db/sqlc"
+)
+
+// Roles posibles de usuario.rol (ver CHECK en schema.sql)
+const (
+	RolCliente = "cliente"
+	RolStaff   = "staff"
+	RolAdmin   = "admin"
+)
+
+// Permiso agrupa las rutas de administración que protege RequirePermiso
+type Permiso string
+
+const (
+	PermisoProductos Permiso = "productos" // alta, baja y edición de productos
+	PermisoUsuarios  Permiso = "usuarios"  // gestión de cuentas y roles
+	PermisoVentas    Permiso = "ventas"    // administración de ventas
+	PermisoMonedas   Permiso = "monedas"   // monedas y tipos de cambio
+	PermisoImpuestos Permiso = "impuestos" // alícuotas de impuestos
+)
+
+var permisosPorRol = map[string][]Permiso{
+	RolStaff: {PermisoProductos, PermisoVentas},
+	RolAdmin: {PermisoProductos, PermisoUsuarios, PermisoVentas, PermisoMonedas, PermisoImpuestos},
+}
+
+// RolValido indica si el string corresponde a un rol conocido
+func RolValido(rol string) bool {
+	return rol == RolCliente || rol == RolStaff || rol == RolAdmin
+}
+
+// Tiene indica si el rol del usuario incluye el permiso
+func Tiene(usuario sqlc.Usuario, permiso Permiso) bool {
+	for _, p := range permisosPorRol[usuario.Rol] {
+		if p == permiso {
+			return true
+		}
+	}
+	return false
+}
+
+// Puede es la versión para vistas: consulta el usuario del contexto
+func Puede(ctx context.Context, permiso Permiso) bool {
+	usuario, ok := UsuarioActual(ctx)
+	return ok && Tiene(usuario, permiso)
+}
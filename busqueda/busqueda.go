@@ -0,0 +1,84 @@
+// Package busqueda arma las consultas de texto completo de Postgres a partir
+// de lo que escribe el usuario y separa los fragmentos resaltados por
+// ts_headline para mostrarlos.
+package busqueda
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// maxPalabras corta las búsquedas demasiado largas
+const maxPalabras = 8
+
+// Marcas con que BuscarProductos rodea las palabras encontradas. Son
+// caracteres de control que no aparecen en los textos de los productos.
+const (
+	inicioMarca = "\x01"
+	finMarca    = "\x02"
+)
+
+// Consulta pasa el texto del buscador a la sintaxis de to_tsquery: las
+// palabras se unen con & y la última se busca como prefijo, porque mientras
+// se escribe suele estar incompleta ("tecl" encuentra "teclado"). Solo quedan
+// letras y números, así el usuario no puede armar operadores. Devuelve "" si
+// no hay nada para buscar.
+func Consulta(texto string) string {
+	palabras := strings.FieldsFunc(texto, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(palabras) == 0 {
+		return ""
+	}
+	if len(palabras) > maxPalabras {
+		palabras = palabras[:maxPalabras]
+	}
+	palabras[len(palabras)-1] += ":*"
+	return strings.Join(palabras, " & ")
+}
+
+// Tramo es un pedazo de un texto resaltado; Marcado indica si es una de las
+// palabras encontradas
+type Tramo struct {
+	Texto   string
+	Marcado bool
+}
+
+// Tramos separa un texto devuelto por BuscarProductos en pedazos marcados y
+// sin marcar, para que la vista los escape y resalte
+func Tramos(s string) []Tramo {
+	var tramos []Tramo
+	for s != "" {
+		i := strings.Index(s, inicioMarca)
+		if i < 0 {
+			tramos = append(tramos, Tramo{Texto: s})
+			break
+		}
+		if i > 0 {
+			tramos = append(tramos, Tramo{Texto: s[:i]})
+		}
+		s = s[i+len(inicioMarca):]
+		j := strings.Index(s, finMarca)
+		if j < 0 {
+			j = len(s)
+		}
+		tramos = append(tramos, Tramo{Texto: s[:j], Marcado: true})
+		s = strings.TrimPrefix(s[j:], finMarca)
+	}
+	return tramos
+}
+
+// HTML devuelve el texto escapado con las palabras encontradas entre <mark>,
+// para las respuestas JSON
+func HTML(s string) string {
+	var b strings.Builder
+	for _, t := range Tramos(s) {
+		if t.Marcado {
+			b.WriteString("<mark>" + html.EscapeString(t.Texto) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(t.Texto))
+		}
+	}
+	return b.String()
+}
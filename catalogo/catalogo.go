@@ -0,0 +1,161 @@
+// Package catalogo lee los filtros de la lista de productos de la URL y los
+// vuelve a escribir, así una vista filtrada se puede compartir o guardar, arma
+// la consulta de la lista con su orden y arma los conteos por categoría del
+// panel de filtros.
+package catalogo
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"carrito.com/categorias"
+	"carrito.com/dinero"
+)
+
+// Tamaño de las páginas de la lista: TamPagina si no se pide otro con
+// limite, y nunca más de MaxPagina
+const (
+	TamPagina = 24
+	MaxPagina = 100
+)
+
+// Filtro es el estado de la lista de productos
+type Filtro struct {
+	Q string // texto del buscador
+	// Rango de precios en la moneda de la sesión; 0 es sin límite
+	Min, Max   dinero.Monto
+	Categorias []string // slugs; cada una incluye sus subcategorías
+	ConStock   bool
+	Orden      string
+	// Página: Despues es el id del último producto de la anterior (0 para
+	// la primera) y Limite la cantidad de productos
+	Despues int32
+	Limite  int32
+}
+
+// Leer arma el filtro a partir de los parámetros de la URL:
+// q, min, max, categoria (repetible), stock=1, sort, despues y limite. Los
+// valores que no se entienden se ignoran en vez de dar error, porque vienen
+// de links guardados.
+func Leer(v url.Values) Filtro {
+	f := Filtro{
+		Q:        strings.TrimSpace(v.Get("q")),
+		ConStock: v.Get("stock") != "",
+		Orden:    v.Get("sort"),
+		Limite:   TamPagina,
+	}
+	if n, err := strconv.Atoi(v.Get("limite")); err == nil && n > 0 {
+		f.Limite = int32(min(n, MaxPagina))
+	}
+	if id, err := strconv.Atoi(v.Get("despues")); err == nil && id > 0 {
+		f.Despues = int32(id)
+	}
+	if !ordenValido(f.Orden) {
+		f.Orden = ""
+	}
+	if m, err := dinero.Parse(v.Get("min")); err == nil && m > 0 {
+		f.Min = m
+	}
+	if m, err := dinero.Parse(v.Get("max")); err == nil && m > 0 {
+		f.Max = m
+	}
+	for _, slug := range v["categoria"] {
+		if slug != "" && !slices.Contains(f.Categorias, slug) {
+			f.Categorias = append(f.Categorias, slug)
+		}
+	}
+	return f
+}
+
+// Valores devuelve el filtro como parámetros de URL, sin los que están vacíos
+func (f Filtro) Valores() url.Values {
+	v := url.Values{}
+	if f.Q != "" {
+		v.Set("q", f.Q)
+	}
+	if f.Min > 0 {
+		v.Set("min", f.Min.String())
+	}
+	if f.Max > 0 {
+		v.Set("max", f.Max.String())
+	}
+	for _, slug := range f.Categorias {
+		v.Add("categoria", slug)
+	}
+	if f.ConStock {
+		v.Set("stock", "1")
+	}
+	if f.Orden != "" {
+		v.Set("sort", f.Orden)
+	}
+	if f.Despues > 0 {
+		v.Set("despues", strconv.Itoa(int(f.Despues)))
+	}
+	if f.Limite > 0 && f.Limite != TamPagina {
+		v.Set("limite", strconv.Itoa(int(f.Limite)))
+	}
+	return v
+}
+
+// Siguiente devuelve la URL de la página que sigue al producto id, con los
+// mismos filtros
+func (f Filtro) Siguiente(ruta string, id int32) string {
+	f.Despues = id
+	return ruta + "?" + f.Valores().Encode()
+}
+
+// Activo indica si hay algún filtro aplicado además del orden
+func (f Filtro) Activo() bool {
+	return f.Q != "" || f.Min > 0 || f.Max > 0 || len(f.Categorias) > 0 || f.ConStock
+}
+
+// TieneCategoria indica si la categoría está elegida
+func (f Filtro) TieneCategoria(slug string) bool {
+	return slices.Contains(f.Categorias, slug)
+}
+
+// IDs devuelve las categorías elegidas con todas sus subcategorías. Los slugs
+// que ya no existen no suman ninguna.
+func (f Filtro) IDs(arbol *categorias.Arbol) []int32 {
+	ids := []int32{}
+	for _, slug := range f.Categorias {
+		c, ok := arbol.BuscarSlug(slug)
+		if !ok {
+			continue
+		}
+		for _, id := range arbol.Descendientes(c.IDCategoria) {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Faceta es una categoría del panel de filtros con la cantidad de productos
+// que quedan al elegirla, contando los de sus subcategorías
+type Faceta struct {
+	categorias.Nodo
+	Cantidad int64
+}
+
+// Facetas suma los conteos por categoría (cantidades, indexado por id) hacia
+// sus ancestros y devuelve el árbol en orden, sin las categorías vacías salvo
+// que estén elegidas
+func Facetas(arbol *categorias.Arbol, cantidades map[int32]int64, f Filtro) []Faceta {
+	totales := map[int32]int64{}
+	for id, n := range cantidades {
+		for _, c := range arbol.Ruta(id) {
+			totales[c.IDCategoria] += n
+		}
+	}
+	var facetas []Faceta
+	for _, n := range arbol.Lista() {
+		if totales[n.IDCategoria] > 0 || f.TieneCategoria(n.Slug) {
+			facetas = append(facetas, Faceta{Nodo: n, Cantidad: totales[n.IDCategoria]})
+		}
+	}
+	return facetas
+}
@@ -0,0 +1,173 @@
+package catalogo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"github.com/lib/pq"
+)
+
+// Consulta es una página de la lista de productos con el filtro ya resuelto:
+// los precios en la moneda base y las categorías en ids con sus
+// subcategorías. La arman la tienda, el admin y la API.
+type Consulta struct {
+	Archivados bool
+	// Rango de precios en la moneda base; 0 es sin límite
+	PrecioMin, PrecioMax dinero.Monto
+	PorCategoria         bool
+	IDs                  []int32
+	ConStock             bool
+	Texto                string // to_tsquery de busqueda.Consulta; "" sin búsqueda
+	Orden                string // campo-dirección de la lista blanca, ver Ordenes
+	// Cursor de la página: id del último producto de la anterior (0 para la
+	// primera) y cuántos productos traer
+	Despues int32
+	Limite  int32
+}
+
+// ErrCursor indica que el producto de Despues ya no existe: sin él no se sabe
+// dónde empieza la página, así que hay que volver a pedir la primera
+var ErrCursor = errors.New("el producto de despues ya no existe: volvé a pedir la primera página")
+
+// Resultado es un producto de la lista. Buscando trae también su relevancia y
+// el nombre y la descripción con las palabras encontradas marcadas como
+// espera busqueda.Tramos; sin búsqueda esos campos quedan vacíos.
+type Resultado struct {
+	sqlc.Producto
+	Rango                float32
+	NombreResaltado      string
+	DescripcionResaltada string
+}
+
+// columnas son las de sqlc.Producto, en el orden en que Listar las lee
+const columnas = `p.id_producto, p.nombre_producto, p.descripcion, p.precio, p.moneda, p.stock,
+    p.categoria, p.id_categoria, p.imagen, p.peso, p.archivado`
+
+// resaltados marcan las palabras encontradas entre \x01 y \x02
+const resaltados = `ts_rank(documento_producto(p.nombre_producto, p.categoria, p.descripcion), q)::real,
+    ts_headline('es', p.nombre_producto, q, 'StartSel="' || chr(1) || '", StopSel="' || chr(2) || '", HighlightAll=true),
+    ts_headline('es', p.descripcion, q, 'StartSel="' || chr(1) || '", StopSel="' || chr(2) || '", MaxWords=30, MinWords=15')`
+
+// ventas son las unidades vendidas de cada producto, sin los pedidos
+// cancelados
+const ventas = `LEFT JOIN (
+    SELECT i.id_producto, SUM(i.cantidad) AS cantidad
+    FROM pedido_item i JOIN pedido pe ON pe.id_pedido = i.id_pedido
+    WHERE pe.estado <> 'cancelado'
+    GROUP BY i.id_producto
+) v ON v.id_producto = p.id_producto`
+
+// SQL arma la consulta y sus argumentos. Los valores siempre van como
+// argumentos; al texto solo llegan las expresiones de la lista blanca de
+// campos.
+//
+// Pagina por cursor: la página siguiente empieza después del producto
+// Despues en el mismo orden, que siempre termina en id_producto para que no
+// haya empates entre páginas.
+func (c Consulta) SQL() (string, []any) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	campo, desc := orden(c.Orden, c.Texto != "")
+
+	desde := "producto p JOIN moneda m ON m.codigo = p.moneda"
+	if c.Texto != "" {
+		desde += " CROSS JOIN to_tsquery('es', " + arg(c.Texto) + ") q"
+	}
+	if campo.ventas {
+		desde += "\n" + ventas
+	}
+
+	where := []string{"p.archivado = " + arg(c.Archivados)}
+	if c.Texto != "" {
+		where = append(where, "documento_producto(p.nombre_producto, p.categoria, p.descripcion) @@ q")
+	}
+	if c.PrecioMin > 0 {
+		where = append(where, "p.precio * m.tasa >= "+arg(c.PrecioMin)+"::numeric")
+	}
+	if c.PrecioMax > 0 {
+		where = append(where, "p.precio * m.tasa <= "+arg(c.PrecioMax)+"::numeric")
+	}
+	if c.PorCategoria {
+		where = append(where, "p.id_categoria = ANY("+arg(pq.Array(c.IDs))+"::int[])")
+	}
+	if c.ConStock {
+		where = append(where, "p.stock > 0")
+	}
+	comparar, dir := ">", "ASC"
+	if desc {
+		comparar, dir = "<", "DESC"
+	}
+	if c.Despues > 0 {
+		// El cursor se compara con los valores actuales del producto, así que
+		// la página siguiente es correcta aunque su precio o stock cambien o
+		// se haya archivado. Si se borró, Listar devuelve ErrCursor.
+		where = append(where, "("+campo.expr+", p.id_producto) "+comparar+
+			" (SELECT "+campo.expr+", p.id_producto FROM "+desde+" WHERE p.id_producto = "+arg(c.Despues)+")")
+	}
+
+	var sql strings.Builder
+	sql.WriteString("SELECT " + columnas + ",\n    ")
+	if c.Texto != "" {
+		sql.WriteString(resaltados)
+	} else {
+		sql.WriteString("0::real, '', ''")
+	}
+	sql.WriteString("\nFROM " + desde)
+	sql.WriteString("\nWHERE " + strings.Join(where, "\n    AND "))
+	sql.WriteString("\nORDER BY " + campo.expr + " " + dir + ", p.id_producto " + dir)
+	sql.WriteString("\nLIMIT " + arg(c.Limite))
+	return sql.String(), args
+}
+
+// Listar ejecuta la consulta. Con cursor primero verifica que el producto
+// exista: si no, la comparación da NULL y la página vendría vacía como si no
+// hubiera más productos.
+func (c Consulta) Listar(ctx context.Context, db sqlc.DBTX) ([]Resultado, error) {
+	if c.Despues > 0 {
+		var existe bool
+		err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM producto WHERE id_producto = $1)", c.Despues).Scan(&existe)
+		if err != nil {
+			return nil, err
+		}
+		if !existe {
+			return nil, ErrCursor
+		}
+	}
+	sql, args := c.SQL()
+	rows, err := db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resultados := []Resultado{}
+	for rows.Next() {
+		var r Resultado
+		if err := rows.Scan(
+			&r.IDProducto,
+			&r.NombreProducto,
+			&r.Descripcion,
+			&r.Precio,
+			&r.Moneda,
+			&r.Stock,
+			&r.Categoria,
+			&r.IDCategoria,
+			&r.Imagen,
+			&r.Peso,
+			&r.Archivado,
+			&r.Rango,
+			&r.NombreResaltado,
+			&r.DescripcionResaltada,
+		); err != nil {
+			return nil, err
+		}
+		resultados = append(resultados, r)
+	}
+	return resultados, rows.Err()
+}
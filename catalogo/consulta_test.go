@@ -0,0 +1,116 @@
+package catalogo
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"carrito.com/dinero"
+	"github.com/lib/pq"
+)
+
+func TestConsultaSQL(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		consulta Consulta
+		contiene []string
+		args     []any
+	}{
+		{
+			nombre:   "primera página por nombre",
+			consulta: Consulta{Limite: 21},
+			contiene: []string{
+				"WHERE p.archivado = $1\n",
+				"ORDER BY p.nombre_producto ASC, p.id_producto ASC\nLIMIT $2",
+				"0::real, '', ''",
+			},
+			args: []any{false, int32(21)},
+		},
+		{
+			nombre:   "archivados con stock",
+			consulta: Consulta{Archivados: true, ConStock: true, Limite: 5},
+			contiene: []string{"p.archivado = $1\n    AND p.stock > 0"},
+			args:     []any{true, int32(5)},
+		},
+		{
+			nombre: "rango de precio y cursor por precio descendente",
+			consulta: Consulta{
+				PrecioMin: 1000, PrecioMax: 5000, Orden: "price-desc", Despues: 7, Limite: 11,
+			},
+			contiene: []string{
+				"p.precio * m.tasa >= $2::numeric",
+				"p.precio * m.tasa <= $3::numeric",
+				"(p.precio * m.tasa, p.id_producto) < (SELECT p.precio * m.tasa, p.id_producto FROM producto p JOIN moneda m ON m.codigo = p.moneda WHERE p.id_producto = $4)",
+				"ORDER BY p.precio * m.tasa DESC, p.id_producto DESC\nLIMIT $5",
+			},
+			args: []any{false, dinero.Monto(1000), dinero.Monto(5000), int32(7), int32(11)},
+		},
+		{
+			nombre:   "categorías con sus subcategorías",
+			consulta: Consulta{PorCategoria: true, IDs: []int32{2, 5}, Orden: "newest-asc", Limite: 21},
+			contiene: []string{
+				"p.id_categoria = ANY($2::int[])",
+				"ORDER BY p.id_producto ASC, p.id_producto ASC",
+			},
+			args: []any{false, pq.Array([]int32{2, 5}), int32(21)},
+		},
+		{
+			nombre:   "la búsqueda ordena por relevancia y el texto va como argumento",
+			consulta: Consulta{Texto: "teclado:*", Despues: 3, Limite: 21},
+			contiene: []string{
+				"CROSS JOIN to_tsquery('es', $1) q",
+				"p.archivado = $2",
+				"documento_producto(p.nombre_producto, p.categoria, p.descripcion) @@ q",
+				"ts_headline(",
+				"WHERE p.id_producto = $3)",
+				"ORDER BY ts_rank(documento_producto(p.nombre_producto, p.categoria, p.descripcion), q)::real DESC, p.id_producto DESC\nLIMIT $4",
+			},
+			args: []any{"teclado:*", false, int32(3), int32(21)},
+		},
+		{
+			nombre:   "por ventas el cursor también lee las ventas",
+			consulta: Consulta{Orden: "sales-desc", Despues: 9, Limite: 21},
+			contiene: []string{
+				"(COALESCE(v.cantidad, 0), p.id_producto) < (SELECT COALESCE(v.cantidad, 0), p.id_producto FROM producto p JOIN moneda m ON m.codigo = p.moneda\nLEFT JOIN (",
+				"ORDER BY COALESCE(v.cantidad, 0) DESC, p.id_producto DESC",
+			},
+			args: []any{false, int32(9), int32(21)},
+		},
+		{
+			nombre:   "un orden fuera de la lista blanca no llega al texto",
+			consulta: Consulta{Orden: "precio; DROP TABLE producto-asc", Limite: 21},
+			contiene: []string{"ORDER BY p.nombre_producto ASC, p.id_producto ASC"},
+			args:     []any{false, int32(21)},
+		},
+		{
+			nombre:   "relevancia sin búsqueda vuelve al nombre",
+			consulta: Consulta{Orden: "relevance-desc", Limite: 21},
+			contiene: []string{"ORDER BY p.nombre_producto ASC, p.id_producto ASC"},
+			args:     []any{false, int32(21)},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			sql, args := c.consulta.SQL()
+			for _, parte := range c.contiene {
+				if !strings.Contains(sql, parte) {
+					t.Errorf("falta %q en:\n%s", parte, sql)
+				}
+			}
+			if strings.Contains(sql, "DROP") {
+				t.Errorf("el orden llegó al texto de la consulta:\n%s", sql)
+			}
+			if !reflect.DeepEqual(args, c.args) {
+				t.Errorf("args %#v; se esperaba %#v", args, c.args)
+			}
+			// Cada argumento se usa y no hay marcadores de más
+			for i := 1; i <= len(args)+1; i++ {
+				marcador := "$" + strconv.Itoa(i)
+				if usado := strings.Contains(sql, marcador); usado != (i <= len(args)) {
+					t.Errorf("%s usado = %v con %d argumentos", marcador, usado, len(args))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package catalogo
+
+import "strings"
+
+// campo es un campo por el que se puede ordenar la lista. expr es la
+// expresión SQL sobre p (producto), m (su moneda), q (la búsqueda) y v (las
+// unidades vendidas); es lo único de sort que llega al texto de la consulta.
+type campo struct {
+	expr   string
+	ventas bool // necesita el join con las ventas
+	texto  bool // solo tiene sentido buscando
+}
+
+// campos es la lista blanca de campos de sort. Los ids son correlativos, así
+// que ordenar por id es ordenar por fecha de alta.
+var campos = map[string]campo{
+	"name":      {expr: "p.nombre_producto"},
+	"price":     {expr: "p.precio * m.tasa"},
+	"newest":    {expr: "p.id_producto"},
+	"stock":     {expr: "p.stock"},
+	"sales":     {expr: "COALESCE(v.cantidad, 0)", ventas: true},
+	"relevance": {expr: "ts_rank(documento_producto(p.nombre_producto, p.categoria, p.descripcion), q)::real", texto: true},
+}
+
+// Opcion es una entrada del select de orden
+type Opcion struct {
+	Valor    string
+	Etiqueta string
+}
+
+// Ordenes son las opciones del select de orden. sort acepta además cualquier
+// otro campo de la lista blanca en las dos direcciones ("stock-asc").
+var Ordenes = []Opcion{
+	{"", "Ordenar por Nombre"},
+	{"name-desc", "▼ Nombre (Z a A)"},
+	{"price-asc", "▲ Precio (Menor a Mayor)"},
+	{"price-desc", "▼ Precio (Mayor a Menor)"},
+	{"newest-desc", "Más nuevos primero"},
+	{"newest-asc", "Más antiguos primero"},
+	{"stock-desc", "▼ Stock (Mayor a Menor)"},
+	{"stock-asc", "▲ Stock (Menor a Mayor)"},
+	{"sales-desc", "Más vendidos"},
+	{"sales-asc", "Menos vendidos"},
+}
+
+// ordenValido indica si sort es un campo de la lista blanca seguido de -asc
+// o -desc. "" también vale: es el orden por defecto.
+func ordenValido(sort string) bool {
+	if sort == "" {
+		return true
+	}
+	nombre, dir, ok := strings.Cut(sort, "-")
+	_, existe := campos[nombre]
+	return ok && existe && (dir == "asc" || dir == "desc")
+}
+
+// orden devuelve el campo y la dirección de sort. Sin orden, o con uno que
+// solo vale buscando, es por relevancia si hay búsqueda y por nombre si no.
+func orden(sort string, buscando bool) (campo, bool) {
+	nombre, dir, _ := strings.Cut(sort, "-")
+	c, ok := campos[nombre]
+	if !ok || (c.texto && !buscando) {
+		if buscando {
+			return campos["relevance"], true
+		}
+		return campos["name"], false
+	}
+	return c, dir == "desc"
+}
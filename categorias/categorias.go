@@ -0,0 +1,271 @@
+// Package categorias mantiene en memoria el árbol de categorías de productos,
+// arma los slugs de sus URLs y guarda el árbol en el contexto para las vistas.
+package categorias
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	sqlc "carrito.com/db/sqlc"
+)
+
+// Arbol es una copia en memoria de la tabla categoria. Se carga al arrancar y
+// se recarga cada vez que un admin cambia una categoría.
+type Arbol struct {
+	mu      sync.RWMutex
+	lista   []Nodo
+	porID   map[int32]sqlc.Categoria
+	porSlug map[string]sqlc.Categoria
+	hijos   map[int32][]sqlc.Categoria
+}
+
+// Nodo es una categoría con su profundidad en el árbol (0 para las raíces)
+type Nodo struct {
+	sqlc.Categoria
+	Nivel int
+}
+
+// Cargar lee todas las categorías de la base
+func Cargar(ctx context.Context, queries *sqlc.Queries) (*Arbol, error) {
+	a := &Arbol{}
+	if err := a.Recargar(ctx, queries); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Recargar vuelve a leer las categorías de la base
+func (a *Arbol) Recargar(ctx context.Context, queries *sqlc.Queries) error {
+	todas, err := queries.ListCategorias(ctx)
+	if err != nil {
+		return err
+	}
+	porID := make(map[int32]sqlc.Categoria, len(todas))
+	porSlug := make(map[string]sqlc.Categoria, len(todas))
+	hijos := map[int32][]sqlc.Categoria{}
+	for _, c := range todas {
+		porID[c.IDCategoria] = c
+		porSlug[c.Slug] = c
+		var padre int32
+		if c.IDPadre != nil {
+			padre = *c.IDPadre
+		}
+		// Las raíces quedan bajo el id 0; todas ya vienen ordenadas por nombre
+		hijos[padre] = append(hijos[padre], c)
+	}
+
+	lista := make([]Nodo, 0, len(todas))
+	var recorrer func(padre int32, nivel int)
+	recorrer = func(padre int32, nivel int) {
+		for _, c := range hijos[padre] {
+			lista = append(lista, Nodo{Categoria: c, Nivel: nivel})
+			recorrer(c.IDCategoria, nivel+1)
+		}
+	}
+	recorrer(0, 0)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lista = lista
+	a.porID = porID
+	a.porSlug = porSlug
+	a.hijos = hijos
+	return nil
+}
+
+// Lista devuelve todas las categorías en orden de árbol: cada una seguida de
+// sus descendientes
+func (a *Arbol) Lista() []Nodo {
+	if a == nil {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lista
+}
+
+// Raices devuelve las categorías de primer nivel
+func (a *Arbol) Raices() []sqlc.Categoria {
+	return a.Hijos(0)
+}
+
+// Hijos devuelve las subcategorías directas de la categoría id
+func (a *Arbol) Hijos(id int32) []sqlc.Categoria {
+	if a == nil {
+		return nil
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.hijos[id]
+}
+
+// Buscar devuelve la categoría con ese id
+func (a *Arbol) Buscar(id int32) (sqlc.Categoria, bool) {
+	if a == nil {
+		return sqlc.Categoria{}, false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	c, ok := a.porID[id]
+	return c, ok
+}
+
+// BuscarSlug devuelve la categoría con ese slug
+func (a *Arbol) BuscarSlug(slug string) (sqlc.Categoria, bool) {
+	if a == nil {
+		return sqlc.Categoria{}, false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	c, ok := a.porSlug[slug]
+	return c, ok
+}
+
+// Resolver encuentra la categoría que corresponde a un texto libre: por su
+// slug o por su nombre, sin importar mayúsculas ni tildes. Así "Periféricos",
+// "perifericos" y "Perifericos" son la misma categoría.
+func (a *Arbol) Resolver(texto string) (sqlc.Categoria, bool) {
+	slug := Slug(texto)
+	if slug == "" {
+		return sqlc.Categoria{}, false
+	}
+	if c, ok := a.BuscarSlug(slug); ok {
+		return c, true
+	}
+	for _, n := range a.Lista() {
+		if Slug(n.Nombre) == slug {
+			return n.Categoria, true
+		}
+	}
+	return sqlc.Categoria{}, false
+}
+
+// Descendientes devuelve el id de la categoría y los de todas las que cuelgan
+// de ella, para listar sus productos
+func (a *Arbol) Descendientes(id int32) []int32 {
+	ids := []int32{id}
+	for i := 0; i < len(ids); i++ {
+		for _, h := range a.Hijos(ids[i]) {
+			ids = append(ids, h.IDCategoria)
+		}
+	}
+	return ids
+}
+
+// Ruta devuelve los ancestros de la categoría, de la raíz hasta ella
+func (a *Arbol) Ruta(id int32) []sqlc.Categoria {
+	var ruta []sqlc.Categoria
+	for {
+		c, ok := a.Buscar(id)
+		if !ok {
+			return ruta
+		}
+		ruta = append([]sqlc.Categoria{c}, ruta...)
+		if c.IDPadre == nil {
+			return ruta
+		}
+		id = *c.IDPadre
+	}
+}
+
+// Ancestros devuelve el id de la categoría y los de las que la contienen, de
+// ella hasta la raíz; nil sin categoría. Una regla de cupón, promoción o
+// impuesto de una categoría alcanza al producto si la categoría está en sus
+// ancestros, así vale también para las subcategorías.
+func (a *Arbol) Ancestros(id *int32) []int32 {
+	if id == nil {
+		return nil
+	}
+	ruta := a.Ruta(*id)
+	ids := make([]int32, len(ruta))
+	for i, c := range ruta {
+		ids[len(ruta)-1-i] = c.IDCategoria
+	}
+	return ids
+}
+
+// acentos son las letras que Slug reemplaza antes de descartar lo que no
+// sea a-z o 0-9
+var acentos = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"à", "a", "è", "e", "ì", "i", "ò", "o", "ù", "u", "ç", "c",
+)
+
+// Slug pasa un nombre a la forma de las URLs: minúsculas sin tildes y
+// palabras separadas por guiones ("PC de escritorio" → "pc-de-escritorio")
+func Slug(s string) string {
+	s = acentos.Replace(strings.ToLower(strings.TrimSpace(s)))
+	var b strings.Builder
+	guion := false
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if guion && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			guion = false
+		} else {
+			guion = true
+		}
+	}
+	return b.String()
+}
+
+// Validar normaliza una categoría antes de guardarla: recorta el nombre,
+// arma el slug si no vino y verifica que el padre exista y no sea ella misma
+// ni una de sus descendientes. id es 0 para una categoría nueva.
+func (a *Arbol) Validar(id int32, c *sqlc.CreateCategoriaParams) error {
+	c.Nombre = strings.TrimSpace(c.Nombre)
+	if c.Nombre == "" {
+		return errors.New("el nombre es requerido")
+	}
+	if utf8.RuneCountInString(c.Nombre) > 50 {
+		return errors.New("el nombre no puede superar los 50 caracteres")
+	}
+	if c.Slug == "" {
+		c.Slug = c.Nombre
+	}
+	if c.Slug = Slug(c.Slug); c.Slug == "" {
+		return errors.New("el slug tiene que tener letras o números")
+	}
+	if len(c.Slug) > 60 {
+		return errors.New("el slug no puede superar los 60 caracteres")
+	}
+	if otra, ok := a.BuscarSlug(c.Slug); ok && otra.IDCategoria != id {
+		return errors.New("ya hay una categoría con el slug " + c.Slug)
+	}
+
+	if c.IDPadre == nil || *c.IDPadre == 0 {
+		c.IDPadre = nil
+		return nil
+	}
+	if _, ok := a.Buscar(*c.IDPadre); !ok {
+		return errors.New("la categoría padre no existe")
+	}
+	if id != 0 {
+		for _, d := range a.Descendientes(id) {
+			if d == *c.IDPadre {
+				return errors.New("una categoría no puede colgar de sí misma ni de una de sus subcategorías")
+			}
+		}
+	}
+	return nil
+}
+
+type claveContexto int
+
+const claveArbol claveContexto = iota
+
+// ConArbol devuelve un contexto con el árbol de categorías
+func ConArbol(ctx context.Context, a *Arbol) context.Context {
+	return context.WithValue(ctx, claveArbol, a)
+}
+
+// De devuelve el árbol del contexto, o nil si no hay
+func De(ctx context.Context) *Arbol {
+	a, _ := ctx.Value(claveArbol).(*Arbol)
+	return a
+}
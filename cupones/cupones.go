@@ -0,0 +1,194 @@
+// Package cupones valida cupones de descuento y calcula cuánto descuentan
+// sobre un carrito. Lo usan el carrito, para mostrar el total con descuento,
+// y el checkout, que vuelve a aplicarlo al crear el pedido.
+package cupones
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+// Tipos de cupón (ver CHECK en schema.sql)
+const (
+	TipoPorcentaje = "porcentaje"
+	TipoMonto      = "monto"
+)
+
+// ErrCupon explica por qué un cupón no se puede usar. El mensaje se le
+// muestra tal cual al usuario.
+type ErrCupon struct {
+	Motivo string
+}
+
+func (e ErrCupon) Error() string {
+	return e.Motivo
+}
+
+// Normalizar deja el código como se guarda: sin espacios y en mayúsculas
+func Normalizar(codigo string) string {
+	return strings.ToUpper(strings.TrimSpace(codigo))
+}
+
+// Linea es una línea del carrito con su subtotal ya pasado a la moneda del
+// cobro. Categorias son la categoría del producto y sus ancestros, como los
+// devuelve categorias.Arbol.Ancestros.
+type Linea struct {
+	Categorias []int32
+	Subtotal   dinero.Monto
+}
+
+// Aplicacion es el resultado de aplicar un cupón a un carrito
+type Aplicacion struct {
+	Cupon     sqlc.Cupon
+	Subtotal  dinero.Monto   // suma de todas las líneas, sin descuento
+	Descuento dinero.Monto   // nunca supera lo que suman las líneas alcanzadas
+	PorLinea  []dinero.Monto // el descuento repartido, en el orden de las líneas
+}
+
+// Total es lo que queda por pagar después del descuento
+func (a Aplicacion) Total() dinero.Monto {
+	return a.Subtotal - a.Descuento
+}
+
+// Vigente verifica que el cupón esté activo y dentro de sus fechas
+func Vigente(c sqlc.Cupon, ahora time.Time) error {
+	switch {
+	case !c.Activo:
+		return ErrCupon{fmt.Sprintf("el cupón %s ya no está disponible", c.Codigo)}
+	case c.Desde != nil && ahora.Before(*c.Desde):
+		return ErrCupon{fmt.Sprintf("el cupón %s es válido desde el %s", c.Codigo, c.Desde.Format("02/01/2006"))}
+	case c.Hasta != nil && !ahora.Before(*c.Hasta):
+		return ErrCupon{fmt.Sprintf("el cupón %s venció el %s", c.Codigo, c.Hasta.Format("02/01/2006"))}
+	}
+	return nil
+}
+
+// VerificarUsos compara los pedidos que ya usaron el cupón, en total y del
+// usuario, contra sus límites
+func VerificarUsos(c sqlc.Cupon, usos, usosUsuario int64) error {
+	if c.UsosMaximos != nil && usos >= int64(*c.UsosMaximos) {
+		return ErrCupon{fmt.Sprintf("el cupón %s ya alcanzó su límite de usos", c.Codigo)}
+	}
+	if c.UsosPorUsuario != nil && usosUsuario >= int64(*c.UsosPorUsuario) {
+		return ErrCupon{fmt.Sprintf("ya usaste el cupón %s la cantidad de veces permitida", c.Codigo)}
+	}
+	return nil
+}
+
+// Aplicar calcula el descuento del cupón sobre las líneas, que están en la
+// moneda indicada. El mínimo y el monto fijo del cupón se pasan a esa moneda
+// con las cotizaciones vigentes. No revisa fechas ni usos: eso es Vigente y
+// VerificarUsos.
+func Aplicar(cot *monedas.Cotizaciones, moneda string, c sqlc.Cupon, lineas []Linea) (Aplicacion, error) {
+	if len(lineas) == 0 {
+		return Aplicacion{}, ErrCupon{"el carrito está vacío"}
+	}
+	a := Aplicacion{Cupon: c, PorLinea: make([]dinero.Monto, len(lineas))}
+
+	var alcanzado dinero.Monto
+	for _, l := range lineas {
+		a.Subtotal += l.Subtotal
+		if aplicaA(c, l) {
+			alcanzado += l.Subtotal
+		}
+	}
+
+	minimo, err := cot.Convertir(c.Minimo, c.Moneda, moneda)
+	if err != nil {
+		return Aplicacion{}, err
+	}
+	if a.Subtotal < minimo {
+		return Aplicacion{}, ErrCupon{fmt.Sprintf("el cupón %s requiere una compra mínima de %s", c.Codigo, minimo.FormatoCon(cot.Simbolo(moneda)))}
+	}
+	if alcanzado == 0 {
+		return Aplicacion{}, ErrCupon{fmt.Sprintf("el cupón %s no aplica a ningún producto del carrito", c.Codigo)}
+	}
+
+	switch c.Tipo {
+	case TipoPorcentaje:
+		a.Descuento = alcanzado.Proporcion(int64(c.Porcentaje), 100)
+	case TipoMonto:
+		monto, err := cot.Convertir(c.Monto, c.Moneda, moneda)
+		if err != nil {
+			return Aplicacion{}, err
+		}
+		a.Descuento = min(monto, alcanzado)
+	default:
+		return Aplicacion{}, fmt.Errorf("tipo de cupón desconocido: %q", c.Tipo)
+	}
+
+	// Cada línea alcanzada se lleva una parte proporcional a su subtotal. Lo
+	// que sobra del truncado va a las últimas líneas, sin que ninguna quede
+	// con más descuento que su subtotal.
+	var repartido dinero.Monto
+	for i, l := range lineas {
+		if aplicaA(c, l) {
+			a.PorLinea[i] = a.Descuento.Proporcion(int64(l.Subtotal), int64(alcanzado))
+			repartido += a.PorLinea[i]
+		}
+	}
+	resto := a.Descuento - repartido
+	for i := len(lineas) - 1; i >= 0 && resto > 0; i-- {
+		if !aplicaA(c, lineas[i]) {
+			continue
+		}
+		extra := min(resto, lineas[i].Subtotal-a.PorLinea[i])
+		a.PorLinea[i] += extra
+		resto -= extra
+	}
+	return a, nil
+}
+
+// Validar normaliza y revisa un cupón antes de crearlo. La moneda vacía se
+// completa con la base.
+func Validar(cot *monedas.Cotizaciones, p *sqlc.CreateCuponParams) error {
+	p.Codigo = Normalizar(p.Codigo)
+	if p.Codigo == "" || len(p.Codigo) > 40 {
+		return ErrCupon{"el código es requerido y tiene hasta 40 caracteres"}
+	}
+	if p.Moneda == "" {
+		p.Moneda = monedas.Base
+	}
+	if _, ok := cot.Buscar(p.Moneda); !ok {
+		return ErrCupon{"moneda desconocida: " + p.Moneda}
+	}
+
+	switch p.Tipo {
+	case TipoPorcentaje:
+		if p.Porcentaje < 1 || p.Porcentaje > 100 || p.Monto != 0 {
+			return ErrCupon{"un cupón de porcentaje lleva un porcentaje entre 1 y 100 y no lleva monto"}
+		}
+	case TipoMonto:
+		if p.Monto <= 0 || p.Monto > dinero.Maximo || p.Porcentaje != 0 {
+			return ErrCupon{"un cupón de monto fijo lleva un monto mayor a cero y no lleva porcentaje"}
+		}
+	default:
+		return ErrCupon{fmt.Sprintf("tipo de cupón inválido: use %s o %s", TipoPorcentaje, TipoMonto)}
+	}
+
+	if p.Minimo < 0 || p.Minimo > dinero.Maximo {
+		return ErrCupon{"mínimo de compra inválido"}
+	}
+	if p.Desde != nil && p.Hasta != nil && !p.Hasta.After(*p.Desde) {
+		return ErrCupon{"la fecha de fin tiene que ser posterior a la de inicio"}
+	}
+	if (p.UsosMaximos != nil && *p.UsosMaximos < 1) || (p.UsosPorUsuario != nil && *p.UsosPorUsuario < 1) {
+		return ErrCupon{"los límites de uso deben ser positivos"}
+	}
+	if p.IDCategoria != nil && *p.IDCategoria <= 0 {
+		p.IDCategoria = nil
+	}
+	return nil
+}
+
+// aplicaA indica si la línea entra en la restricción de categoría del cupón,
+// que incluye a sus subcategorías
+func aplicaA(c sqlc.Cupon, l Linea) bool {
+	return c.IDCategoria == nil || slices.Contains(l.Categorias, *c.IDCategoria)
+}
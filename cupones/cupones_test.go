@@ -0,0 +1,154 @@
+package cupones
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+var cotizaciones = monedas.Fijas([]sqlc.Moneda{
+	{Codigo: monedas.Base, Simbolo: "$", Tasa: dinero.TasaUno},
+	{Codigo: "USD", Simbolo: "US$", Tasa: 1000 * dinero.TasaUno},
+})
+
+func porcentaje(p int32) sqlc.Cupon {
+	return sqlc.Cupon{Codigo: "PORC", Tipo: TipoPorcentaje, Porcentaje: p, Moneda: monedas.Base, Activo: true}
+}
+
+func monto(m dinero.Monto, moneda string) sqlc.Cupon {
+	return sqlc.Cupon{Codigo: "MONTO", Tipo: TipoMonto, Monto: m, Moneda: moneda, Activo: true}
+}
+
+func lineas(subtotales ...dinero.Monto) []Linea {
+	l := make([]Linea, len(subtotales))
+	for i, s := range subtotales {
+		l[i] = Linea{Subtotal: s}
+	}
+	return l
+}
+
+func TestAplicar(t *testing.T) {
+	perifericos := int32(2)
+	deCategoria := porcentaje(10)
+	deCategoria.IDCategoria = &perifericos
+
+	casos := []struct {
+		nombre    string
+		cupon     sqlc.Cupon
+		lineas    []Linea
+		descuento dinero.Monto
+		porLinea  []dinero.Monto
+	}{
+		{
+			nombre:    "una línea",
+			cupon:     porcentaje(10),
+			lineas:    lineas(1000),
+			descuento: 100,
+			porLinea:  []dinero.Monto{100},
+		},
+		{
+			nombre:    "el resto del truncado va a la última línea",
+			cupon:     monto(1000, monedas.Base),
+			lineas:    lineas(1000, 1000, 1000),
+			descuento: 1000,
+			porLinea:  []dinero.Monto{333, 333, 334},
+		},
+		{
+			nombre:    "las líneas suman exactamente el monto del cupón",
+			cupon:     monto(1000, monedas.Base),
+			lineas:    lineas(333, 333, 334),
+			descuento: 1000,
+			porLinea:  []dinero.Monto{333, 333, 334},
+		},
+		{
+			nombre:    "un descuento mayor al subtotal se limita a lo que suman las líneas",
+			cupon:     monto(5000, monedas.Base),
+			lineas:    lineas(1000, 500),
+			descuento: 1500,
+			porLinea:  []dinero.Monto{1000, 500},
+		},
+		{
+			nombre:    "las líneas en cero no reciben descuento",
+			cupon:     monto(1000, monedas.Base),
+			lineas:    lineas(0, 1000, 0),
+			descuento: 1000,
+			porLinea:  []dinero.Monto{0, 1000, 0},
+		},
+		{
+			nombre:    "el resto no deja una línea con más descuento que su subtotal",
+			cupon:     monto(2, monedas.Base),
+			lineas:    lineas(1, 1, 1, 0),
+			descuento: 2,
+			porLinea:  []dinero.Monto{0, 1, 1, 0},
+		},
+		{
+			nombre:    "un monto en otra moneda se convierte",
+			cupon:     monto(100, "USD"),
+			lineas:    lineas(300000),
+			descuento: 100000,
+			porLinea:  []dinero.Monto{100000},
+		},
+		{
+			nombre: "solo las líneas de la categoría o sus subcategorías",
+			cupon:  deCategoria,
+			lineas: []Linea{
+				{Categorias: []int32{5, 2}, Subtotal: 1000},
+				{Categorias: []int32{1}, Subtotal: 1000},
+				{Subtotal: 1000},
+			},
+			descuento: 100,
+			porLinea:  []dinero.Monto{100, 0, 0},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			a, err := Aplicar(cotizaciones, monedas.Base, c.cupon, c.lineas)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Descuento != c.descuento || !slices.Equal(a.PorLinea, c.porLinea) {
+				t.Fatalf("descuento %d %v; se esperaba %d %v", a.Descuento, a.PorLinea, c.descuento, c.porLinea)
+			}
+			var suma, subtotal dinero.Monto
+			for i, d := range a.PorLinea {
+				suma += d
+				subtotal += c.lineas[i].Subtotal
+			}
+			if suma != a.Descuento || a.Subtotal != subtotal || a.Total() != subtotal-suma {
+				t.Fatalf("el reparto suma %d y el total es %d", suma, a.Total())
+			}
+		})
+	}
+}
+
+func TestAplicarRechaza(t *testing.T) {
+	otra := int32(9)
+	deOtraCategoria := porcentaje(10)
+	deOtraCategoria.IDCategoria = &otra
+	conMinimo := porcentaje(10)
+	conMinimo.Minimo = 5000
+
+	casos := []struct {
+		nombre string
+		cupon  sqlc.Cupon
+		lineas []Linea
+	}{
+		{"carrito vacío", porcentaje(10), nil},
+		{"no llega al mínimo", conMinimo, lineas(4999)},
+		{"ninguna línea de la categoría", deOtraCategoria, []Linea{{Categorias: []int32{1}, Subtotal: 1000}}},
+		{"todas las líneas en cero", porcentaje(10), lineas(0, 0)},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			_, err := Aplicar(cotizaciones, monedas.Base, c.cupon, c.lineas)
+			var errCupon ErrCupon
+			if !errors.As(err, &errCupon) {
+				t.Fatalf("Aplicar = %v; se esperaba ErrCupon", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+-- Pasa una base existente a la tabla categoria (las bases nuevas ya la crean
+-- desde db/schema). Los valores libres de producto.categoria se asocian a la
+-- categoría con el mismo slug o nombre ("Periféricos", "perifericos"); los que
+-- no coinciden con ninguna se crean como categorías de primer nivel.
+-- La categoría de cupones, promociones e impuestos se pasa después con
+-- 003_categorias_reglas.sql.
+--
+--   docker compose exec -T db psql -U postgres apirest < db/migraciones/001_categorias.sql
+BEGIN;
+
+CREATE TABLE categoria (
+    id_categoria SERIAL PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL,
+    slug VARCHAR(60) NOT NULL UNIQUE CHECK (slug ~ '^[a-z0-9]+(-[a-z0-9]+)*$'),
+    id_padre INT REFERENCES categoria(id_categoria) ON DELETE RESTRICT,
+    CHECK (id_padre <> id_categoria)
+);
+
+INSERT INTO categoria (nombre, slug) VALUES
+    ('Computadoras', 'computadoras'),
+    ('Periféricos', 'perifericos'),
+    ('Componentes', 'componentes'),
+    ('Otros', 'otros');
+INSERT INTO categoria (nombre, slug, id_padre)
+    SELECT v.nombre, v.slug, c.id_categoria
+    FROM (VALUES ('PC de escritorio', 'pc'), ('Laptops', 'laptop')) AS v(nombre, slug), categoria c
+    WHERE c.slug = 'computadoras';
+
+ALTER TABLE producto ADD COLUMN id_categoria INT REFERENCES categoria(id_categoria) ON DELETE SET NULL;
+
+-- El mismo slug que arma categorias.Slug en Go
+CREATE FUNCTION pg_temp.slug(t TEXT) RETURNS TEXT LANGUAGE sql IMMUTABLE AS $$
+    SELECT trim(BOTH '-' FROM regexp_replace(
+        translate(lower(trim(t)), 'áéíóúüñàèìòùç', 'aeiouunaeiouc'),
+        '[^a-z0-9]+', '-', 'g'))
+$$;
+
+-- Categorías que ya usan los productos y no son ninguna de las de fábrica
+INSERT INTO categoria (nombre, slug)
+    SELECT MIN(trim(p.categoria)), pg_temp.slug(p.categoria)
+    FROM producto p
+    WHERE pg_temp.slug(p.categoria) <> ''
+      AND NOT EXISTS (
+          SELECT 1 FROM categoria c
+          WHERE c.slug = pg_temp.slug(p.categoria) OR pg_temp.slug(c.nombre) = pg_temp.slug(p.categoria)
+      )
+    GROUP BY pg_temp.slug(p.categoria);
+
+-- Primero por slug y después por nombre, igual que categorias.Resolver
+UPDATE producto p SET id_categoria = c.id_categoria, categoria = c.nombre
+FROM categoria c
+WHERE c.slug = pg_temp.slug(p.categoria);
+
+UPDATE producto p SET id_categoria = c.id_categoria, categoria = c.nombre
+FROM categoria c
+WHERE p.id_categoria IS NULL AND pg_temp.slug(c.nombre) = pg_temp.slug(p.categoria);
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- Agrega la búsqueda de productos a una base existente (las bases nuevas ya
+-- la crean desde db/schema).
+--
+--   docker compose exec -T db psql -U postgres apirest < db/migraciones/002_busqueda.sql
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE TEXT SEARCH CONFIGURATION es (COPY = spanish);
+ALTER TEXT SEARCH CONFIGURATION es
+    ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+
+CREATE FUNCTION documento_producto(nombre TEXT, categoria TEXT, descripcion TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT setweight(to_tsvector('es', nombre), 'A')
+        || setweight(to_tsvector('es', categoria), 'B')
+        || setweight(to_tsvector('es', descripcion), 'C')
+$$;
+
+CREATE INDEX producto_busqueda_idx ON producto
+    USING GIN (documento_producto(nombre_producto, categoria, descripcion));
+
+COMMIT;
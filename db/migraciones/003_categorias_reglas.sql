@@ -0,0 +1,69 @@
+-- Pasa cupones, promociones e impuestos de la categoría en texto libre a
+-- id_categoria, después de 001 (las bases nuevas ya la crean desde
+-- db/schema). Igual que en 001, cada texto se asocia a la categoría con el
+-- mismo slug o nombre, y los que no coinciden con ninguna se crean como
+-- categorías de primer nivel para que la regla no pase a valer para todo.
+--
+--   docker compose exec -T db psql -U postgres apirest < db/migraciones/003_categorias_reglas.sql
+BEGIN;
+
+-- El mismo slug que arma categorias.Slug en Go
+CREATE FUNCTION pg_temp.slug(t TEXT) RETURNS TEXT LANGUAGE sql IMMUTABLE AS $$
+    SELECT trim(BOTH '-' FROM regexp_replace(
+        translate(lower(trim(t)), 'áéíóúüñàèìòùç', 'aeiouunaeiouc'),
+        '[^a-z0-9]+', '-', 'g'))
+$$;
+
+INSERT INTO categoria (nombre, slug)
+    SELECT MIN(trim(r.categoria)), pg_temp.slug(r.categoria)
+    FROM (
+        SELECT categoria FROM cupon
+        UNION ALL SELECT categoria FROM promocion
+        UNION ALL SELECT categoria FROM impuesto
+    ) r
+    WHERE pg_temp.slug(r.categoria) <> ''
+      AND NOT EXISTS (
+          SELECT 1 FROM categoria c
+          WHERE c.slug = pg_temp.slug(r.categoria) OR pg_temp.slug(c.nombre) = pg_temp.slug(r.categoria)
+      )
+    GROUP BY pg_temp.slug(r.categoria);
+
+ALTER TABLE cupon ADD COLUMN id_categoria INT REFERENCES categoria(id_categoria) ON DELETE RESTRICT;
+ALTER TABLE promocion ADD COLUMN id_categoria INT REFERENCES categoria(id_categoria) ON DELETE RESTRICT;
+ALTER TABLE impuesto ADD COLUMN id_categoria INT REFERENCES categoria(id_categoria) ON DELETE RESTRICT;
+
+-- Primero por slug y después por nombre, igual que categorias.Resolver
+UPDATE cupon r SET id_categoria = c.id_categoria
+FROM categoria c
+WHERE c.slug = pg_temp.slug(r.categoria);
+
+UPDATE cupon r SET id_categoria = c.id_categoria
+FROM categoria c
+WHERE r.id_categoria IS NULL AND pg_temp.slug(c.nombre) = pg_temp.slug(r.categoria);
+
+UPDATE promocion r SET id_categoria = c.id_categoria
+FROM categoria c
+WHERE c.slug = pg_temp.slug(r.categoria);
+
+UPDATE promocion r SET id_categoria = c.id_categoria
+FROM categoria c
+WHERE r.id_categoria IS NULL AND pg_temp.slug(c.nombre) = pg_temp.slug(r.categoria);
+
+UPDATE impuesto r SET id_categoria = c.id_categoria
+FROM categoria c
+WHERE c.slug = pg_temp.slug(r.categoria);
+
+UPDATE impuesto r SET id_categoria = c.id_categoria
+FROM categoria c
+WHERE r.id_categoria IS NULL AND pg_temp.slug(c.nombre) = pg_temp.slug(r.categoria);
+
+-- Borrar la columna vieja se lleva el CHECK de promocion y el índice único
+-- de impuesto que la usaban; se vuelven a crear sobre id_categoria
+ALTER TABLE cupon DROP COLUMN categoria;
+ALTER TABLE promocion DROP COLUMN categoria;
+ALTER TABLE impuesto DROP COLUMN categoria;
+
+ALTER TABLE promocion ADD CHECK (tipo <> 'categoria' OR (id_categoria IS NOT NULL AND cantidad >= 1 AND porcentaje BETWEEN 1 AND 100));
+CREATE UNIQUE INDEX impuesto_categoria_region_idx ON impuesto (COALESCE(id_categoria, 0), LOWER(COALESCE(region, '')));
+
+COMMIT;
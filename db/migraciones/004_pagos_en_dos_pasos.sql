@@ -0,0 +1,19 @@
+-- Captura y reembolsos por la pasarela en dos pasos, después de 003 (las
+-- bases nuevas ya lo crean desde db/schema): el pago pasa por 'capturando'
+-- mientras se espera la respuesta de la pasarela y cada reembolso anota el
+-- cobro por el que se devuelve y en qué quedó allá. Los reembolsos ya hechos
+-- por la pasarela no se pueden distinguir de los manuales, así que quedan
+-- con estado_pago vacío.
+--
+--   docker compose exec -T db psql -U postgres apirest < db/migraciones/004_pagos_en_dos_pasos.sql
+BEGIN;
+
+ALTER TABLE pago DROP CONSTRAINT pago_estado_check;
+ALTER TABLE pago ADD CONSTRAINT pago_estado_check
+    CHECK (estado IN ('creado','capturando','capturado','rechazado','anulado'));
+
+ALTER TABLE reembolso ADD COLUMN id_pago INT REFERENCES pago(id_pago) ON DELETE SET NULL;
+ALTER TABLE reembolso ADD COLUMN estado_pago VARCHAR(20) NOT NULL DEFAULT ''
+    CHECK (estado_pago IN ('','pendiente','devuelto','rechazado'));
+
+COMMIT;
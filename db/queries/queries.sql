@@ -1,41 +1,31 @@
 -- name: CreateProd :one
-INSERT INTO producto (nombre_producto, descripcion, precio, stock, categoria, imagen) VALUES ($1,$2, $3, $4, $5, $6) RETURNING *;
+INSERT INTO producto (nombre_producto, descripcion, precio, stock, categoria, imagen, moneda, peso, id_categoria) VALUES ($1,$2, $3, $4, $5, $6, $7, $8, $9) RETURNING *;
 
 -- name: CreateUser :one
-INSERT INTO usuario (nombre_usuario, email) VALUES ($1, $2) RETURNING id_usuario, nombre_usuario, email;
-
--- name: CreateVenta :one
-INSERT INTO venta (id_producto,id_usuario, cantidad, total, fecha) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+INSERT INTO usuario (nombre_usuario, email, password_hash) VALUES ($1, $2, $3) RETURNING *;
 
 -- name: GetProd :one
 SELECT * FROM producto WHERE id_producto = $1;
 
--- name: GetVenta :one
-SELECT * FROM venta WHERE id_venta = $1;
-
--- name: GetVenta_usuario :one
-SELECT * FROM venta WHERE id_usuario = $1;
+-- name: GetProdForUpdate :one
+SELECT * FROM producto WHERE id_producto = $1 FOR UPDATE;
 
 -- name: GetUserByEmail :one
-SELECT id_usuario, nombre_usuario, email FROM usuario WHERE email = $1;
+SELECT * FROM usuario WHERE email = $1;
 
 -- name: GetUser :one
-SELECT nombre_usuario, email FROM usuario WHERE id_usuario = $1;
+SELECT * FROM usuario WHERE id_usuario = $1;
 
--- name: ListProd :many
-SELECT * FROM producto ORDER BY nombre_producto;
+-- Bloquea al comprador durante el checkout: dos compras simultáneas del mismo
+-- usuario se hacen una después de la otra y la segunda ve el carrito vacío
+-- name: BloquearUsuario :one
+SELECT id_usuario FROM usuario WHERE id_usuario = $1 FOR UPDATE;
 
 -- name: ListUsers :many
 SELECT * FROM usuario ORDER BY nombre_usuario;
 
--- name: ListVentasUsuario :many
-SELECT * FROM venta WHERE id_usuario = $1;
-
--- name: ListVentas :many
-SELECT * FROM venta ORDER BY fecha;
-
--- name: UpdateProducto :exec
-UPDATE producto SET nombre_producto = $2, descripcion = $3, stock = $4, precio = $5, categoria = $6, imagen = $7 WHERE id_producto = $1;
+-- name: UpdateProducto :one
+UPDATE producto SET nombre_producto = $2, descripcion = $3, stock = $4, precio = $5, categoria = $6, imagen = $7, moneda = $8, peso = $9, id_categoria = $10 WHERE id_producto = $1 RETURNING *;
 
 -- name: UpdateProductoPrecio :exec
 UPDATE producto SET precio = $2 WHERE id_producto = $1;
@@ -43,41 +33,370 @@ UPDATE producto SET precio = $2 WHERE id_producto = $1;
 -- name: UpdateProductoStock :exec
 UPDATE producto SET stock = $2 WHERE id_producto = $1;
 
--- name: UpdateUser :exec
-UPDATE usuario SET nombre_usuario = $2, email = $3 WHERE id_usuario = $1;
+-- name: SumarStockProducto :exec
+UPDATE producto SET stock = stock + $2 WHERE id_producto = $1;
 
--- name: UpdateVenta :exec
-UPDATE venta SET cantidad = $2, total = $3, fecha = $4 WHERE id_venta = $1;
+-- name: UpdateUser :one
+UPDATE usuario SET nombre_usuario = $2, email = $3 WHERE id_usuario = $1 RETURNING *;
 
--- name: DeleteProd :exec
-DELETE FROM producto WHERE id_producto = $1;
+-- name: UpdateUserRol :exec
+UPDATE usuario SET rol = $2 WHERE id_usuario = $1;
 
--- name: DeleteUser :exec
-DELETE FROM usuario WHERE id_usuario = $1;
+-- name: UpdateUserRegion :exec
+UPDATE usuario SET region = $2 WHERE id_usuario = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE usuario SET password_hash = $2 WHERE id_usuario = $1;
 
--- name: DeleteVenta :exec
-DELETE FROM venta WHERE id_venta = $1;
+-- name: ArchivarProd :execrows
+UPDATE producto SET archivado = true WHERE id_producto = $1;
 
--- name: ListProductsByPriceAsc :many
-SELECT id_producto, nombre_producto, descripcion, precio, stock, categoria, imagen FROM producto ORDER BY precio ASC;
+-- name: RestaurarProd :one
+UPDATE producto SET archivado = false WHERE id_producto = $1 RETURNING *;
 
--- name: ListProductsByPriceDesc :many
-SELECT id_producto, nombre_producto, descripcion, precio, stock, categoria, imagen FROM producto ORDER BY precio DESC;
+-- name: DeleteUser :execrows
+DELETE FROM usuario WHERE id_usuario = $1;
 
+-- Un producto archivado no se puede agregar: no inserta nada y devuelve sql.ErrNoRows
 -- name: AddToCart :one
-INSERT INTO carrito (id_usuario, id_producto, cantidad) VALUES ($1, $2, $3) RETURNING *;
+INSERT INTO carrito (id_usuario, id_producto, cantidad)
+SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM producto WHERE id_producto = $2 AND NOT archivado)
+RETURNING *;
 
--- name: DeleteProdCarrito :exec
-DELETE FROM carrito WHERE id_item = $1;
+-- name: DeleteProdCarrito :execrows
+DELETE FROM carrito WHERE id_item = $1 AND id_usuario = $2;
 
 -- name: DeleteCart :exec
 DELETE FROM carrito WHERE id_usuario = $1;
 
--- name: UpdateCartItem :exec
-UPDATE carrito SET cantidad = $2 WHERE id_item = $1;
+-- name: DeleteProdDeCarritos :exec
+DELETE FROM carrito WHERE id_producto = $1;
+
+-- name: UpdateCartItem :execrows
+UPDATE carrito SET cantidad = $3 WHERE id_item = $1 AND id_usuario = $2;
 
 -- name: GetCartItems :many
-SELECT c.*, p.nombre_producto, p.precio FROM carrito c JOIN producto p ON c.id_producto = p.id_producto WHERE c.id_usuario = $1;
+SELECT c.*, p.nombre_producto, p.precio, p.moneda, p.categoria, p.id_categoria, p.peso FROM carrito c JOIN producto p ON c.id_producto = p.id_producto WHERE c.id_usuario = $1 ORDER BY c.id_producto;
+
+-- Lo mismo que GetCartItems pero bloquea las filas del carrito hasta el fin
+-- de la transacción del checkout, así un cambio de cantidad espera a la compra
+-- name: GetCartItemsForUpdate :many
+SELECT c.*, p.nombre_producto, p.precio, p.moneda, p.categoria, p.id_categoria, p.peso FROM carrito c JOIN producto p ON c.id_producto = p.id_producto WHERE c.id_usuario = $1 ORDER BY c.id_producto FOR UPDATE OF c;
+
+-- Borra del carrito solo las líneas que se compraron; lo que se agregó
+-- durante el checkout queda para la próxima compra
+-- name: DeleteCartItems :exec
+DELETE FROM carrito WHERE id_usuario = sqlc.arg(id_usuario) AND id_item = ANY(sqlc.arg(ids)::int[]);
 
 -- name: GetCartItemByUserAndProduct :one
 SELECT * FROM carrito WHERE id_usuario = $1 AND id_producto = $2;
+
+-- name: CreateSesion :exec
+INSERT INTO sesion (token_hash, id_usuario, csrf_token, expira) VALUES ($1, $2, $3, $4);
+
+-- name: GetUsuarioSesion :one
+SELECT sqlc.embed(u), s.csrf_token, s.moneda FROM sesion s JOIN usuario u ON s.id_usuario = u.id_usuario WHERE s.token_hash = $1 AND s.expira > NOW();
+
+-- name: UpdateSesionMoneda :exec
+UPDATE sesion SET moneda = $2 WHERE token_hash = $1;
+
+-- name: DeleteSesion :exec
+DELETE FROM sesion WHERE token_hash = $1;
+
+-- name: DeleteSesionesUsuario :exec
+DELETE FROM sesion WHERE id_usuario = $1;
+
+-- name: DeleteSesionesExpiradas :exec
+DELETE FROM sesion WHERE expira <= NOW();
+
+-- name: CreatePedido :one
+INSERT INTO pedido (id_usuario, total, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING *;
+
+-- name: CreatePedidoItem :one
+INSERT INTO pedido_item (id_pedido, id_producto, nombre_producto, precio_unitario, cantidad, subtotal, descuento, promociones, impuesto_nombre, alicuota, impuesto, impuesto_incluido)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING *;
+
+-- name: GetPedido :one
+SELECT * FROM pedido WHERE id_pedido = $1;
+
+-- name: ListPedidos :many
+SELECT * FROM pedido ORDER BY fecha DESC;
+
+-- name: ListPedidosPorEstado :many
+SELECT * FROM pedido WHERE estado = $1 ORDER BY fecha DESC;
+
+-- name: GetPedidoForUpdate :one
+SELECT * FROM pedido WHERE id_pedido = $1 FOR UPDATE;
+
+-- name: UpdatePedidoEstado :one
+UPDATE pedido SET estado = $2, actualizado = NOW() WHERE id_pedido = $1 RETURNING *;
+
+-- name: CreatePedidoHistorial :one
+INSERT INTO pedido_historial (id_pedido, estado_anterior, estado, id_usuario) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: ListHistorialDePedidos :many
+SELECT * FROM pedido_historial WHERE id_pedido = ANY(sqlc.arg(ids)::int[]) ORDER BY id_pedido, fecha, id_historial;
+
+-- name: ListPedidosUsuario :many
+SELECT * FROM pedido WHERE id_usuario = $1 ORDER BY fecha DESC;
+
+-- name: ListItemsDePedidos :many
+SELECT * FROM pedido_item WHERE id_pedido = ANY(sqlc.arg(ids)::int[]) ORDER BY id_pedido, id_item;
+
+-- name: ListItemsPedidoForUpdate :many
+SELECT * FROM pedido_item WHERE id_pedido = $1 ORDER BY id_item FOR UPDATE;
+
+-- name: SumarItemReembolsado :exec
+UPDATE pedido_item SET cantidad_reembolsada = cantidad_reembolsada + $2 WHERE id_item = $1;
+
+-- name: SumarReembolsoPedido :one
+UPDATE pedido SET total_reembolsado = total_reembolsado + $2, actualizado = NOW() WHERE id_pedido = $1 RETURNING *;
+
+-- name: CreateReembolso :one
+INSERT INTO reembolso (id_pedido, monto, motivo, id_usuario) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: CreateReembolsoItem :exec
+INSERT INTO reembolso_item (id_reembolso, id_item, cantidad, monto) VALUES ($1, $2, $3, $4);
+
+-- name: GetReembolso :one
+SELECT * FROM reembolso WHERE id_reembolso = $1;
+
+-- name: GetReembolsoForUpdate :one
+SELECT * FROM reembolso WHERE id_reembolso = $1 FOR UPDATE;
+
+-- name: MarcarReembolsoPendiente :exec
+UPDATE reembolso SET id_pago = $2, estado_pago = 'pendiente' WHERE id_reembolso = $1;
+
+-- name: UpdateReembolsoEstadoPago :exec
+UPDATE reembolso SET estado_pago = $2 WHERE id_reembolso = $1;
+
+-- Con id_pedido 0 trae los de todos los pedidos
+-- name: ListReembolsosPendientes :many
+SELECT r.id_reembolso, r.id_pedido, r.monto, p.id_pago, p.proveedor, p.referencia
+FROM reembolso r JOIN pago p ON p.id_pago = r.id_pago
+WHERE r.estado_pago = 'pendiente'
+  AND (sqlc.arg(id_pedido)::int = 0 OR r.id_pedido = sqlc.arg(id_pedido))
+ORDER BY r.id_reembolso;
+
+-- name: ListReembolsosDePedidos :many
+SELECT * FROM reembolso WHERE id_pedido = ANY(sqlc.arg(ids)::int[]) ORDER BY id_pedido, fecha, id_reembolso;
+
+-- name: ListMonedas :many
+SELECT * FROM moneda ORDER BY codigo;
+
+-- name: UpsertMoneda :one
+INSERT INTO moneda (codigo, nombre, simbolo, tasa) VALUES ($1, $2, $3, $4)
+ON CONFLICT (codigo) DO UPDATE SET nombre = EXCLUDED.nombre, simbolo = EXCLUDED.simbolo, tasa = EXCLUDED.tasa, actualizado = NOW()
+RETURNING *;
+
+-- name: UpdateMonedaTasa :one
+UPDATE moneda SET tasa = $2, actualizado = NOW() WHERE codigo = $1 RETURNING *;
+
+-- name: ListCupones :many
+SELECT * FROM cupon ORDER BY creado DESC;
+
+-- name: GetCupon :one
+SELECT * FROM cupon WHERE codigo = $1;
+
+-- name: GetCuponForUpdate :one
+SELECT * FROM cupon WHERE codigo = $1 FOR UPDATE;
+
+-- name: CreateCupon :one
+INSERT INTO cupon (codigo, tipo, porcentaje, monto, moneda, minimo, desde, hasta, usos_maximos, usos_por_usuario, id_categoria)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING *;
+
+-- name: DesactivarCupon :execrows
+UPDATE cupon SET activo = FALSE WHERE codigo = $1;
+
+-- Los pedidos cancelados no consumen usos del cupón
+-- name: ContarUsosCupon :one
+SELECT COUNT(*) FROM pedido WHERE cupon = $1 AND estado <> 'cancelado';
+
+-- name: ContarUsosCuponUsuario :one
+SELECT COUNT(*) FROM pedido WHERE cupon = $1 AND id_usuario = $2 AND estado <> 'cancelado';
+
+-- name: GetCarritoCupon :one
+SELECT c.* FROM carrito_cupon cc JOIN cupon c ON cc.codigo = c.codigo WHERE cc.id_usuario = $1;
+
+-- name: SetCarritoCupon :exec
+INSERT INTO carrito_cupon (id_usuario, codigo) VALUES ($1, $2)
+ON CONFLICT (id_usuario) DO UPDATE SET codigo = EXCLUDED.codigo;
+
+-- name: DeleteCarritoCupon :exec
+DELETE FROM carrito_cupon WHERE id_usuario = $1;
+
+-- name: ListPromociones :many
+SELECT * FROM promocion ORDER BY id_promocion;
+
+-- name: ListPromocionesActivas :many
+SELECT * FROM promocion WHERE activa ORDER BY id_promocion;
+
+-- name: GetPromocion :one
+SELECT * FROM promocion WHERE id_promocion = $1;
+
+-- name: CreatePromocion :one
+INSERT INTO promocion (nombre, tipo, id_categoria, id_producto, cantidad, paga, porcentaje, minimo, moneda, activa)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING *;
+
+-- name: UpdatePromocion :one
+UPDATE promocion SET nombre = $2, tipo = $3, id_categoria = $4, id_producto = $5, cantidad = $6,
+    paga = $7, porcentaje = $8, minimo = $9, moneda = $10, activa = $11
+WHERE id_promocion = $1 RETURNING *;
+
+-- name: UpdatePromocionActiva :one
+UPDATE promocion SET activa = $2 WHERE id_promocion = $1 RETURNING *;
+
+-- name: DeletePromocion :execrows
+DELETE FROM promocion WHERE id_promocion = $1;
+
+-- name: ListImpuestos :many
+SELECT * FROM impuesto ORDER BY LOWER(COALESCE(region, '')), COALESCE(id_categoria, 0), id_impuesto;
+
+-- name: GetImpuesto :one
+SELECT * FROM impuesto WHERE id_impuesto = $1;
+
+-- name: CreateImpuesto :one
+INSERT INTO impuesto (nombre, alicuota, id_categoria, region, incluido) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: UpdateImpuesto :one
+UPDATE impuesto SET nombre = $2, alicuota = $3, id_categoria = $4, region = $5, incluido = $6 WHERE id_impuesto = $1 RETURNING *;
+
+-- name: DeleteImpuesto :execrows
+DELETE FROM impuesto WHERE id_impuesto = $1;
+
+-- name: ListRegionesImpuesto :many
+SELECT DISTINCT region::text FROM impuesto WHERE region IS NOT NULL ORDER BY region;
+
+-- Líneas con impuesto de los pedidos cobrados en el período, para el reporte.
+-- Los pendientes todavía no se cobraron y los cancelados no cuentan.
+-- name: ListItemsImpuestos :many
+SELECT sqlc.embed(i), p.region, p.moneda, p.tasa
+FROM pedido_item i JOIN pedido p ON p.id_pedido = i.id_pedido
+WHERE p.fecha >= sqlc.arg(desde) AND p.fecha < sqlc.arg(hasta)
+    AND p.estado IN ('pagado', 'enviado', 'entregado') AND i.impuesto_nombre <> ''
+ORDER BY p.id_pedido, i.id_item;
+
+-- name: ListDirecciones :many
+SELECT * FROM direccion WHERE id_usuario = $1 ORDER BY id_direccion;
+
+-- name: GetDireccion :one
+SELECT * FROM direccion WHERE id_direccion = $1 AND id_usuario = $2;
+
+-- name: CreateDireccion :one
+INSERT INTO direccion (id_usuario, alias, destinatario, calle, ciudad, provincia, codigo_postal, telefono)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: UpdateDireccion :one
+UPDATE direccion SET alias = $3, destinatario = $4, calle = $5, ciudad = $6, provincia = $7, codigo_postal = $8, telefono = $9
+WHERE id_direccion = $1 AND id_usuario = $2 RETURNING *;
+
+-- name: DeleteDireccion :execrows
+DELETE FROM direccion WHERE id_direccion = $1 AND id_usuario = $2;
+
+-- name: ListMetodosEnvio :many
+SELECT * FROM metodo_envio ORDER BY id_metodo;
+
+-- name: ListMetodosEnvioActivos :many
+SELECT * FROM metodo_envio WHERE activo ORDER BY id_metodo;
+
+-- name: GetMetodoEnvio :one
+SELECT * FROM metodo_envio WHERE id_metodo = $1;
+
+-- name: CreateMetodoEnvio :one
+INSERT INTO metodo_envio (nombre, tipo, costo, moneda, activo) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: UpdateMetodoEnvioActivo :one
+UPDATE metodo_envio SET activo = $2 WHERE id_metodo = $1 RETURNING *;
+
+-- name: DeleteMetodoEnvio :execrows
+DELETE FROM metodo_envio WHERE id_metodo = $1;
+
+-- name: ListTarifasEnvio :many
+SELECT * FROM tarifa_envio ORDER BY id_metodo, provincia NULLS LAST, peso_hasta;
+
+-- name: ListTarifasMetodo :many
+SELECT * FROM tarifa_envio WHERE id_metodo = $1 ORDER BY provincia NULLS LAST, peso_hasta;
+
+-- name: CreateTarifaEnvio :one
+INSERT INTO tarifa_envio (id_metodo, provincia, peso_hasta, costo) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: DeleteTarifaEnvio :execrows
+DELETE FROM tarifa_envio WHERE id_tarifa = $1;
+
+-- name: GetCarritoEnvio :one
+SELECT * FROM carrito_envio WHERE id_usuario = $1;
+
+-- name: SetCarritoEnvio :exec
+INSERT INTO carrito_envio (id_usuario, id_metodo, id_direccion) VALUES ($1, $2, $3)
+ON CONFLICT (id_usuario) DO UPDATE SET id_metodo = EXCLUDED.id_metodo, id_direccion = EXCLUDED.id_direccion;
+
+-- name: DeleteCarritoEnvio :exec
+DELETE FROM carrito_envio WHERE id_usuario = $1;
+
+-- name: CreatePago :one
+INSERT INTO pago (id_pedido, proveedor, referencia, monto, moneda, url)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetPagoPorReferencia :one
+SELECT * FROM pago WHERE proveedor = $1 AND referencia = $2;
+
+-- name: GetPagoPorReferenciaForUpdate :one
+SELECT * FROM pago WHERE proveedor = $1 AND referencia = $2 FOR UPDATE;
+
+-- name: GetPagoAbierto :one
+SELECT * FROM pago WHERE id_pedido = $1 AND estado IN ('creado', 'capturando') ORDER BY id_pago DESC LIMIT 1;
+
+-- name: GetPagoCapturadoForUpdate :one
+SELECT * FROM pago WHERE id_pedido = $1 AND estado = 'capturado' ORDER BY id_pago DESC LIMIT 1 FOR UPDATE;
+
+-- name: ListPagosDePedidos :many
+SELECT * FROM pago WHERE id_pedido = ANY(sqlc.arg(ids)::int[]) ORDER BY id_pedido, id_pago;
+
+-- name: UpdatePagoEstado :one
+UPDATE pago SET estado = $2, actualizado = NOW() WHERE id_pago = $1 RETURNING *;
+
+-- name: AnularPagosAbiertos :exec
+UPDATE pago SET estado = 'anulado', actualizado = NOW() WHERE id_pedido = $1 AND estado = 'creado';
+
+-- name: SumarPagoReembolsado :exec
+UPDATE pago SET reembolsado = reembolsado + $2, actualizado = NOW() WHERE id_pago = $1;
+
+-- Devuelve 0 filas si el evento ya se había procesado
+-- name: CreatePagoEvento :execrows
+INSERT INTO pago_evento (proveedor, id_evento, tipo) VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: ListCategorias :many
+SELECT * FROM categoria ORDER BY LOWER(nombre), id_categoria;
+
+-- name: CreateCategoria :one
+INSERT INTO categoria (nombre, slug, id_padre) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: UpdateCategoria :one
+UPDATE categoria SET nombre = $2, slug = $3, id_padre = $4 WHERE id_categoria = $1 RETURNING *;
+
+-- name: DeleteCategoria :execrows
+DELETE FROM categoria WHERE id_categoria = $1;
+
+-- name: RenombrarCategoriaProductos :exec
+UPDATE producto SET categoria = $2 WHERE id_categoria = $1;
+
+-- name: QuitarCategoriaProductos :exec
+UPDATE producto SET categoria = '', id_categoria = NULL WHERE id_categoria = $1;
+
+-- La lista de productos con sus filtros y su orden no está acá: la arma
+-- catalogo.Consulta, porque el ORDER BY depende del campo elegido.
+
+-- Cuántos productos publicados de cada categoría pasan los demás filtros
+-- (precio, stock y búsqueda), para el panel de filtros
+-- name: ContarProdPorCategoria :many
+SELECT p.id_categoria, COUNT(*) AS cantidad
+FROM producto p JOIN moneda m ON m.codigo = p.moneda
+WHERE NOT p.archivado AND p.id_categoria IS NOT NULL
+    AND (sqlc.arg(precio_min)::numeric = 0 OR p.precio * m.tasa >= sqlc.arg(precio_min)::numeric)
+    AND (sqlc.arg(precio_max)::numeric = 0 OR p.precio * m.tasa <= sqlc.arg(precio_max)::numeric)
+    AND (NOT sqlc.arg(con_stock)::bool OR p.stock > 0)
+    AND (sqlc.arg(consulta)::text = ''
+        OR documento_producto(p.nombre_producto, p.categoria, p.descripcion) @@ to_tsquery('es', sqlc.arg(consulta)::text))
+GROUP BY p.id_categoria;
@@ -1,37 +1,380 @@
+-- Monedas con su tasa contra el peso (ARS), la moneda base: cuántos pesos
+-- vale una unidad. Los admins las actualizan desde /admin/monedas.
+CREATE TABLE moneda (
+    codigo VARCHAR(3) PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL,
+    simbolo VARCHAR(5) NOT NULL,
+    tasa DECIMAL(18,6) NOT NULL CHECK (tasa > 0),
+    actualizado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (codigo <> 'ARS' OR tasa = 1)
+);
+
+-- La tasa del dólar es solo un valor inicial
+INSERT INTO moneda (codigo, nombre, simbolo, tasa) VALUES
+    ('ARS', 'Peso argentino', '$', 1),
+    ('USD', 'Dólar estadounidense', 'US$', 1000);
+
+-- Categorías de productos. id_padre arma el árbol (NULL es una raíz) y slug
+-- es el identificador de las URLs (/categoria/{slug}). Una categoría con
+-- hijas no se puede borrar.
+CREATE TABLE categoria (
+    id_categoria SERIAL PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL,
+    slug VARCHAR(60) NOT NULL UNIQUE CHECK (slug ~ '^[a-z0-9]+(-[a-z0-9]+)*$'),
+    id_padre INT REFERENCES categoria(id_categoria) ON DELETE RESTRICT,
+    CHECK (id_padre <> id_categoria)
+);
+
+INSERT INTO categoria (nombre, slug) VALUES
+    ('Computadoras', 'computadoras'),
+    ('Periféricos', 'perifericos'),
+    ('Componentes', 'componentes'),
+    ('Otros', 'otros');
+INSERT INTO categoria (nombre, slug, id_padre)
+    SELECT v.nombre, v.slug, c.id_categoria
+    FROM (VALUES ('PC de escritorio', 'pc'), ('Laptops', 'laptop')) AS v(nombre, slug), categoria c
+    WHERE c.slug = 'computadoras';
+
 CREATE TABLE producto (
     id_producto SERIAL PRIMARY KEY,
     nombre_producto VARCHAR(100) NOT NULL,
     descripcion TEXT NOT NULL DEFAULT '',
     precio DECIMAL(10,2) NOT NULL,
-    stock INT NOT NULL DEFAULT 0,
+    moneda VARCHAR(3) NOT NULL DEFAULT 'ARS' REFERENCES moneda(codigo),
+    stock INT NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    -- categoria repite el nombre de id_categoria para la búsqueda y se
+    -- actualiza al renombrar la categoría; cupones, promociones e impuestos
+    -- comparan id_categoria
     categoria VARCHAR(50) NOT NULL DEFAULT '',
-    imagen TEXT NOT NULL DEFAULT ''
+    id_categoria INT REFERENCES categoria(id_categoria) ON DELETE SET NULL,
+    imagen TEXT NOT NULL DEFAULT '',
+    -- Peso en gramos, para cotizar el envío
+    peso INT NOT NULL DEFAULT 0 CHECK (peso >= 0),
+    -- Los productos no se borran: archivados dejan de verse en la tienda pero
+    -- siguen referenciados por los pedidos y se pueden restaurar
+    archivado BOOLEAN NOT NULL DEFAULT false
 );
 
+-- Búsqueda de productos. La configuración "es" es la de español con unaccent
+-- antes del stemming, así "periférico" encuentra "PERIFERICOS".
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE TEXT SEARCH CONFIGURATION es (COPY = spanish);
+ALTER TEXT SEARCH CONFIGURATION es
+    ALTER MAPPING FOR hword, hword_part, word WITH unaccent, spanish_stem;
+
+-- documento_producto es el texto donde se busca: el nombre pesa más que la
+-- categoría y ésta más que la descripción. Las consultas usan la misma
+-- expresión que el índice para que Postgres lo aproveche.
+CREATE FUNCTION documento_producto(nombre TEXT, categoria TEXT, descripcion TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT setweight(to_tsvector('es', nombre), 'A')
+        || setweight(to_tsvector('es', categoria), 'B')
+        || setweight(to_tsvector('es', descripcion), 'C')
+$$;
+
+CREATE INDEX producto_busqueda_idx ON producto
+    USING GIN (documento_producto(nombre_producto, categoria, descripcion));
+
 CREATE TABLE usuario (
     id_usuario SERIAL PRIMARY KEY,
     nombre_usuario VARCHAR(50) NOT NULL,
-    email VARCHAR(255) UNIQUE NOT NULL
+    email VARCHAR(255) UNIQUE NOT NULL,
+    password_hash TEXT NOT NULL DEFAULT '',
+    rol VARCHAR(20) NOT NULL DEFAULT 'cliente' CHECK (rol IN ('cliente', 'staff', 'admin')),
+    -- Región fiscal del comprador; elige qué alícuotas de impuesto se le aplican
+    region VARCHAR(50) NOT NULL DEFAULT ''
 );
 
-CREATE TABLE venta (
-    id_venta SERIAL PRIMARY KEY,
-    id_producto INT NOT NULL,
+-- Alícuotas de impuesto por categoría de producto y región del comprador.
+-- id_categoria y region en NULL valen para cualquiera; una categoría vale
+-- también para sus subcategorías. Cada línea usa la regla más específica:
+-- categoría y región, solo categoría, solo región y por último la general;
+-- entre reglas de categoría gana la más cercana a la del producto. Con incluido el precio ya trae el impuesto y solo se discrimina;
+-- sin incluido se suma al total a pagar.
+CREATE TABLE impuesto (
+    id_impuesto SERIAL PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL,
+    alicuota DECIMAL(5,2) NOT NULL CHECK (alicuota > 0 AND alicuota <= 100),
+    id_categoria INT REFERENCES categoria(id_categoria) ON DELETE RESTRICT,
+    region VARCHAR(50),
+    incluido BOOLEAN NOT NULL DEFAULT TRUE,
+    creado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX impuesto_categoria_region_idx ON impuesto (COALESCE(id_categoria, 0), LOWER(COALESCE(region, '')));
+
+-- IVA general, incluido en los precios de lista
+INSERT INTO impuesto (nombre, alicuota) VALUES ('IVA 21%', 21);
+
+-- Cupones de descuento. Los de tipo 'porcentaje' descuentan porcentaje (1 a
+-- 100) y los de tipo 'monto' un importe fijo; monto y minimo están en moneda.
+-- Las fechas y los límites de uso en NULL no restringen, y id_categoria en
+-- NULL aplica el cupón a todo el carrito; si no, a esa categoría y sus
+-- subcategorías. Los usos se cuentan desde pedido.cupon.
+CREATE TABLE cupon (
+    codigo VARCHAR(40) PRIMARY KEY,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('porcentaje','monto')),
+    porcentaje INT NOT NULL DEFAULT 0,
+    monto DECIMAL(10,2) NOT NULL DEFAULT 0,
+    moneda VARCHAR(3) NOT NULL DEFAULT 'ARS' REFERENCES moneda(codigo),
+    minimo DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (minimo >= 0),
+    desde TIMESTAMP WITH TIME ZONE,
+    hasta TIMESTAMP WITH TIME ZONE,
+    usos_maximos INT CHECK (usos_maximos > 0),
+    usos_por_usuario INT CHECK (usos_por_usuario > 0),
+    id_categoria INT REFERENCES categoria(id_categoria) ON DELETE RESTRICT,
+    activo BOOLEAN NOT NULL DEFAULT TRUE,
+    creado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((tipo = 'porcentaje' AND porcentaje BETWEEN 1 AND 100 AND monto = 0)
+        OR (tipo = 'monto' AND monto > 0 AND porcentaje = 0)),
+    CHECK (desde IS NULL OR hasta IS NULL OR hasta > desde)
+);
+
+-- Promociones automáticas: se evalúan en cada render del carrito y en el
+-- checkout, en orden de id. Qué columnas usa cada tipo:
+--   'categoria': llevando al menos cantidad unidades de id_categoria (o sus
+--                subcategorías), esas líneas tienen porcentaje de descuento
+--   'nxm':       cada cantidad unidades de id_producto se pagan paga (2x1)
+--   'regalo':    con un total de al menos minimo (en moneda), una unidad de
+--                id_producto va de regalo si está en el carrito
+CREATE TABLE promocion (
+    id_promocion SERIAL PRIMARY KEY,
+    nombre VARCHAR(100) NOT NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('categoria','nxm','regalo')),
+    id_categoria INT REFERENCES categoria(id_categoria) ON DELETE RESTRICT,
+    id_producto INT REFERENCES producto(id_producto) ON DELETE CASCADE,
+    cantidad INT NOT NULL DEFAULT 0,
+    paga INT NOT NULL DEFAULT 0,
+    porcentaje INT NOT NULL DEFAULT 0,
+    minimo DECIMAL(10,2) NOT NULL DEFAULT 0,
+    moneda VARCHAR(3) NOT NULL DEFAULT 'ARS' REFERENCES moneda(codigo),
+    activa BOOLEAN NOT NULL DEFAULT TRUE,
+    creado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (tipo <> 'categoria' OR (id_categoria IS NOT NULL AND cantidad >= 1 AND porcentaje BETWEEN 1 AND 100)),
+    CHECK (tipo <> 'nxm' OR (id_producto IS NOT NULL AND paga >= 1 AND cantidad > paga)),
+    CHECK (tipo <> 'regalo' OR (id_producto IS NOT NULL AND minimo > 0))
+);
+
+-- Libreta de direcciones de cada usuario
+CREATE TABLE direccion (
+    id_direccion SERIAL PRIMARY KEY,
+    id_usuario INT NOT NULL REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    alias VARCHAR(50) NOT NULL DEFAULT '',
+    destinatario VARCHAR(100) NOT NULL,
+    calle VARCHAR(200) NOT NULL,
+    ciudad VARCHAR(100) NOT NULL,
+    provincia VARCHAR(50) NOT NULL,
+    codigo_postal VARCHAR(10) NOT NULL,
+    telefono VARCHAR(30) NOT NULL DEFAULT '',
+    creada TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX direccion_id_usuario_idx ON direccion (id_usuario);
+
+-- Métodos de envío. El tipo elige cómo se cotiza:
+--   'retiro': se retira en el local, sin dirección; cuesta costo (normalmente 0)
+--   'fijo':   costo fijo a cualquier dirección
+--   'tabla':  según la provincia de la dirección y el peso, con tarifa_envio
+-- costo está en moneda.
+CREATE TABLE metodo_envio (
+    id_metodo SERIAL PRIMARY KEY,
+    nombre VARCHAR(100) NOT NULL,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('retiro','fijo','tabla')),
+    costo DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (costo >= 0),
+    moneda VARCHAR(3) NOT NULL DEFAULT 'ARS' REFERENCES moneda(codigo),
+    activo BOOLEAN NOT NULL DEFAULT TRUE,
+    creado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO metodo_envio (nombre, tipo) VALUES ('Retiro en el local', 'retiro');
+
+-- Tarifas de los métodos 'tabla': envíos a provincia (NULL es el resto del
+-- país) de hasta peso_hasta gramos. Gana la fila de menor peso_hasta que
+-- alcance, primero entre las de la provincia. costo en la moneda del método.
+CREATE TABLE tarifa_envio (
+    id_tarifa SERIAL PRIMARY KEY,
+    id_metodo INT NOT NULL REFERENCES metodo_envio(id_metodo) ON DELETE CASCADE,
+    provincia VARCHAR(50),
+    peso_hasta INT NOT NULL CHECK (peso_hasta > 0),
+    costo DECIMAL(10,2) NOT NULL CHECK (costo >= 0)
+);
+
+CREATE INDEX tarifa_envio_id_metodo_idx ON tarifa_envio (id_metodo);
+
+CREATE TABLE pedido (
+    id_pedido SERIAL PRIMARY KEY,
     id_usuario INT NOT NULL,
-    cantidad INT NOT NULL,
+    estado VARCHAR(20) NOT NULL DEFAULT 'pendiente'
+        CHECK (estado IN ('pendiente','esperando_pago','pagado','enviado','entregado','cancelado')),
     total DECIMAL(10,2) NOT NULL,
-    fecha TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (id_producto) REFERENCES producto(id_producto),
+    total_reembolsado DECIMAL(10,2) NOT NULL DEFAULT 0,
+    -- Moneda en la que se cobró (todos los importes del pedido están en ella)
+    -- y su tasa contra el peso al momento de la compra
+    moneda VARCHAR(3) NOT NULL DEFAULT 'ARS' REFERENCES moneda(codigo),
+    tasa DECIMAL(18,6) NOT NULL DEFAULT 1,
+    -- Cupón aplicado y lo que descontó, y lo que descontaron las promociones;
+    -- total ya tiene ambos descuentos restados
+    cupon VARCHAR(40) REFERENCES cupon(codigo) ON DELETE SET NULL,
+    descuento DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (descuento >= 0),
+    descuento_promociones DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (descuento_promociones >= 0),
+    -- Región fiscal del comprador al momento de la compra y los impuestos que
+    -- se sumaron al total (los incluidos en el precio no cambian el total)
+    region VARCHAR(50) NOT NULL DEFAULT '',
+    impuestos DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (impuestos >= 0),
+    -- Envío: nombre del método, dirección copiada como texto (vacía si se
+    -- retira) y costo, que está sumado al total
+    envio VARCHAR(100) NOT NULL DEFAULT '',
+    direccion_envio TEXT NOT NULL DEFAULT '',
+    costo_envio DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (costo_envio >= 0),
+    fecha TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actualizado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (id_usuario) REFERENCES usuario(id_usuario)
 );
 
+-- Cada línea guarda nombre y precio del producto al momento de la compra,
+-- así el historial no cambia si después se edita o se borra el producto.
+CREATE TABLE pedido_item (
+    id_item SERIAL PRIMARY KEY,
+    id_pedido INT NOT NULL,
+    id_producto INT,
+    nombre_producto VARCHAR(100) NOT NULL,
+    precio_unitario DECIMAL(10,2) NOT NULL,
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    subtotal DECIMAL(10,2) NOT NULL,
+    -- Descuento de la línea (sus promociones más su parte del cupón); lo que
+    -- se reembolsa por unidad es (subtotal - descuento) / cantidad, más el
+    -- impuesto si no estaba incluido.
+    -- promociones guarda los nombres de las que se aplicaron.
+    descuento DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (descuento BETWEEN 0 AND subtotal),
+    promociones TEXT NOT NULL DEFAULT '',
+    -- Impuesto de la línea sobre subtotal - descuento: nombre y alícuota de la
+    -- regla aplicada (nombre vacío si ninguna), importe y si estaba incluido
+    impuesto_nombre VARCHAR(50) NOT NULL DEFAULT '',
+    alicuota DECIMAL(5,2) NOT NULL DEFAULT 0,
+    impuesto DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (impuesto >= 0),
+    impuesto_incluido BOOLEAN NOT NULL DEFAULT TRUE,
+    cantidad_reembolsada INT NOT NULL DEFAULT 0 CHECK (cantidad_reembolsada BETWEEN 0 AND cantidad),
+    FOREIGN KEY (id_pedido) REFERENCES pedido(id_pedido) ON DELETE CASCADE,
+    FOREIGN KEY (id_producto) REFERENCES producto(id_producto) ON DELETE SET NULL
+);
+
+CREATE INDEX pedido_id_usuario_idx ON pedido (id_usuario);
+CREATE INDEX pedido_estado_idx ON pedido (estado);
+CREATE INDEX pedido_cupon_idx ON pedido (cupon);
+CREATE INDEX pedido_fecha_idx ON pedido (fecha);
+CREATE INDEX pedido_item_id_pedido_idx ON pedido_item (id_pedido);
+
+-- Un registro por cada cambio de estado. La primera fila de cada pedido tiene
+-- estado_anterior en NULL; id_usuario es quien hizo el cambio (en el alta,
+-- el dueño del pedido).
+CREATE TABLE pedido_historial (
+    id_historial SERIAL PRIMARY KEY,
+    id_pedido INT NOT NULL,
+    estado_anterior VARCHAR(20),
+    estado VARCHAR(20) NOT NULL,
+    id_usuario INT,
+    fecha TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (id_pedido) REFERENCES pedido(id_pedido) ON DELETE CASCADE,
+    FOREIGN KEY (id_usuario) REFERENCES usuario(id_usuario) ON DELETE SET NULL
+);
+
+CREATE INDEX pedido_historial_id_pedido_idx ON pedido_historial (id_pedido);
+
+-- Cobros a través de un proveedor de pagos. referencia es el id del intento
+-- de pago en el proveedor; un pedido puede tener varios si un intento falla.
+-- reembolsado acumula lo que se devolvió por el proveedor, incluidos los
+-- reembolsos que todavía no se le pidieron. 'capturando' es un pago
+-- autorizado cuya captura se pidió y todavía no tiene respuesta.
+CREATE TABLE pago (
+    id_pago SERIAL PRIMARY KEY,
+    id_pedido INT NOT NULL REFERENCES pedido(id_pedido) ON DELETE CASCADE,
+    proveedor VARCHAR(30) NOT NULL,
+    referencia VARCHAR(100) NOT NULL,
+    monto DECIMAL(10,2) NOT NULL CHECK (monto >= 0),
+    moneda VARCHAR(3) NOT NULL REFERENCES moneda(codigo),
+    estado VARCHAR(20) NOT NULL DEFAULT 'creado'
+        CHECK (estado IN ('creado','capturando','capturado','rechazado','anulado')),
+    url TEXT NOT NULL DEFAULT '',
+    reembolsado DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (reembolsado BETWEEN 0 AND monto),
+    creado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actualizado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (proveedor, referencia)
+);
+
+CREATE INDEX pago_id_pedido_idx ON pago (id_pedido);
+
+-- Devoluciones de dinero. Cada reembolso detalla cuántas unidades de cada
+-- línea devuelve; esas unidades vuelven al stock en la misma transacción.
+-- Si el pedido se cobró por una pasarela, id_pago es ese cobro y estado_pago
+-- sigue la devolución allá: 'pendiente' hasta que la pasarela responde,
+-- después 'devuelto' o 'rechazado'. Vacío si no hay nada que devolver por ahí.
+CREATE TABLE reembolso (
+    id_reembolso SERIAL PRIMARY KEY,
+    id_pedido INT NOT NULL,
+    monto DECIMAL(10,2) NOT NULL CHECK (monto >= 0),
+    motivo TEXT NOT NULL DEFAULT '',
+    id_usuario INT,
+    fecha TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    id_pago INT REFERENCES pago(id_pago) ON DELETE SET NULL,
+    estado_pago VARCHAR(20) NOT NULL DEFAULT ''
+        CHECK (estado_pago IN ('','pendiente','devuelto','rechazado')),
+    FOREIGN KEY (id_pedido) REFERENCES pedido(id_pedido) ON DELETE CASCADE,
+    FOREIGN KEY (id_usuario) REFERENCES usuario(id_usuario) ON DELETE SET NULL
+);
+
+CREATE TABLE reembolso_item (
+    id_reembolso INT NOT NULL,
+    id_item INT NOT NULL,
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    monto DECIMAL(10,2) NOT NULL,
+    PRIMARY KEY (id_reembolso, id_item),
+    FOREIGN KEY (id_reembolso) REFERENCES reembolso(id_reembolso) ON DELETE CASCADE,
+    FOREIGN KEY (id_item) REFERENCES pedido_item(id_item) ON DELETE CASCADE
+);
+
+CREATE INDEX reembolso_id_pedido_idx ON reembolso (id_pedido);
+
+-- Eventos de webhook ya procesados: los proveedores reintentan, así que un
+-- evento repetido se reconoce por su id y no se vuelve a aplicar
+CREATE TABLE pago_evento (
+    proveedor VARCHAR(30) NOT NULL,
+    id_evento VARCHAR(100) NOT NULL,
+    tipo VARCHAR(40) NOT NULL,
+    recibido TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (proveedor, id_evento)
+);
+
 CREATE TABLE carrito (
     id_item SERIAL PRIMARY KEY,
     id_usuario INT NOT NULL,
     id_producto INT NOT NULL,
     cantidad INT NOT NULL,
-    fecha_agregado TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    fecha_agregado TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (id_producto) REFERENCES producto(id_producto),
     FOREIGN KEY (id_usuario) REFERENCES usuario(id_usuario)
 );
 
+-- Cupón que el usuario aplicó a su carrito; se vuelve a validar al comprar
+CREATE TABLE carrito_cupon (
+    id_usuario INT PRIMARY KEY REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    codigo VARCHAR(40) NOT NULL REFERENCES cupon(codigo) ON DELETE CASCADE
+);
+
+-- Envío elegido en el carrito; se vuelve a cotizar al comprar
+CREATE TABLE carrito_envio (
+    id_usuario INT PRIMARY KEY REFERENCES usuario(id_usuario) ON DELETE CASCADE,
+    id_metodo INT NOT NULL REFERENCES metodo_envio(id_metodo) ON DELETE CASCADE,
+    id_direccion INT REFERENCES direccion(id_direccion) ON DELETE SET NULL
+);
+
+CREATE TABLE sesion (
+    token_hash CHAR(64) PRIMARY KEY,
+    id_usuario INT NOT NULL,
+    csrf_token VARCHAR(64) NOT NULL,
+    moneda VARCHAR(3) NOT NULL DEFAULT 'ARS' REFERENCES moneda(codigo),
+    creada TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expira TIMESTAMP WITH TIME ZONE NOT NULL,
+    FOREIGN KEY (id_usuario) REFERENCES usuario(id_usuario) ON DELETE CASCADE
+);
+
+CREATE INDEX sesion_id_usuario_idx ON sesion (id_usuario);
@@ -5,38 +5,230 @@
 package db
 
 import (
-	"database/sql"
+	"time"
+
+	"carrito.com/dinero"
 )
 
 type Carrito struct {
-	IDItem        int32        `json:"id_item"`
-	IDUsuario     int32        `json:"id_usuario"`
-	IDProducto    int32        `json:"id_producto"`
-	Cantidad      int32        `json:"cantidad"`
-	FechaAgregado sql.NullTime `json:"fecha_agregado"`
+	IDItem        int32     `json:"id_item"`
+	IDUsuario     int32     `json:"id_usuario"`
+	IDProducto    int32     `json:"id_producto"`
+	Cantidad      int32     `json:"cantidad"`
+	FechaAgregado time.Time `json:"fecha_agregado"`
+}
+
+type CarritoCupon struct {
+	IDUsuario int32  `json:"id_usuario"`
+	Codigo    string `json:"codigo"`
+}
+
+type CarritoEnvio struct {
+	IDUsuario   int32  `json:"id_usuario"`
+	IDMetodo    int32  `json:"id_metodo"`
+	IDDireccion *int32 `json:"id_direccion"`
+}
+
+type Categoria struct {
+	IDCategoria int32  `json:"id_categoria"`
+	Nombre      string `json:"nombre"`
+	Slug        string `json:"slug"`
+	IDPadre     *int32 `json:"id_padre"`
+}
+
+type Cupon struct {
+	Codigo         string       `json:"codigo"`
+	Tipo           string       `json:"tipo"`
+	Porcentaje     int32        `json:"porcentaje"`
+	Monto          dinero.Monto `json:"monto"`
+	Moneda         string       `json:"moneda"`
+	Minimo         dinero.Monto `json:"minimo"`
+	Desde          *time.Time   `json:"desde"`
+	Hasta          *time.Time   `json:"hasta"`
+	UsosMaximos    *int32       `json:"usos_maximos"`
+	UsosPorUsuario *int32       `json:"usos_por_usuario"`
+	IDCategoria    *int32       `json:"id_categoria"`
+	Activo         bool         `json:"activo"`
+	Creado         time.Time    `json:"creado"`
+}
+
+type Direccion struct {
+	IDDireccion  int32     `json:"id_direccion"`
+	IDUsuario    int32     `json:"id_usuario"`
+	Alias        string    `json:"alias"`
+	Destinatario string    `json:"destinatario"`
+	Calle        string    `json:"calle"`
+	Ciudad       string    `json:"ciudad"`
+	Provincia    string    `json:"provincia"`
+	CodigoPostal string    `json:"codigo_postal"`
+	Telefono     string    `json:"telefono"`
+	Creada       time.Time `json:"creada"`
+}
+
+type Impuesto struct {
+	IDImpuesto  int32             `json:"id_impuesto"`
+	Nombre      string            `json:"nombre"`
+	Alicuota    dinero.Porcentaje `json:"alicuota"`
+	IDCategoria *int32            `json:"id_categoria"`
+	Region      *string           `json:"region"`
+	Incluido    bool              `json:"incluido"`
+	Creado      time.Time         `json:"creado"`
+}
+
+type MetodoEnvio struct {
+	IDMetodo int32        `json:"id_metodo"`
+	Nombre   string       `json:"nombre"`
+	Tipo     string       `json:"tipo"`
+	Costo    dinero.Monto `json:"costo"`
+	Moneda   string       `json:"moneda"`
+	Activo   bool         `json:"activo"`
+	Creado   time.Time    `json:"creado"`
+}
+
+type Moneda struct {
+	Codigo      string      `json:"codigo"`
+	Nombre      string      `json:"nombre"`
+	Simbolo     string      `json:"simbolo"`
+	Tasa        dinero.Tasa `json:"tasa"`
+	Actualizado time.Time   `json:"actualizado"`
+}
+
+type Pago struct {
+	IDPago      int32        `json:"id_pago"`
+	IDPedido    int32        `json:"id_pedido"`
+	Proveedor   string       `json:"proveedor"`
+	Referencia  string       `json:"referencia"`
+	Monto       dinero.Monto `json:"monto"`
+	Moneda      string       `json:"moneda"`
+	Estado      string       `json:"estado"`
+	Url         string       `json:"url"`
+	Reembolsado dinero.Monto `json:"reembolsado"`
+	Creado      time.Time    `json:"creado"`
+	Actualizado time.Time    `json:"actualizado"`
+}
+
+type PagoEvento struct {
+	Proveedor string    `json:"proveedor"`
+	IDEvento  string    `json:"id_evento"`
+	Tipo      string    `json:"tipo"`
+	Recibido  time.Time `json:"recibido"`
+}
+
+type Pedido struct {
+	IDPedido             int32        `json:"id_pedido"`
+	IDUsuario            int32        `json:"id_usuario"`
+	Estado               string       `json:"estado"`
+	Total                dinero.Monto `json:"total"`
+	TotalReembolsado     dinero.Monto `json:"total_reembolsado"`
+	Moneda               string       `json:"moneda"`
+	Tasa                 dinero.Tasa  `json:"tasa"`
+	Cupon                *string      `json:"cupon"`
+	Descuento            dinero.Monto `json:"descuento"`
+	DescuentoPromociones dinero.Monto `json:"descuento_promociones"`
+	Region               string       `json:"region"`
+	Impuestos            dinero.Monto `json:"impuestos"`
+	Envio                string       `json:"envio"`
+	DireccionEnvio       string       `json:"direccion_envio"`
+	CostoEnvio           dinero.Monto `json:"costo_envio"`
+	Fecha                time.Time    `json:"fecha"`
+	Actualizado          time.Time    `json:"actualizado"`
+}
+
+type PedidoHistorial struct {
+	IDHistorial    int32     `json:"id_historial"`
+	IDPedido       int32     `json:"id_pedido"`
+	EstadoAnterior *string   `json:"estado_anterior"`
+	Estado         string    `json:"estado"`
+	IDUsuario      *int32    `json:"id_usuario"`
+	Fecha          time.Time `json:"fecha"`
+}
+
+type PedidoItem struct {
+	IDItem              int32             `json:"id_item"`
+	IDPedido            int32             `json:"id_pedido"`
+	IDProducto          *int32            `json:"id_producto"`
+	NombreProducto      string            `json:"nombre_producto"`
+	PrecioUnitario      dinero.Monto      `json:"precio_unitario"`
+	Cantidad            int32             `json:"cantidad"`
+	Subtotal            dinero.Monto      `json:"subtotal"`
+	Descuento           dinero.Monto      `json:"descuento"`
+	Promociones         string            `json:"promociones"`
+	ImpuestoNombre      string            `json:"impuesto_nombre"`
+	Alicuota            dinero.Porcentaje `json:"alicuota"`
+	Impuesto            dinero.Monto      `json:"impuesto"`
+	ImpuestoIncluido    bool              `json:"impuesto_incluido"`
+	CantidadReembolsada int32             `json:"cantidad_reembolsada"`
 }
 
 type Producto struct {
-	IDProducto     int32  `json:"id_producto"`
-	NombreProducto string `json:"nombre_producto"`
-	Descripcion    string `json:"descripcion"`
-	Precio         string `json:"precio"`
-	Stock          int32  `json:"stock"`
-	Categoria      string `json:"categoria"`
-	Imagen         string `json:"imagen"`
+	IDProducto     int32        `json:"id_producto"`
+	NombreProducto string       `json:"nombre_producto"`
+	Descripcion    string       `json:"descripcion"`
+	Precio         dinero.Monto `json:"precio"`
+	Moneda         string       `json:"moneda"`
+	Stock          int32        `json:"stock"`
+	Categoria      string       `json:"categoria"`
+	IDCategoria    *int32       `json:"id_categoria"`
+	Imagen         string       `json:"imagen"`
+	Peso           int32        `json:"peso"`
+	Archivado      bool         `json:"archivado"`
+}
+
+type Promocion struct {
+	IDPromocion int32        `json:"id_promocion"`
+	Nombre      string       `json:"nombre"`
+	Tipo        string       `json:"tipo"`
+	IDCategoria *int32       `json:"id_categoria"`
+	IDProducto  *int32       `json:"id_producto"`
+	Cantidad    int32        `json:"cantidad"`
+	Paga        int32        `json:"paga"`
+	Porcentaje  int32        `json:"porcentaje"`
+	Minimo      dinero.Monto `json:"minimo"`
+	Moneda      string       `json:"moneda"`
+	Activa      bool         `json:"activa"`
+	Creado      time.Time    `json:"creado"`
+}
+
+type Reembolso struct {
+	IDReembolso int32        `json:"id_reembolso"`
+	IDPedido    int32        `json:"id_pedido"`
+	Monto       dinero.Monto `json:"monto"`
+	Motivo      string       `json:"motivo"`
+	IDUsuario   *int32       `json:"id_usuario"`
+	Fecha       time.Time    `json:"fecha"`
+	IDPago      *int32       `json:"id_pago"`
+	EstadoPago  string       `json:"estado_pago"`
+}
+
+type ReembolsoItem struct {
+	IDReembolso int32        `json:"id_reembolso"`
+	IDItem      int32        `json:"id_item"`
+	Cantidad    int32        `json:"cantidad"`
+	Monto       dinero.Monto `json:"monto"`
+}
+
+type Sesion struct {
+	TokenHash string    `json:"token_hash"`
+	IDUsuario int32     `json:"id_usuario"`
+	CsrfToken string    `json:"csrf_token"`
+	Moneda    string    `json:"moneda"`
+	Creada    time.Time `json:"creada"`
+	Expira    time.Time `json:"expira"`
+}
+
+type TarifaEnvio struct {
+	IDTarifa  int32        `json:"id_tarifa"`
+	IDMetodo  int32        `json:"id_metodo"`
+	Provincia *string      `json:"provincia"`
+	PesoHasta int32        `json:"peso_hasta"`
+	Costo     dinero.Monto `json:"costo"`
 }
 
 type Usuario struct {
 	IDUsuario     int32  `json:"id_usuario"`
 	NombreUsuario string `json:"nombre_usuario"`
 	Email         string `json:"email"`
-}
-
-type Ventum struct {
-	IDVenta    int32        `json:"id_venta"`
-	IDProducto int32        `json:"id_producto"`
-	IDUsuario  int32        `json:"id_usuario"`
-	Cantidad   int32        `json:"cantidad"`
-	Total      string       `json:"total"`
-	Fecha      sql.NullTime `json:"fecha"`
+	PasswordHash  string `json:"-"`
+	Rol           string `json:"rol"`
+	Region        string `json:"region"`
 }
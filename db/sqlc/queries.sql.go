@@ -7,11 +7,16 @@ package db
 
 import (
 	"context"
-	"database/sql"
+	"time"
+
+	"carrito.com/dinero"
+	"github.com/lib/pq"
 )
 
 const addToCart = `-- name: AddToCart :one
-INSERT INTO carrito (id_usuario, id_producto, cantidad) VALUES ($1, $2, $3) RETURNING id_item, id_usuario, id_producto, cantidad, fecha_agregado
+INSERT INTO carrito (id_usuario, id_producto, cantidad)
+SELECT $1, $2, $3 WHERE EXISTS (SELECT 1 FROM producto WHERE id_producto = $2 AND NOT archivado)
+RETURNING id_item, id_usuario, id_producto, cantidad, fecha_agregado
 `
 
 type AddToCartParams struct {
@@ -20,6 +25,7 @@ type AddToCartParams struct {
 	Cantidad   int32 `json:"cantidad"`
 }
 
+// Un producto archivado no se puede agregar: no inserta nada y devuelve sql.ErrNoRows
 func (q *Queries) AddToCart(ctx context.Context, arg AddToCartParams) (Carrito, error) {
 	row := q.db.QueryRowContext(ctx, addToCart, arg.IDUsuario, arg.IDProducto, arg.Cantidad)
 	var i Carrito
@@ -33,304 +39,2132 @@ func (q *Queries) AddToCart(ctx context.Context, arg AddToCartParams) (Carrito,
 	return i, err
 }
 
-const createProd = `-- name: CreateProd :one
-INSERT INTO producto (nombre_producto, descripcion, precio, stock, categoria, imagen) VALUES ($1,$2, $3, $4, $5, $6) RETURNING id_producto, nombre_producto, descripcion, precio, stock, categoria, imagen
+const anularPagosAbiertos = `-- name: AnularPagosAbiertos :exec
+UPDATE pago SET estado = 'anulado', actualizado = NOW() WHERE id_pedido = $1 AND estado = 'creado'
 `
 
-type CreateProdParams struct {
-	NombreProducto string `json:"nombre_producto"`
-	Descripcion    string `json:"descripcion"`
-	Precio         string `json:"precio"`
-	Stock          int32  `json:"stock"`
-	Categoria      string `json:"categoria"`
-	Imagen         string `json:"imagen"`
+func (q *Queries) AnularPagosAbiertos(ctx context.Context, idPedido int32) error {
+	_, err := q.db.ExecContext(ctx, anularPagosAbiertos, idPedido)
+	return err
 }
 
-func (q *Queries) CreateProd(ctx context.Context, arg CreateProdParams) (Producto, error) {
-	row := q.db.QueryRowContext(ctx, createProd,
-		arg.NombreProducto,
-		arg.Descripcion,
-		arg.Precio,
-		arg.Stock,
-		arg.Categoria,
-		arg.Imagen,
+const archivarProd = `-- name: ArchivarProd :execrows
+UPDATE producto SET archivado = true WHERE id_producto = $1
+`
+
+func (q *Queries) ArchivarProd(ctx context.Context, idProducto int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, archivarProd, idProducto)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const bloquearUsuario = `-- name: BloquearUsuario :one
+SELECT id_usuario FROM usuario WHERE id_usuario = $1 FOR UPDATE
+`
+
+// Bloquea al comprador durante el checkout: dos compras simultáneas del mismo
+// usuario se hacen una después de la otra y la segunda ve el carrito vacío
+func (q *Queries) BloquearUsuario(ctx context.Context, idUsuario int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, bloquearUsuario, idUsuario)
+	var id_usuario int32
+	err := row.Scan(&id_usuario)
+	return id_usuario, err
+}
+
+const contarProdPorCategoria = `-- name: ContarProdPorCategoria :many
+
+SELECT p.id_categoria, COUNT(*) AS cantidad
+FROM producto p JOIN moneda m ON m.codigo = p.moneda
+WHERE NOT p.archivado AND p.id_categoria IS NOT NULL
+    AND ($1::numeric = 0 OR p.precio * m.tasa >= $1::numeric)
+    AND ($2::numeric = 0 OR p.precio * m.tasa <= $2::numeric)
+    AND (NOT $3::bool OR p.stock > 0)
+    AND ($4::text = ''
+        OR documento_producto(p.nombre_producto, p.categoria, p.descripcion) @@ to_tsquery('es', $4::text))
+GROUP BY p.id_categoria
+`
+
+type ContarProdPorCategoriaParams struct {
+	PrecioMin dinero.Monto `json:"precio_min"`
+	PrecioMax dinero.Monto `json:"precio_max"`
+	ConStock  bool         `json:"con_stock"`
+	Consulta  string       `json:"consulta"`
+}
+
+type ContarProdPorCategoriaRow struct {
+	IDCategoria *int32 `json:"id_categoria"`
+	Cantidad    int64  `json:"cantidad"`
+}
+
+// La lista de productos con sus filtros y su orden no está acá: la arma
+// catalogo.Consulta, porque el ORDER BY depende del campo elegido.
+// Cuántos productos publicados de cada categoría pasan los demás filtros
+// (precio, stock y búsqueda), para el panel de filtros
+func (q *Queries) ContarProdPorCategoria(ctx context.Context, arg ContarProdPorCategoriaParams) ([]ContarProdPorCategoriaRow, error) {
+	rows, err := q.db.QueryContext(ctx, contarProdPorCategoria,
+		arg.PrecioMin,
+		arg.PrecioMax,
+		arg.ConStock,
+		arg.Consulta,
 	)
-	var i Producto
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ContarProdPorCategoriaRow{}
+	for rows.Next() {
+		var i ContarProdPorCategoriaRow
+		if err := rows.Scan(&i.IDCategoria, &i.Cantidad); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const contarUsosCupon = `-- name: ContarUsosCupon :one
+SELECT COUNT(*) FROM pedido WHERE cupon = $1 AND estado <> 'cancelado'
+`
+
+// Los pedidos cancelados no consumen usos del cupón
+func (q *Queries) ContarUsosCupon(ctx context.Context, cupon *string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, contarUsosCupon, cupon)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const contarUsosCuponUsuario = `-- name: ContarUsosCuponUsuario :one
+SELECT COUNT(*) FROM pedido WHERE cupon = $1 AND id_usuario = $2 AND estado <> 'cancelado'
+`
+
+type ContarUsosCuponUsuarioParams struct {
+	Cupon     *string `json:"cupon"`
+	IDUsuario int32   `json:"id_usuario"`
+}
+
+func (q *Queries) ContarUsosCuponUsuario(ctx context.Context, arg ContarUsosCuponUsuarioParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, contarUsosCuponUsuario, arg.Cupon, arg.IDUsuario)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createCategoria = `-- name: CreateCategoria :one
+INSERT INTO categoria (nombre, slug, id_padre) VALUES ($1, $2, $3) RETURNING id_categoria, nombre, slug, id_padre
+`
+
+type CreateCategoriaParams struct {
+	Nombre  string `json:"nombre"`
+	Slug    string `json:"slug"`
+	IDPadre *int32 `json:"id_padre"`
+}
+
+func (q *Queries) CreateCategoria(ctx context.Context, arg CreateCategoriaParams) (Categoria, error) {
+	row := q.db.QueryRowContext(ctx, createCategoria, arg.Nombre, arg.Slug, arg.IDPadre)
+	var i Categoria
 	err := row.Scan(
-		&i.IDProducto,
-		&i.NombreProducto,
-		&i.Descripcion,
-		&i.Precio,
-		&i.Stock,
-		&i.Categoria,
-		&i.Imagen,
+		&i.IDCategoria,
+		&i.Nombre,
+		&i.Slug,
+		&i.IDPadre,
 	)
 	return i, err
 }
 
-const createUser = `-- name: CreateUser :one
-INSERT INTO usuario (nombre_usuario, email) VALUES ($1, $2) RETURNING id_usuario, nombre_usuario, email
+const createCupon = `-- name: CreateCupon :one
+INSERT INTO cupon (codigo, tipo, porcentaje, monto, moneda, minimo, desde, hasta, usos_maximos, usos_por_usuario, id_categoria)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING codigo, tipo, porcentaje, monto, moneda, minimo, desde, hasta, usos_maximos, usos_por_usuario, id_categoria, activo, creado
 `
 
-type CreateUserParams struct {
-	NombreUsuario string `json:"nombre_usuario"`
-	Email         string `json:"email"`
+type CreateCuponParams struct {
+	Codigo         string       `json:"codigo"`
+	Tipo           string       `json:"tipo"`
+	Porcentaje     int32        `json:"porcentaje"`
+	Monto          dinero.Monto `json:"monto"`
+	Moneda         string       `json:"moneda"`
+	Minimo         dinero.Monto `json:"minimo"`
+	Desde          *time.Time   `json:"desde"`
+	Hasta          *time.Time   `json:"hasta"`
+	UsosMaximos    *int32       `json:"usos_maximos"`
+	UsosPorUsuario *int32       `json:"usos_por_usuario"`
+	IDCategoria    *int32       `json:"id_categoria"`
 }
 
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (Usuario, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.NombreUsuario, arg.Email)
-	var i Usuario
-	err := row.Scan(&i.IDUsuario, &i.NombreUsuario, &i.Email)
+func (q *Queries) CreateCupon(ctx context.Context, arg CreateCuponParams) (Cupon, error) {
+	row := q.db.QueryRowContext(ctx, createCupon,
+		arg.Codigo,
+		arg.Tipo,
+		arg.Porcentaje,
+		arg.Monto,
+		arg.Moneda,
+		arg.Minimo,
+		arg.Desde,
+		arg.Hasta,
+		arg.UsosMaximos,
+		arg.UsosPorUsuario,
+		arg.IDCategoria,
+	)
+	var i Cupon
+	err := row.Scan(
+		&i.Codigo,
+		&i.Tipo,
+		&i.Porcentaje,
+		&i.Monto,
+		&i.Moneda,
+		&i.Minimo,
+		&i.Desde,
+		&i.Hasta,
+		&i.UsosMaximos,
+		&i.UsosPorUsuario,
+		&i.IDCategoria,
+		&i.Activo,
+		&i.Creado,
+	)
 	return i, err
 }
 
-const createVenta = `-- name: CreateVenta :one
-INSERT INTO venta (id_producto,id_usuario, cantidad, total, fecha) VALUES ($1, $2, $3, $4, $5) RETURNING id_venta, id_producto, id_usuario, cantidad, total, fecha
+const createDireccion = `-- name: CreateDireccion :one
+INSERT INTO direccion (id_usuario, alias, destinatario, calle, ciudad, provincia, codigo_postal, telefono)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id_direccion, id_usuario, alias, destinatario, calle, ciudad, provincia, codigo_postal, telefono, creada
 `
 
-type CreateVentaParams struct {
-	IDProducto int32        `json:"id_producto"`
-	IDUsuario  int32        `json:"id_usuario"`
-	Cantidad   int32        `json:"cantidad"`
-	Total      string       `json:"total"`
-	Fecha      sql.NullTime `json:"fecha"`
+type CreateDireccionParams struct {
+	IDUsuario    int32  `json:"id_usuario"`
+	Alias        string `json:"alias"`
+	Destinatario string `json:"destinatario"`
+	Calle        string `json:"calle"`
+	Ciudad       string `json:"ciudad"`
+	Provincia    string `json:"provincia"`
+	CodigoPostal string `json:"codigo_postal"`
+	Telefono     string `json:"telefono"`
 }
 
-func (q *Queries) CreateVenta(ctx context.Context, arg CreateVentaParams) (Ventum, error) {
-	row := q.db.QueryRowContext(ctx, createVenta,
-		arg.IDProducto,
+func (q *Queries) CreateDireccion(ctx context.Context, arg CreateDireccionParams) (Direccion, error) {
+	row := q.db.QueryRowContext(ctx, createDireccion,
 		arg.IDUsuario,
-		arg.Cantidad,
-		arg.Total,
-		arg.Fecha,
+		arg.Alias,
+		arg.Destinatario,
+		arg.Calle,
+		arg.Ciudad,
+		arg.Provincia,
+		arg.CodigoPostal,
+		arg.Telefono,
 	)
-	var i Ventum
+	var i Direccion
 	err := row.Scan(
-		&i.IDVenta,
-		&i.IDProducto,
+		&i.IDDireccion,
 		&i.IDUsuario,
-		&i.Cantidad,
-		&i.Total,
-		&i.Fecha,
+		&i.Alias,
+		&i.Destinatario,
+		&i.Calle,
+		&i.Ciudad,
+		&i.Provincia,
+		&i.CodigoPostal,
+		&i.Telefono,
+		&i.Creada,
 	)
 	return i, err
 }
 
-const deleteCart = `-- name: DeleteCart :exec
-DELETE FROM carrito WHERE id_usuario = $1
+const createImpuesto = `-- name: CreateImpuesto :one
+INSERT INTO impuesto (nombre, alicuota, id_categoria, region, incluido) VALUES ($1, $2, $3, $4, $5) RETURNING id_impuesto, nombre, alicuota, id_categoria, region, incluido, creado
 `
 
-func (q *Queries) DeleteCart(ctx context.Context, idUsuario int32) error {
-	_, err := q.db.ExecContext(ctx, deleteCart, idUsuario)
-	return err
+type CreateImpuestoParams struct {
+	Nombre      string            `json:"nombre"`
+	Alicuota    dinero.Porcentaje `json:"alicuota"`
+	IDCategoria *int32            `json:"id_categoria"`
+	Region      *string           `json:"region"`
+	Incluido    bool              `json:"incluido"`
+}
+
+func (q *Queries) CreateImpuesto(ctx context.Context, arg CreateImpuestoParams) (Impuesto, error) {
+	row := q.db.QueryRowContext(ctx, createImpuesto,
+		arg.Nombre,
+		arg.Alicuota,
+		arg.IDCategoria,
+		arg.Region,
+		arg.Incluido,
+	)
+	var i Impuesto
+	err := row.Scan(
+		&i.IDImpuesto,
+		&i.Nombre,
+		&i.Alicuota,
+		&i.IDCategoria,
+		&i.Region,
+		&i.Incluido,
+		&i.Creado,
+	)
+	return i, err
 }
 
-const deleteProd = `-- name: DeleteProd :exec
-DELETE FROM producto WHERE id_producto = $1
+const createMetodoEnvio = `-- name: CreateMetodoEnvio :one
+INSERT INTO metodo_envio (nombre, tipo, costo, moneda, activo) VALUES ($1, $2, $3, $4, $5) RETURNING id_metodo, nombre, tipo, costo, moneda, activo, creado
 `
 
-func (q *Queries) DeleteProd(ctx context.Context, idProducto int32) error {
-	_, err := q.db.ExecContext(ctx, deleteProd, idProducto)
-	return err
+type CreateMetodoEnvioParams struct {
+	Nombre string       `json:"nombre"`
+	Tipo   string       `json:"tipo"`
+	Costo  dinero.Monto `json:"costo"`
+	Moneda string       `json:"moneda"`
+	Activo bool         `json:"activo"`
+}
+
+func (q *Queries) CreateMetodoEnvio(ctx context.Context, arg CreateMetodoEnvioParams) (MetodoEnvio, error) {
+	row := q.db.QueryRowContext(ctx, createMetodoEnvio,
+		arg.Nombre,
+		arg.Tipo,
+		arg.Costo,
+		arg.Moneda,
+		arg.Activo,
+	)
+	var i MetodoEnvio
+	err := row.Scan(
+		&i.IDMetodo,
+		&i.Nombre,
+		&i.Tipo,
+		&i.Costo,
+		&i.Moneda,
+		&i.Activo,
+		&i.Creado,
+	)
+	return i, err
 }
 
-const deleteProdCarrito = `-- name: DeleteProdCarrito :exec
-DELETE FROM carrito WHERE id_item = $1
+const createPago = `-- name: CreatePago :one
+INSERT INTO pago (id_pedido, proveedor, referencia, monto, moneda, url)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id_pago, id_pedido, proveedor, referencia, monto, moneda, estado, url, reembolsado, creado, actualizado
 `
 
-func (q *Queries) DeleteProdCarrito(ctx context.Context, idItem int32) error {
-	_, err := q.db.ExecContext(ctx, deleteProdCarrito, idItem)
-	return err
+type CreatePagoParams struct {
+	IDPedido   int32        `json:"id_pedido"`
+	Proveedor  string       `json:"proveedor"`
+	Referencia string       `json:"referencia"`
+	Monto      dinero.Monto `json:"monto"`
+	Moneda     string       `json:"moneda"`
+	Url        string       `json:"url"`
 }
 
-const deleteUser = `-- name: DeleteUser :exec
-DELETE FROM usuario WHERE id_usuario = $1
+func (q *Queries) CreatePago(ctx context.Context, arg CreatePagoParams) (Pago, error) {
+	row := q.db.QueryRowContext(ctx, createPago,
+		arg.IDPedido,
+		arg.Proveedor,
+		arg.Referencia,
+		arg.Monto,
+		arg.Moneda,
+		arg.Url,
+	)
+	var i Pago
+	err := row.Scan(
+		&i.IDPago,
+		&i.IDPedido,
+		&i.Proveedor,
+		&i.Referencia,
+		&i.Monto,
+		&i.Moneda,
+		&i.Estado,
+		&i.Url,
+		&i.Reembolsado,
+		&i.Creado,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const createPagoEvento = `-- name: CreatePagoEvento :execrows
+INSERT INTO pago_evento (proveedor, id_evento, tipo) VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, idUsuario int32) error {
-	_, err := q.db.ExecContext(ctx, deleteUser, idUsuario)
-	return err
+type CreatePagoEventoParams struct {
+	Proveedor string `json:"proveedor"`
+	IDEvento  string `json:"id_evento"`
+	Tipo      string `json:"tipo"`
 }
 
-const deleteVenta = `-- name: DeleteVenta :exec
-DELETE FROM venta WHERE id_venta = $1
+// Devuelve 0 filas si el evento ya se había procesado
+func (q *Queries) CreatePagoEvento(ctx context.Context, arg CreatePagoEventoParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createPagoEvento, arg.Proveedor, arg.IDEvento, arg.Tipo)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createPedido = `-- name: CreatePedido :one
+INSERT INTO pedido (id_usuario, total, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id_pedido, id_usuario, estado, total, total_reembolsado, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio, fecha, actualizado
 `
 
-func (q *Queries) DeleteVenta(ctx context.Context, idVenta int32) error {
-	_, err := q.db.ExecContext(ctx, deleteVenta, idVenta)
-	return err
+type CreatePedidoParams struct {
+	IDUsuario            int32        `json:"id_usuario"`
+	Total                dinero.Monto `json:"total"`
+	Moneda               string       `json:"moneda"`
+	Tasa                 dinero.Tasa  `json:"tasa"`
+	Cupon                *string      `json:"cupon"`
+	Descuento            dinero.Monto `json:"descuento"`
+	DescuentoPromociones dinero.Monto `json:"descuento_promociones"`
+	Region               string       `json:"region"`
+	Impuestos            dinero.Monto `json:"impuestos"`
+	Envio                string       `json:"envio"`
+	DireccionEnvio       string       `json:"direccion_envio"`
+	CostoEnvio           dinero.Monto `json:"costo_envio"`
 }
 
-const getCartItemByUserAndProduct = `-- name: GetCartItemByUserAndProduct :one
-SELECT id_item, id_usuario, id_producto, cantidad, fecha_agregado FROM carrito WHERE id_usuario = $1 AND id_producto = $2
+func (q *Queries) CreatePedido(ctx context.Context, arg CreatePedidoParams) (Pedido, error) {
+	row := q.db.QueryRowContext(ctx, createPedido,
+		arg.IDUsuario,
+		arg.Total,
+		arg.Moneda,
+		arg.Tasa,
+		arg.Cupon,
+		arg.Descuento,
+		arg.DescuentoPromociones,
+		arg.Region,
+		arg.Impuestos,
+		arg.Envio,
+		arg.DireccionEnvio,
+		arg.CostoEnvio,
+	)
+	var i Pedido
+	err := row.Scan(
+		&i.IDPedido,
+		&i.IDUsuario,
+		&i.Estado,
+		&i.Total,
+		&i.TotalReembolsado,
+		&i.Moneda,
+		&i.Tasa,
+		&i.Cupon,
+		&i.Descuento,
+		&i.DescuentoPromociones,
+		&i.Region,
+		&i.Impuestos,
+		&i.Envio,
+		&i.DireccionEnvio,
+		&i.CostoEnvio,
+		&i.Fecha,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const createPedidoHistorial = `-- name: CreatePedidoHistorial :one
+INSERT INTO pedido_historial (id_pedido, estado_anterior, estado, id_usuario) VALUES ($1, $2, $3, $4) RETURNING id_historial, id_pedido, estado_anterior, estado, id_usuario, fecha
 `
 
-type GetCartItemByUserAndProductParams struct {
-	IDUsuario  int32 `json:"id_usuario"`
-	IDProducto int32 `json:"id_producto"`
+type CreatePedidoHistorialParams struct {
+	IDPedido       int32   `json:"id_pedido"`
+	EstadoAnterior *string `json:"estado_anterior"`
+	Estado         string  `json:"estado"`
+	IDUsuario      *int32  `json:"id_usuario"`
 }
 
-func (q *Queries) GetCartItemByUserAndProduct(ctx context.Context, arg GetCartItemByUserAndProductParams) (Carrito, error) {
-	row := q.db.QueryRowContext(ctx, getCartItemByUserAndProduct, arg.IDUsuario, arg.IDProducto)
-	var i Carrito
+func (q *Queries) CreatePedidoHistorial(ctx context.Context, arg CreatePedidoHistorialParams) (PedidoHistorial, error) {
+	row := q.db.QueryRowContext(ctx, createPedidoHistorial,
+		arg.IDPedido,
+		arg.EstadoAnterior,
+		arg.Estado,
+		arg.IDUsuario,
+	)
+	var i PedidoHistorial
 	err := row.Scan(
-		&i.IDItem,
+		&i.IDHistorial,
+		&i.IDPedido,
+		&i.EstadoAnterior,
+		&i.Estado,
 		&i.IDUsuario,
-		&i.IDProducto,
-		&i.Cantidad,
-		&i.FechaAgregado,
+		&i.Fecha,
 	)
 	return i, err
 }
 
-const getCartItems = `-- name: GetCartItems :many
-SELECT c.id_item, c.id_usuario, c.id_producto, c.cantidad, c.fecha_agregado, p.nombre_producto, p.precio FROM carrito c JOIN producto p ON c.id_producto = p.id_producto WHERE c.id_usuario = $1
+const createPedidoItem = `-- name: CreatePedidoItem :one
+INSERT INTO pedido_item (id_pedido, id_producto, nombre_producto, precio_unitario, cantidad, subtotal, descuento, promociones, impuesto_nombre, alicuota, impuesto, impuesto_incluido)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id_item, id_pedido, id_producto, nombre_producto, precio_unitario, cantidad, subtotal, descuento, promociones, impuesto_nombre, alicuota, impuesto, impuesto_incluido, cantidad_reembolsada
 `
 
-type GetCartItemsRow struct {
-	IDItem         int32        `json:"id_item"`
-	IDUsuario      int32        `json:"id_usuario"`
-	IDProducto     int32        `json:"id_producto"`
-	Cantidad       int32        `json:"cantidad"`
-	FechaAgregado  sql.NullTime `json:"fecha_agregado"`
-	NombreProducto string       `json:"nombre_producto"`
-	Precio         string       `json:"precio"`
+type CreatePedidoItemParams struct {
+	IDPedido         int32             `json:"id_pedido"`
+	IDProducto       *int32            `json:"id_producto"`
+	NombreProducto   string            `json:"nombre_producto"`
+	PrecioUnitario   dinero.Monto      `json:"precio_unitario"`
+	Cantidad         int32             `json:"cantidad"`
+	Subtotal         dinero.Monto      `json:"subtotal"`
+	Descuento        dinero.Monto      `json:"descuento"`
+	Promociones      string            `json:"promociones"`
+	ImpuestoNombre   string            `json:"impuesto_nombre"`
+	Alicuota         dinero.Porcentaje `json:"alicuota"`
+	Impuesto         dinero.Monto      `json:"impuesto"`
+	ImpuestoIncluido bool              `json:"impuesto_incluido"`
 }
 
-func (q *Queries) GetCartItems(ctx context.Context, idUsuario int32) ([]GetCartItemsRow, error) {
-	rows, err := q.db.QueryContext(ctx, getCartItems, idUsuario)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetCartItemsRow
-	for rows.Next() {
-		var i GetCartItemsRow
-		if err := rows.Scan(
-			&i.IDItem,
-			&i.IDUsuario,
-			&i.IDProducto,
-			&i.Cantidad,
-			&i.FechaAgregado,
-			&i.NombreProducto,
-			&i.Precio,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Close(); err != nil {
-		return nil, err
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) CreatePedidoItem(ctx context.Context, arg CreatePedidoItemParams) (PedidoItem, error) {
+	row := q.db.QueryRowContext(ctx, createPedidoItem,
+		arg.IDPedido,
+		arg.IDProducto,
+		arg.NombreProducto,
+		arg.PrecioUnitario,
+		arg.Cantidad,
+		arg.Subtotal,
+		arg.Descuento,
+		arg.Promociones,
+		arg.ImpuestoNombre,
+		arg.Alicuota,
+		arg.Impuesto,
+		arg.ImpuestoIncluido,
+	)
+	var i PedidoItem
+	err := row.Scan(
+		&i.IDItem,
+		&i.IDPedido,
+		&i.IDProducto,
+		&i.NombreProducto,
+		&i.PrecioUnitario,
+		&i.Cantidad,
+		&i.Subtotal,
+		&i.Descuento,
+		&i.Promociones,
+		&i.ImpuestoNombre,
+		&i.Alicuota,
+		&i.Impuesto,
+		&i.ImpuestoIncluido,
+		&i.CantidadReembolsada,
+	)
+	return i, err
 }
 
-const getProd = `-- name: GetProd :one
-SELECT id_producto, nombre_producto, descripcion, precio, stock, categoria, imagen FROM producto WHERE id_producto = $1
+const createProd = `-- name: CreateProd :one
+INSERT INTO producto (nombre_producto, descripcion, precio, stock, categoria, imagen, moneda, peso, id_categoria) VALUES ($1,$2, $3, $4, $5, $6, $7, $8, $9) RETURNING id_producto, nombre_producto, descripcion, precio, moneda, stock, categoria, id_categoria, imagen, peso, archivado
 `
 
-func (q *Queries) GetProd(ctx context.Context, idProducto int32) (Producto, error) {
-	row := q.db.QueryRowContext(ctx, getProd, idProducto)
+type CreateProdParams struct {
+	NombreProducto string       `json:"nombre_producto"`
+	Descripcion    string       `json:"descripcion"`
+	Precio         dinero.Monto `json:"precio"`
+	Stock          int32        `json:"stock"`
+	Categoria      string       `json:"categoria"`
+	Imagen         string       `json:"imagen"`
+	Moneda         string       `json:"moneda"`
+	Peso           int32        `json:"peso"`
+	IDCategoria    *int32       `json:"id_categoria"`
+}
+
+func (q *Queries) CreateProd(ctx context.Context, arg CreateProdParams) (Producto, error) {
+	row := q.db.QueryRowContext(ctx, createProd,
+		arg.NombreProducto,
+		arg.Descripcion,
+		arg.Precio,
+		arg.Stock,
+		arg.Categoria,
+		arg.Imagen,
+		arg.Moneda,
+		arg.Peso,
+		arg.IDCategoria,
+	)
 	var i Producto
 	err := row.Scan(
 		&i.IDProducto,
 		&i.NombreProducto,
 		&i.Descripcion,
 		&i.Precio,
+		&i.Moneda,
 		&i.Stock,
 		&i.Categoria,
+		&i.IDCategoria,
 		&i.Imagen,
+		&i.Peso,
+		&i.Archivado,
 	)
 	return i, err
 }
 
-const getUser = `-- name: GetUser :one
-SELECT nombre_usuario, email FROM usuario WHERE id_usuario = $1
+const createPromocion = `-- name: CreatePromocion :one
+INSERT INTO promocion (nombre, tipo, id_categoria, id_producto, cantidad, paga, porcentaje, minimo, moneda, activa)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id_promocion, nombre, tipo, id_categoria, id_producto, cantidad, paga, porcentaje, minimo, moneda, activa, creado
 `
 
-type GetUserRow struct {
-	NombreUsuario string `json:"nombre_usuario"`
-	Email         string `json:"email"`
+type CreatePromocionParams struct {
+	Nombre      string       `json:"nombre"`
+	Tipo        string       `json:"tipo"`
+	IDCategoria *int32       `json:"id_categoria"`
+	IDProducto  *int32       `json:"id_producto"`
+	Cantidad    int32        `json:"cantidad"`
+	Paga        int32        `json:"paga"`
+	Porcentaje  int32        `json:"porcentaje"`
+	Minimo      dinero.Monto `json:"minimo"`
+	Moneda      string       `json:"moneda"`
+	Activa      bool         `json:"activa"`
 }
 
-func (q *Queries) GetUser(ctx context.Context, idUsuario int32) (GetUserRow, error) {
-	row := q.db.QueryRowContext(ctx, getUser, idUsuario)
-	var i GetUserRow
-	err := row.Scan(&i.NombreUsuario, &i.Email)
+func (q *Queries) CreatePromocion(ctx context.Context, arg CreatePromocionParams) (Promocion, error) {
+	row := q.db.QueryRowContext(ctx, createPromocion,
+		arg.Nombre,
+		arg.Tipo,
+		arg.IDCategoria,
+		arg.IDProducto,
+		arg.Cantidad,
+		arg.Paga,
+		arg.Porcentaje,
+		arg.Minimo,
+		arg.Moneda,
+		arg.Activa,
+	)
+	var i Promocion
+	err := row.Scan(
+		&i.IDPromocion,
+		&i.Nombre,
+		&i.Tipo,
+		&i.IDCategoria,
+		&i.IDProducto,
+		&i.Cantidad,
+		&i.Paga,
+		&i.Porcentaje,
+		&i.Minimo,
+		&i.Moneda,
+		&i.Activa,
+		&i.Creado,
+	)
 	return i, err
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id_usuario, nombre_usuario, email FROM usuario WHERE email = $1
+const createReembolso = `-- name: CreateReembolso :one
+INSERT INTO reembolso (id_pedido, monto, motivo, id_usuario) VALUES ($1, $2, $3, $4) RETURNING id_reembolso, id_pedido, monto, motivo, id_usuario, fecha, id_pago, estado_pago
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (Usuario, error) {
-	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
-	var i Usuario
-	err := row.Scan(&i.IDUsuario, &i.NombreUsuario, &i.Email)
-	return i, err
+type CreateReembolsoParams struct {
+	IDPedido  int32        `json:"id_pedido"`
+	Monto     dinero.Monto `json:"monto"`
+	Motivo    string       `json:"motivo"`
+	IDUsuario *int32       `json:"id_usuario"`
 }
 
-const getVenta = `-- name: GetVenta :one
-SELECT id_venta, id_producto, id_usuario, cantidad, total, fecha FROM venta WHERE id_venta = $1
-`
-
-func (q *Queries) GetVenta(ctx context.Context, idVenta int32) (Ventum, error) {
-	row := q.db.QueryRowContext(ctx, getVenta, idVenta)
-	var i Ventum
+func (q *Queries) CreateReembolso(ctx context.Context, arg CreateReembolsoParams) (Reembolso, error) {
+	row := q.db.QueryRowContext(ctx, createReembolso,
+		arg.IDPedido,
+		arg.Monto,
+		arg.Motivo,
+		arg.IDUsuario,
+	)
+	var i Reembolso
 	err := row.Scan(
-		&i.IDVenta,
-		&i.IDProducto,
+		&i.IDReembolso,
+		&i.IDPedido,
+		&i.Monto,
+		&i.Motivo,
 		&i.IDUsuario,
-		&i.Cantidad,
-		&i.Total,
 		&i.Fecha,
+		&i.IDPago,
+		&i.EstadoPago,
 	)
 	return i, err
 }
 
-const getVenta_usuario = `-- name: GetVenta_usuario :one
-SELECT id_venta, id_producto, id_usuario, cantidad, total, fecha FROM venta WHERE id_usuario = $1
+const createReembolsoItem = `-- name: CreateReembolsoItem :exec
+INSERT INTO reembolso_item (id_reembolso, id_item, cantidad, monto) VALUES ($1, $2, $3, $4)
+`
+
+type CreateReembolsoItemParams struct {
+	IDReembolso int32        `json:"id_reembolso"`
+	IDItem      int32        `json:"id_item"`
+	Cantidad    int32        `json:"cantidad"`
+	Monto       dinero.Monto `json:"monto"`
+}
+
+func (q *Queries) CreateReembolsoItem(ctx context.Context, arg CreateReembolsoItemParams) error {
+	_, err := q.db.ExecContext(ctx, createReembolsoItem,
+		arg.IDReembolso,
+		arg.IDItem,
+		arg.Cantidad,
+		arg.Monto,
+	)
+	return err
+}
+
+const createSesion = `-- name: CreateSesion :exec
+INSERT INTO sesion (token_hash, id_usuario, csrf_token, expira) VALUES ($1, $2, $3, $4)
+`
+
+type CreateSesionParams struct {
+	TokenHash string    `json:"token_hash"`
+	IDUsuario int32     `json:"id_usuario"`
+	CsrfToken string    `json:"csrf_token"`
+	Expira    time.Time `json:"expira"`
+}
+
+func (q *Queries) CreateSesion(ctx context.Context, arg CreateSesionParams) error {
+	_, err := q.db.ExecContext(ctx, createSesion,
+		arg.TokenHash,
+		arg.IDUsuario,
+		arg.CsrfToken,
+		arg.Expira,
+	)
+	return err
+}
+
+const createTarifaEnvio = `-- name: CreateTarifaEnvio :one
+INSERT INTO tarifa_envio (id_metodo, provincia, peso_hasta, costo) VALUES ($1, $2, $3, $4) RETURNING id_tarifa, id_metodo, provincia, peso_hasta, costo
 `
 
-func (q *Queries) GetVenta_usuario(ctx context.Context, idUsuario int32) (Ventum, error) {
-	row := q.db.QueryRowContext(ctx, getVenta_usuario, idUsuario)
-	var i Ventum
+type CreateTarifaEnvioParams struct {
+	IDMetodo  int32        `json:"id_metodo"`
+	Provincia *string      `json:"provincia"`
+	PesoHasta int32        `json:"peso_hasta"`
+	Costo     dinero.Monto `json:"costo"`
+}
+
+func (q *Queries) CreateTarifaEnvio(ctx context.Context, arg CreateTarifaEnvioParams) (TarifaEnvio, error) {
+	row := q.db.QueryRowContext(ctx, createTarifaEnvio,
+		arg.IDMetodo,
+		arg.Provincia,
+		arg.PesoHasta,
+		arg.Costo,
+	)
+	var i TarifaEnvio
 	err := row.Scan(
-		&i.IDVenta,
-		&i.IDProducto,
-		&i.IDUsuario,
-		&i.Cantidad,
-		&i.Total,
-		&i.Fecha,
+		&i.IDTarifa,
+		&i.IDMetodo,
+		&i.Provincia,
+		&i.PesoHasta,
+		&i.Costo,
 	)
 	return i, err
 }
 
-const listProd = `-- name: ListProd :many
-SELECT id_producto, nombre_producto, descripcion, precio, stock, categoria, imagen FROM producto ORDER BY nombre_producto
+const createUser = `-- name: CreateUser :one
+INSERT INTO usuario (nombre_usuario, email, password_hash) VALUES ($1, $2, $3) RETURNING id_usuario, nombre_usuario, email, password_hash, rol, region
 `
 
-func (q *Queries) ListProd(ctx context.Context) ([]Producto, error) {
-	rows, err := q.db.QueryContext(ctx, listProd)
+type CreateUserParams struct {
+	NombreUsuario string `json:"nombre_usuario"`
+	Email         string `json:"email"`
+	PasswordHash  string `json:"-"`
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (Usuario, error) {
+	row := q.db.QueryRowContext(ctx, createUser, arg.NombreUsuario, arg.Email, arg.PasswordHash)
+	var i Usuario
+	err := row.Scan(
+		&i.IDUsuario,
+		&i.NombreUsuario,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Rol,
+		&i.Region,
+	)
+	return i, err
+}
+
+const deleteCarritoCupon = `-- name: DeleteCarritoCupon :exec
+DELETE FROM carrito_cupon WHERE id_usuario = $1
+`
+
+func (q *Queries) DeleteCarritoCupon(ctx context.Context, idUsuario int32) error {
+	_, err := q.db.ExecContext(ctx, deleteCarritoCupon, idUsuario)
+	return err
+}
+
+const deleteCarritoEnvio = `-- name: DeleteCarritoEnvio :exec
+DELETE FROM carrito_envio WHERE id_usuario = $1
+`
+
+func (q *Queries) DeleteCarritoEnvio(ctx context.Context, idUsuario int32) error {
+	_, err := q.db.ExecContext(ctx, deleteCarritoEnvio, idUsuario)
+	return err
+}
+
+const deleteCart = `-- name: DeleteCart :exec
+DELETE FROM carrito WHERE id_usuario = $1
+`
+
+func (q *Queries) DeleteCart(ctx context.Context, idUsuario int32) error {
+	_, err := q.db.ExecContext(ctx, deleteCart, idUsuario)
+	return err
+}
+
+const deleteCartItems = `-- name: DeleteCartItems :exec
+DELETE FROM carrito WHERE id_usuario = $1 AND id_item = ANY($2::int[])
+`
+
+type DeleteCartItemsParams struct {
+	IDUsuario int32   `json:"id_usuario"`
+	Ids       []int32 `json:"ids"`
+}
+
+// Borra del carrito solo las líneas que se compraron; lo que se agregó
+// durante el checkout queda para la próxima compra
+func (q *Queries) DeleteCartItems(ctx context.Context, arg DeleteCartItemsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteCartItems, arg.IDUsuario, pq.Array(arg.Ids))
+	return err
+}
+
+const deleteCategoria = `-- name: DeleteCategoria :execrows
+DELETE FROM categoria WHERE id_categoria = $1
+`
+
+func (q *Queries) DeleteCategoria(ctx context.Context, idCategoria int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteCategoria, idCategoria)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteDireccion = `-- name: DeleteDireccion :execrows
+DELETE FROM direccion WHERE id_direccion = $1 AND id_usuario = $2
+`
+
+type DeleteDireccionParams struct {
+	IDDireccion int32 `json:"id_direccion"`
+	IDUsuario   int32 `json:"id_usuario"`
+}
+
+func (q *Queries) DeleteDireccion(ctx context.Context, arg DeleteDireccionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDireccion, arg.IDDireccion, arg.IDUsuario)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteImpuesto = `-- name: DeleteImpuesto :execrows
+DELETE FROM impuesto WHERE id_impuesto = $1
+`
+
+func (q *Queries) DeleteImpuesto(ctx context.Context, idImpuesto int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteImpuesto, idImpuesto)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteMetodoEnvio = `-- name: DeleteMetodoEnvio :execrows
+DELETE FROM metodo_envio WHERE id_metodo = $1
+`
+
+func (q *Queries) DeleteMetodoEnvio(ctx context.Context, idMetodo int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMetodoEnvio, idMetodo)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteProdCarrito = `-- name: DeleteProdCarrito :execrows
+DELETE FROM carrito WHERE id_item = $1 AND id_usuario = $2
+`
+
+type DeleteProdCarritoParams struct {
+	IDItem    int32 `json:"id_item"`
+	IDUsuario int32 `json:"id_usuario"`
+}
+
+func (q *Queries) DeleteProdCarrito(ctx context.Context, arg DeleteProdCarritoParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteProdCarrito, arg.IDItem, arg.IDUsuario)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteProdDeCarritos = `-- name: DeleteProdDeCarritos :exec
+DELETE FROM carrito WHERE id_producto = $1
+`
+
+func (q *Queries) DeleteProdDeCarritos(ctx context.Context, idProducto int32) error {
+	_, err := q.db.ExecContext(ctx, deleteProdDeCarritos, idProducto)
+	return err
+}
+
+const deletePromocion = `-- name: DeletePromocion :execrows
+DELETE FROM promocion WHERE id_promocion = $1
+`
+
+func (q *Queries) DeletePromocion(ctx context.Context, idPromocion int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePromocion, idPromocion)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSesion = `-- name: DeleteSesion :exec
+DELETE FROM sesion WHERE token_hash = $1
+`
+
+func (q *Queries) DeleteSesion(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, deleteSesion, tokenHash)
+	return err
+}
+
+const deleteSesionesExpiradas = `-- name: DeleteSesionesExpiradas :exec
+DELETE FROM sesion WHERE expira <= NOW()
+`
+
+func (q *Queries) DeleteSesionesExpiradas(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteSesionesExpiradas)
+	return err
+}
+
+const deleteSesionesUsuario = `-- name: DeleteSesionesUsuario :exec
+DELETE FROM sesion WHERE id_usuario = $1
+`
+
+func (q *Queries) DeleteSesionesUsuario(ctx context.Context, idUsuario int32) error {
+	_, err := q.db.ExecContext(ctx, deleteSesionesUsuario, idUsuario)
+	return err
+}
+
+const deleteTarifaEnvio = `-- name: DeleteTarifaEnvio :execrows
+DELETE FROM tarifa_envio WHERE id_tarifa = $1
+`
+
+func (q *Queries) DeleteTarifaEnvio(ctx context.Context, idTarifa int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTarifaEnvio, idTarifa)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM usuario WHERE id_usuario = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, idUsuario int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, idUsuario)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const desactivarCupon = `-- name: DesactivarCupon :execrows
+UPDATE cupon SET activo = FALSE WHERE codigo = $1
+`
+
+func (q *Queries) DesactivarCupon(ctx context.Context, codigo string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, desactivarCupon, codigo)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getCarritoCupon = `-- name: GetCarritoCupon :one
+SELECT c.codigo, c.tipo, c.porcentaje, c.monto, c.moneda, c.minimo, c.desde, c.hasta, c.usos_maximos, c.usos_por_usuario, c.id_categoria, c.activo, c.creado FROM carrito_cupon cc JOIN cupon c ON cc.codigo = c.codigo WHERE cc.id_usuario = $1
+`
+
+func (q *Queries) GetCarritoCupon(ctx context.Context, idUsuario int32) (Cupon, error) {
+	row := q.db.QueryRowContext(ctx, getCarritoCupon, idUsuario)
+	var i Cupon
+	err := row.Scan(
+		&i.Codigo,
+		&i.Tipo,
+		&i.Porcentaje,
+		&i.Monto,
+		&i.Moneda,
+		&i.Minimo,
+		&i.Desde,
+		&i.Hasta,
+		&i.UsosMaximos,
+		&i.UsosPorUsuario,
+		&i.IDCategoria,
+		&i.Activo,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const getCarritoEnvio = `-- name: GetCarritoEnvio :one
+SELECT id_usuario, id_metodo, id_direccion FROM carrito_envio WHERE id_usuario = $1
+`
+
+func (q *Queries) GetCarritoEnvio(ctx context.Context, idUsuario int32) (CarritoEnvio, error) {
+	row := q.db.QueryRowContext(ctx, getCarritoEnvio, idUsuario)
+	var i CarritoEnvio
+	err := row.Scan(&i.IDUsuario, &i.IDMetodo, &i.IDDireccion)
+	return i, err
+}
+
+const getCartItemByUserAndProduct = `-- name: GetCartItemByUserAndProduct :one
+SELECT id_item, id_usuario, id_producto, cantidad, fecha_agregado FROM carrito WHERE id_usuario = $1 AND id_producto = $2
+`
+
+type GetCartItemByUserAndProductParams struct {
+	IDUsuario  int32 `json:"id_usuario"`
+	IDProducto int32 `json:"id_producto"`
+}
+
+func (q *Queries) GetCartItemByUserAndProduct(ctx context.Context, arg GetCartItemByUserAndProductParams) (Carrito, error) {
+	row := q.db.QueryRowContext(ctx, getCartItemByUserAndProduct, arg.IDUsuario, arg.IDProducto)
+	var i Carrito
+	err := row.Scan(
+		&i.IDItem,
+		&i.IDUsuario,
+		&i.IDProducto,
+		&i.Cantidad,
+		&i.FechaAgregado,
+	)
+	return i, err
+}
+
+const getCartItems = `-- name: GetCartItems :many
+SELECT c.id_item, c.id_usuario, c.id_producto, c.cantidad, c.fecha_agregado, p.nombre_producto, p.precio, p.moneda, p.categoria, p.id_categoria, p.peso FROM carrito c JOIN producto p ON c.id_producto = p.id_producto WHERE c.id_usuario = $1 ORDER BY c.id_producto
+`
+
+type GetCartItemsRow struct {
+	IDItem         int32        `json:"id_item"`
+	IDUsuario      int32        `json:"id_usuario"`
+	IDProducto     int32        `json:"id_producto"`
+	Cantidad       int32        `json:"cantidad"`
+	FechaAgregado  time.Time    `json:"fecha_agregado"`
+	NombreProducto string       `json:"nombre_producto"`
+	Precio         dinero.Monto `json:"precio"`
+	Moneda         string       `json:"moneda"`
+	Categoria      string       `json:"categoria"`
+	IDCategoria    *int32       `json:"id_categoria"`
+	Peso           int32        `json:"peso"`
+}
+
+func (q *Queries) GetCartItems(ctx context.Context, idUsuario int32) ([]GetCartItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCartItems, idUsuario)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetCartItemsRow{}
+	for rows.Next() {
+		var i GetCartItemsRow
+		if err := rows.Scan(
+			&i.IDItem,
+			&i.IDUsuario,
+			&i.IDProducto,
+			&i.Cantidad,
+			&i.FechaAgregado,
+			&i.NombreProducto,
+			&i.Precio,
+			&i.Moneda,
+			&i.Categoria,
+			&i.IDCategoria,
+			&i.Peso,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCartItemsForUpdate = `-- name: GetCartItemsForUpdate :many
+SELECT c.id_item, c.id_usuario, c.id_producto, c.cantidad, c.fecha_agregado, p.nombre_producto, p.precio, p.moneda, p.categoria, p.id_categoria, p.peso FROM carrito c JOIN producto p ON c.id_producto = p.id_producto WHERE c.id_usuario = $1 ORDER BY c.id_producto FOR UPDATE OF c
+`
+
+type GetCartItemsForUpdateRow struct {
+	IDItem         int32        `json:"id_item"`
+	IDUsuario      int32        `json:"id_usuario"`
+	IDProducto     int32        `json:"id_producto"`
+	Cantidad       int32        `json:"cantidad"`
+	FechaAgregado  time.Time    `json:"fecha_agregado"`
+	NombreProducto string       `json:"nombre_producto"`
+	Precio         dinero.Monto `json:"precio"`
+	Moneda         string       `json:"moneda"`
+	Categoria      string       `json:"categoria"`
+	IDCategoria    *int32       `json:"id_categoria"`
+	Peso           int32        `json:"peso"`
+}
+
+// Lo mismo que GetCartItems pero bloquea las filas del carrito hasta el fin
+// de la transacción del checkout, así un cambio de cantidad espera a la compra
+func (q *Queries) GetCartItemsForUpdate(ctx context.Context, idUsuario int32) ([]GetCartItemsForUpdateRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCartItemsForUpdate, idUsuario)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetCartItemsForUpdateRow{}
+	for rows.Next() {
+		var i GetCartItemsForUpdateRow
+		if err := rows.Scan(
+			&i.IDItem,
+			&i.IDUsuario,
+			&i.IDProducto,
+			&i.Cantidad,
+			&i.FechaAgregado,
+			&i.NombreProducto,
+			&i.Precio,
+			&i.Moneda,
+			&i.Categoria,
+			&i.IDCategoria,
+			&i.Peso,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCupon = `-- name: GetCupon :one
+SELECT codigo, tipo, porcentaje, monto, moneda, minimo, desde, hasta, usos_maximos, usos_por_usuario, id_categoria, activo, creado FROM cupon WHERE codigo = $1
+`
+
+func (q *Queries) GetCupon(ctx context.Context, codigo string) (Cupon, error) {
+	row := q.db.QueryRowContext(ctx, getCupon, codigo)
+	var i Cupon
+	err := row.Scan(
+		&i.Codigo,
+		&i.Tipo,
+		&i.Porcentaje,
+		&i.Monto,
+		&i.Moneda,
+		&i.Minimo,
+		&i.Desde,
+		&i.Hasta,
+		&i.UsosMaximos,
+		&i.UsosPorUsuario,
+		&i.IDCategoria,
+		&i.Activo,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const getCuponForUpdate = `-- name: GetCuponForUpdate :one
+SELECT codigo, tipo, porcentaje, monto, moneda, minimo, desde, hasta, usos_maximos, usos_por_usuario, id_categoria, activo, creado FROM cupon WHERE codigo = $1 FOR UPDATE
+`
+
+func (q *Queries) GetCuponForUpdate(ctx context.Context, codigo string) (Cupon, error) {
+	row := q.db.QueryRowContext(ctx, getCuponForUpdate, codigo)
+	var i Cupon
+	err := row.Scan(
+		&i.Codigo,
+		&i.Tipo,
+		&i.Porcentaje,
+		&i.Monto,
+		&i.Moneda,
+		&i.Minimo,
+		&i.Desde,
+		&i.Hasta,
+		&i.UsosMaximos,
+		&i.UsosPorUsuario,
+		&i.IDCategoria,
+		&i.Activo,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const getDireccion = `-- name: GetDireccion :one
+SELECT id_direccion, id_usuario, alias, destinatario, calle, ciudad, provincia, codigo_postal, telefono, creada FROM direccion WHERE id_direccion = $1 AND id_usuario = $2
+`
+
+type GetDireccionParams struct {
+	IDDireccion int32 `json:"id_direccion"`
+	IDUsuario   int32 `json:"id_usuario"`
+}
+
+func (q *Queries) GetDireccion(ctx context.Context, arg GetDireccionParams) (Direccion, error) {
+	row := q.db.QueryRowContext(ctx, getDireccion, arg.IDDireccion, arg.IDUsuario)
+	var i Direccion
+	err := row.Scan(
+		&i.IDDireccion,
+		&i.IDUsuario,
+		&i.Alias,
+		&i.Destinatario,
+		&i.Calle,
+		&i.Ciudad,
+		&i.Provincia,
+		&i.CodigoPostal,
+		&i.Telefono,
+		&i.Creada,
+	)
+	return i, err
+}
+
+const getImpuesto = `-- name: GetImpuesto :one
+SELECT id_impuesto, nombre, alicuota, id_categoria, region, incluido, creado FROM impuesto WHERE id_impuesto = $1
+`
+
+func (q *Queries) GetImpuesto(ctx context.Context, idImpuesto int32) (Impuesto, error) {
+	row := q.db.QueryRowContext(ctx, getImpuesto, idImpuesto)
+	var i Impuesto
+	err := row.Scan(
+		&i.IDImpuesto,
+		&i.Nombre,
+		&i.Alicuota,
+		&i.IDCategoria,
+		&i.Region,
+		&i.Incluido,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const getMetodoEnvio = `-- name: GetMetodoEnvio :one
+SELECT id_metodo, nombre, tipo, costo, moneda, activo, creado FROM metodo_envio WHERE id_metodo = $1
+`
+
+func (q *Queries) GetMetodoEnvio(ctx context.Context, idMetodo int32) (MetodoEnvio, error) {
+	row := q.db.QueryRowContext(ctx, getMetodoEnvio, idMetodo)
+	var i MetodoEnvio
+	err := row.Scan(
+		&i.IDMetodo,
+		&i.Nombre,
+		&i.Tipo,
+		&i.Costo,
+		&i.Moneda,
+		&i.Activo,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const getPagoAbierto = `-- name: GetPagoAbierto :one
+SELECT id_pago, id_pedido, proveedor, referencia, monto, moneda, estado, url, reembolsado, creado, actualizado FROM pago WHERE id_pedido = $1 AND estado IN ('creado', 'capturando') ORDER BY id_pago DESC LIMIT 1
+`
+
+func (q *Queries) GetPagoAbierto(ctx context.Context, idPedido int32) (Pago, error) {
+	row := q.db.QueryRowContext(ctx, getPagoAbierto, idPedido)
+	var i Pago
+	err := row.Scan(
+		&i.IDPago,
+		&i.IDPedido,
+		&i.Proveedor,
+		&i.Referencia,
+		&i.Monto,
+		&i.Moneda,
+		&i.Estado,
+		&i.Url,
+		&i.Reembolsado,
+		&i.Creado,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const getPagoCapturadoForUpdate = `-- name: GetPagoCapturadoForUpdate :one
+SELECT id_pago, id_pedido, proveedor, referencia, monto, moneda, estado, url, reembolsado, creado, actualizado FROM pago WHERE id_pedido = $1 AND estado = 'capturado' ORDER BY id_pago DESC LIMIT 1 FOR UPDATE
+`
+
+func (q *Queries) GetPagoCapturadoForUpdate(ctx context.Context, idPedido int32) (Pago, error) {
+	row := q.db.QueryRowContext(ctx, getPagoCapturadoForUpdate, idPedido)
+	var i Pago
+	err := row.Scan(
+		&i.IDPago,
+		&i.IDPedido,
+		&i.Proveedor,
+		&i.Referencia,
+		&i.Monto,
+		&i.Moneda,
+		&i.Estado,
+		&i.Url,
+		&i.Reembolsado,
+		&i.Creado,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const getPagoPorReferencia = `-- name: GetPagoPorReferencia :one
+SELECT id_pago, id_pedido, proveedor, referencia, monto, moneda, estado, url, reembolsado, creado, actualizado FROM pago WHERE proveedor = $1 AND referencia = $2
+`
+
+type GetPagoPorReferenciaParams struct {
+	Proveedor  string `json:"proveedor"`
+	Referencia string `json:"referencia"`
+}
+
+func (q *Queries) GetPagoPorReferencia(ctx context.Context, arg GetPagoPorReferenciaParams) (Pago, error) {
+	row := q.db.QueryRowContext(ctx, getPagoPorReferencia, arg.Proveedor, arg.Referencia)
+	var i Pago
+	err := row.Scan(
+		&i.IDPago,
+		&i.IDPedido,
+		&i.Proveedor,
+		&i.Referencia,
+		&i.Monto,
+		&i.Moneda,
+		&i.Estado,
+		&i.Url,
+		&i.Reembolsado,
+		&i.Creado,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const getPagoPorReferenciaForUpdate = `-- name: GetPagoPorReferenciaForUpdate :one
+SELECT id_pago, id_pedido, proveedor, referencia, monto, moneda, estado, url, reembolsado, creado, actualizado FROM pago WHERE proveedor = $1 AND referencia = $2 FOR UPDATE
+`
+
+type GetPagoPorReferenciaForUpdateParams struct {
+	Proveedor  string `json:"proveedor"`
+	Referencia string `json:"referencia"`
+}
+
+func (q *Queries) GetPagoPorReferenciaForUpdate(ctx context.Context, arg GetPagoPorReferenciaForUpdateParams) (Pago, error) {
+	row := q.db.QueryRowContext(ctx, getPagoPorReferenciaForUpdate, arg.Proveedor, arg.Referencia)
+	var i Pago
+	err := row.Scan(
+		&i.IDPago,
+		&i.IDPedido,
+		&i.Proveedor,
+		&i.Referencia,
+		&i.Monto,
+		&i.Moneda,
+		&i.Estado,
+		&i.Url,
+		&i.Reembolsado,
+		&i.Creado,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const getPedido = `-- name: GetPedido :one
+SELECT id_pedido, id_usuario, estado, total, total_reembolsado, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio, fecha, actualizado FROM pedido WHERE id_pedido = $1
+`
+
+func (q *Queries) GetPedido(ctx context.Context, idPedido int32) (Pedido, error) {
+	row := q.db.QueryRowContext(ctx, getPedido, idPedido)
+	var i Pedido
+	err := row.Scan(
+		&i.IDPedido,
+		&i.IDUsuario,
+		&i.Estado,
+		&i.Total,
+		&i.TotalReembolsado,
+		&i.Moneda,
+		&i.Tasa,
+		&i.Cupon,
+		&i.Descuento,
+		&i.DescuentoPromociones,
+		&i.Region,
+		&i.Impuestos,
+		&i.Envio,
+		&i.DireccionEnvio,
+		&i.CostoEnvio,
+		&i.Fecha,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const getPedidoForUpdate = `-- name: GetPedidoForUpdate :one
+SELECT id_pedido, id_usuario, estado, total, total_reembolsado, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio, fecha, actualizado FROM pedido WHERE id_pedido = $1 FOR UPDATE
+`
+
+func (q *Queries) GetPedidoForUpdate(ctx context.Context, idPedido int32) (Pedido, error) {
+	row := q.db.QueryRowContext(ctx, getPedidoForUpdate, idPedido)
+	var i Pedido
+	err := row.Scan(
+		&i.IDPedido,
+		&i.IDUsuario,
+		&i.Estado,
+		&i.Total,
+		&i.TotalReembolsado,
+		&i.Moneda,
+		&i.Tasa,
+		&i.Cupon,
+		&i.Descuento,
+		&i.DescuentoPromociones,
+		&i.Region,
+		&i.Impuestos,
+		&i.Envio,
+		&i.DireccionEnvio,
+		&i.CostoEnvio,
+		&i.Fecha,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const getProd = `-- name: GetProd :one
+SELECT id_producto, nombre_producto, descripcion, precio, moneda, stock, categoria, id_categoria, imagen, peso, archivado FROM producto WHERE id_producto = $1
+`
+
+func (q *Queries) GetProd(ctx context.Context, idProducto int32) (Producto, error) {
+	row := q.db.QueryRowContext(ctx, getProd, idProducto)
+	var i Producto
+	err := row.Scan(
+		&i.IDProducto,
+		&i.NombreProducto,
+		&i.Descripcion,
+		&i.Precio,
+		&i.Moneda,
+		&i.Stock,
+		&i.Categoria,
+		&i.IDCategoria,
+		&i.Imagen,
+		&i.Peso,
+		&i.Archivado,
+	)
+	return i, err
+}
+
+const getProdForUpdate = `-- name: GetProdForUpdate :one
+SELECT id_producto, nombre_producto, descripcion, precio, moneda, stock, categoria, id_categoria, imagen, peso, archivado FROM producto WHERE id_producto = $1 FOR UPDATE
+`
+
+func (q *Queries) GetProdForUpdate(ctx context.Context, idProducto int32) (Producto, error) {
+	row := q.db.QueryRowContext(ctx, getProdForUpdate, idProducto)
+	var i Producto
+	err := row.Scan(
+		&i.IDProducto,
+		&i.NombreProducto,
+		&i.Descripcion,
+		&i.Precio,
+		&i.Moneda,
+		&i.Stock,
+		&i.Categoria,
+		&i.IDCategoria,
+		&i.Imagen,
+		&i.Peso,
+		&i.Archivado,
+	)
+	return i, err
+}
+
+const getPromocion = `-- name: GetPromocion :one
+SELECT id_promocion, nombre, tipo, id_categoria, id_producto, cantidad, paga, porcentaje, minimo, moneda, activa, creado FROM promocion WHERE id_promocion = $1
+`
+
+func (q *Queries) GetPromocion(ctx context.Context, idPromocion int32) (Promocion, error) {
+	row := q.db.QueryRowContext(ctx, getPromocion, idPromocion)
+	var i Promocion
+	err := row.Scan(
+		&i.IDPromocion,
+		&i.Nombre,
+		&i.Tipo,
+		&i.IDCategoria,
+		&i.IDProducto,
+		&i.Cantidad,
+		&i.Paga,
+		&i.Porcentaje,
+		&i.Minimo,
+		&i.Moneda,
+		&i.Activa,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const getReembolso = `-- name: GetReembolso :one
+SELECT id_reembolso, id_pedido, monto, motivo, id_usuario, fecha, id_pago, estado_pago FROM reembolso WHERE id_reembolso = $1
+`
+
+func (q *Queries) GetReembolso(ctx context.Context, idReembolso int32) (Reembolso, error) {
+	row := q.db.QueryRowContext(ctx, getReembolso, idReembolso)
+	var i Reembolso
+	err := row.Scan(
+		&i.IDReembolso,
+		&i.IDPedido,
+		&i.Monto,
+		&i.Motivo,
+		&i.IDUsuario,
+		&i.Fecha,
+		&i.IDPago,
+		&i.EstadoPago,
+	)
+	return i, err
+}
+
+const getReembolsoForUpdate = `-- name: GetReembolsoForUpdate :one
+SELECT id_reembolso, id_pedido, monto, motivo, id_usuario, fecha, id_pago, estado_pago FROM reembolso WHERE id_reembolso = $1 FOR UPDATE
+`
+
+func (q *Queries) GetReembolsoForUpdate(ctx context.Context, idReembolso int32) (Reembolso, error) {
+	row := q.db.QueryRowContext(ctx, getReembolsoForUpdate, idReembolso)
+	var i Reembolso
+	err := row.Scan(
+		&i.IDReembolso,
+		&i.IDPedido,
+		&i.Monto,
+		&i.Motivo,
+		&i.IDUsuario,
+		&i.Fecha,
+		&i.IDPago,
+		&i.EstadoPago,
+	)
+	return i, err
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id_usuario, nombre_usuario, email, password_hash, rol, region FROM usuario WHERE id_usuario = $1
+`
+
+func (q *Queries) GetUser(ctx context.Context, idUsuario int32) (Usuario, error) {
+	row := q.db.QueryRowContext(ctx, getUser, idUsuario)
+	var i Usuario
+	err := row.Scan(
+		&i.IDUsuario,
+		&i.NombreUsuario,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Rol,
+		&i.Region,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id_usuario, nombre_usuario, email, password_hash, rol, region FROM usuario WHERE email = $1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (Usuario, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i Usuario
+	err := row.Scan(
+		&i.IDUsuario,
+		&i.NombreUsuario,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Rol,
+		&i.Region,
+	)
+	return i, err
+}
+
+const getUsuarioSesion = `-- name: GetUsuarioSesion :one
+SELECT u.id_usuario, u.nombre_usuario, u.email, u.password_hash, u.rol, u.region, s.csrf_token, s.moneda FROM sesion s JOIN usuario u ON s.id_usuario = u.id_usuario WHERE s.token_hash = $1 AND s.expira > NOW()
+`
+
+type GetUsuarioSesionRow struct {
+	Usuario   Usuario `json:"usuario"`
+	CsrfToken string  `json:"csrf_token"`
+	Moneda    string  `json:"moneda"`
+}
+
+func (q *Queries) GetUsuarioSesion(ctx context.Context, tokenHash string) (GetUsuarioSesionRow, error) {
+	row := q.db.QueryRowContext(ctx, getUsuarioSesion, tokenHash)
+	var i GetUsuarioSesionRow
+	err := row.Scan(
+		&i.Usuario.IDUsuario,
+		&i.Usuario.NombreUsuario,
+		&i.Usuario.Email,
+		&i.Usuario.PasswordHash,
+		&i.Usuario.Rol,
+		&i.Usuario.Region,
+		&i.CsrfToken,
+		&i.Moneda,
+	)
+	return i, err
+}
+
+const listCategorias = `-- name: ListCategorias :many
+SELECT id_categoria, nombre, slug, id_padre FROM categoria ORDER BY LOWER(nombre), id_categoria
+`
+
+func (q *Queries) ListCategorias(ctx context.Context) ([]Categoria, error) {
+	rows, err := q.db.QueryContext(ctx, listCategorias)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Categoria{}
+	for rows.Next() {
+		var i Categoria
+		if err := rows.Scan(
+			&i.IDCategoria,
+			&i.Nombre,
+			&i.Slug,
+			&i.IDPadre,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCupones = `-- name: ListCupones :many
+SELECT codigo, tipo, porcentaje, monto, moneda, minimo, desde, hasta, usos_maximos, usos_por_usuario, id_categoria, activo, creado FROM cupon ORDER BY creado DESC
+`
+
+func (q *Queries) ListCupones(ctx context.Context) ([]Cupon, error) {
+	rows, err := q.db.QueryContext(ctx, listCupones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Cupon{}
+	for rows.Next() {
+		var i Cupon
+		if err := rows.Scan(
+			&i.Codigo,
+			&i.Tipo,
+			&i.Porcentaje,
+			&i.Monto,
+			&i.Moneda,
+			&i.Minimo,
+			&i.Desde,
+			&i.Hasta,
+			&i.UsosMaximos,
+			&i.UsosPorUsuario,
+			&i.IDCategoria,
+			&i.Activo,
+			&i.Creado,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDirecciones = `-- name: ListDirecciones :many
+SELECT id_direccion, id_usuario, alias, destinatario, calle, ciudad, provincia, codigo_postal, telefono, creada FROM direccion WHERE id_usuario = $1 ORDER BY id_direccion
+`
+
+func (q *Queries) ListDirecciones(ctx context.Context, idUsuario int32) ([]Direccion, error) {
+	rows, err := q.db.QueryContext(ctx, listDirecciones, idUsuario)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Direccion{}
+	for rows.Next() {
+		var i Direccion
+		if err := rows.Scan(
+			&i.IDDireccion,
+			&i.IDUsuario,
+			&i.Alias,
+			&i.Destinatario,
+			&i.Calle,
+			&i.Ciudad,
+			&i.Provincia,
+			&i.CodigoPostal,
+			&i.Telefono,
+			&i.Creada,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHistorialDePedidos = `-- name: ListHistorialDePedidos :many
+SELECT id_historial, id_pedido, estado_anterior, estado, id_usuario, fecha FROM pedido_historial WHERE id_pedido = ANY($1::int[]) ORDER BY id_pedido, fecha, id_historial
+`
+
+func (q *Queries) ListHistorialDePedidos(ctx context.Context, ids []int32) ([]PedidoHistorial, error) {
+	rows, err := q.db.QueryContext(ctx, listHistorialDePedidos, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PedidoHistorial{}
+	for rows.Next() {
+		var i PedidoHistorial
+		if err := rows.Scan(
+			&i.IDHistorial,
+			&i.IDPedido,
+			&i.EstadoAnterior,
+			&i.Estado,
+			&i.IDUsuario,
+			&i.Fecha,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImpuestos = `-- name: ListImpuestos :many
+SELECT id_impuesto, nombre, alicuota, id_categoria, region, incluido, creado FROM impuesto ORDER BY LOWER(COALESCE(region, '')), COALESCE(id_categoria, 0), id_impuesto
+`
+
+func (q *Queries) ListImpuestos(ctx context.Context) ([]Impuesto, error) {
+	rows, err := q.db.QueryContext(ctx, listImpuestos)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Impuesto{}
+	for rows.Next() {
+		var i Impuesto
+		if err := rows.Scan(
+			&i.IDImpuesto,
+			&i.Nombre,
+			&i.Alicuota,
+			&i.IDCategoria,
+			&i.Region,
+			&i.Incluido,
+			&i.Creado,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listItemsDePedidos = `-- name: ListItemsDePedidos :many
+SELECT id_item, id_pedido, id_producto, nombre_producto, precio_unitario, cantidad, subtotal, descuento, promociones, impuesto_nombre, alicuota, impuesto, impuesto_incluido, cantidad_reembolsada FROM pedido_item WHERE id_pedido = ANY($1::int[]) ORDER BY id_pedido, id_item
+`
+
+func (q *Queries) ListItemsDePedidos(ctx context.Context, ids []int32) ([]PedidoItem, error) {
+	rows, err := q.db.QueryContext(ctx, listItemsDePedidos, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PedidoItem{}
+	for rows.Next() {
+		var i PedidoItem
+		if err := rows.Scan(
+			&i.IDItem,
+			&i.IDPedido,
+			&i.IDProducto,
+			&i.NombreProducto,
+			&i.PrecioUnitario,
+			&i.Cantidad,
+			&i.Subtotal,
+			&i.Descuento,
+			&i.Promociones,
+			&i.ImpuestoNombre,
+			&i.Alicuota,
+			&i.Impuesto,
+			&i.ImpuestoIncluido,
+			&i.CantidadReembolsada,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listItemsImpuestos = `-- name: ListItemsImpuestos :many
+SELECT i.id_item, i.id_pedido, i.id_producto, i.nombre_producto, i.precio_unitario, i.cantidad, i.subtotal, i.descuento, i.promociones, i.impuesto_nombre, i.alicuota, i.impuesto, i.impuesto_incluido, i.cantidad_reembolsada, p.region, p.moneda, p.tasa
+FROM pedido_item i JOIN pedido p ON p.id_pedido = i.id_pedido
+WHERE p.fecha >= $1 AND p.fecha < $2
+    AND p.estado IN ('pagado', 'enviado', 'entregado') AND i.impuesto_nombre <> ''
+ORDER BY p.id_pedido, i.id_item
+`
+
+type ListItemsImpuestosParams struct {
+	Desde time.Time `json:"desde"`
+	Hasta time.Time `json:"hasta"`
+}
+
+type ListItemsImpuestosRow struct {
+	PedidoItem PedidoItem  `json:"pedido_item"`
+	Region     string      `json:"region"`
+	Moneda     string      `json:"moneda"`
+	Tasa       dinero.Tasa `json:"tasa"`
+}
+
+// Líneas con impuesto de los pedidos cobrados en el período, para el reporte.
+// Los pendientes todavía no se cobraron y los cancelados no cuentan.
+func (q *Queries) ListItemsImpuestos(ctx context.Context, arg ListItemsImpuestosParams) ([]ListItemsImpuestosRow, error) {
+	rows, err := q.db.QueryContext(ctx, listItemsImpuestos, arg.Desde, arg.Hasta)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListItemsImpuestosRow{}
+	for rows.Next() {
+		var i ListItemsImpuestosRow
+		if err := rows.Scan(
+			&i.PedidoItem.IDItem,
+			&i.PedidoItem.IDPedido,
+			&i.PedidoItem.IDProducto,
+			&i.PedidoItem.NombreProducto,
+			&i.PedidoItem.PrecioUnitario,
+			&i.PedidoItem.Cantidad,
+			&i.PedidoItem.Subtotal,
+			&i.PedidoItem.Descuento,
+			&i.PedidoItem.Promociones,
+			&i.PedidoItem.ImpuestoNombre,
+			&i.PedidoItem.Alicuota,
+			&i.PedidoItem.Impuesto,
+			&i.PedidoItem.ImpuestoIncluido,
+			&i.PedidoItem.CantidadReembolsada,
+			&i.Region,
+			&i.Moneda,
+			&i.Tasa,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listItemsPedidoForUpdate = `-- name: ListItemsPedidoForUpdate :many
+SELECT id_item, id_pedido, id_producto, nombre_producto, precio_unitario, cantidad, subtotal, descuento, promociones, impuesto_nombre, alicuota, impuesto, impuesto_incluido, cantidad_reembolsada FROM pedido_item WHERE id_pedido = $1 ORDER BY id_item FOR UPDATE
+`
+
+func (q *Queries) ListItemsPedidoForUpdate(ctx context.Context, idPedido int32) ([]PedidoItem, error) {
+	rows, err := q.db.QueryContext(ctx, listItemsPedidoForUpdate, idPedido)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Producto
+	items := []PedidoItem{}
 	for rows.Next() {
-		var i Producto
+		var i PedidoItem
 		if err := rows.Scan(
+			&i.IDItem,
+			&i.IDPedido,
 			&i.IDProducto,
 			&i.NombreProducto,
-			&i.Descripcion,
-			&i.Precio,
-			&i.Stock,
-			&i.Categoria,
-			&i.Imagen,
+			&i.PrecioUnitario,
+			&i.Cantidad,
+			&i.Subtotal,
+			&i.Descuento,
+			&i.Promociones,
+			&i.ImpuestoNombre,
+			&i.Alicuota,
+			&i.Impuesto,
+			&i.ImpuestoIncluido,
+			&i.CantidadReembolsada,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMetodosEnvio = `-- name: ListMetodosEnvio :many
+SELECT id_metodo, nombre, tipo, costo, moneda, activo, creado FROM metodo_envio ORDER BY id_metodo
+`
+
+func (q *Queries) ListMetodosEnvio(ctx context.Context) ([]MetodoEnvio, error) {
+	rows, err := q.db.QueryContext(ctx, listMetodosEnvio)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MetodoEnvio{}
+	for rows.Next() {
+		var i MetodoEnvio
+		if err := rows.Scan(
+			&i.IDMetodo,
+			&i.Nombre,
+			&i.Tipo,
+			&i.Costo,
+			&i.Moneda,
+			&i.Activo,
+			&i.Creado,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMetodosEnvioActivos = `-- name: ListMetodosEnvioActivos :many
+SELECT id_metodo, nombre, tipo, costo, moneda, activo, creado FROM metodo_envio WHERE activo ORDER BY id_metodo
+`
+
+func (q *Queries) ListMetodosEnvioActivos(ctx context.Context) ([]MetodoEnvio, error) {
+	rows, err := q.db.QueryContext(ctx, listMetodosEnvioActivos)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []MetodoEnvio{}
+	for rows.Next() {
+		var i MetodoEnvio
+		if err := rows.Scan(
+			&i.IDMetodo,
+			&i.Nombre,
+			&i.Tipo,
+			&i.Costo,
+			&i.Moneda,
+			&i.Activo,
+			&i.Creado,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMonedas = `-- name: ListMonedas :many
+SELECT codigo, nombre, simbolo, tasa, actualizado FROM moneda ORDER BY codigo
+`
+
+func (q *Queries) ListMonedas(ctx context.Context) ([]Moneda, error) {
+	rows, err := q.db.QueryContext(ctx, listMonedas)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Moneda{}
+	for rows.Next() {
+		var i Moneda
+		if err := rows.Scan(
+			&i.Codigo,
+			&i.Nombre,
+			&i.Simbolo,
+			&i.Tasa,
+			&i.Actualizado,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPagosDePedidos = `-- name: ListPagosDePedidos :many
+SELECT id_pago, id_pedido, proveedor, referencia, monto, moneda, estado, url, reembolsado, creado, actualizado FROM pago WHERE id_pedido = ANY($1::int[]) ORDER BY id_pedido, id_pago
+`
+
+func (q *Queries) ListPagosDePedidos(ctx context.Context, ids []int32) ([]Pago, error) {
+	rows, err := q.db.QueryContext(ctx, listPagosDePedidos, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Pago{}
+	for rows.Next() {
+		var i Pago
+		if err := rows.Scan(
+			&i.IDPago,
+			&i.IDPedido,
+			&i.Proveedor,
+			&i.Referencia,
+			&i.Monto,
+			&i.Moneda,
+			&i.Estado,
+			&i.Url,
+			&i.Reembolsado,
+			&i.Creado,
+			&i.Actualizado,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPedidos = `-- name: ListPedidos :many
+SELECT id_pedido, id_usuario, estado, total, total_reembolsado, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio, fecha, actualizado FROM pedido ORDER BY fecha DESC
+`
+
+func (q *Queries) ListPedidos(ctx context.Context) ([]Pedido, error) {
+	rows, err := q.db.QueryContext(ctx, listPedidos)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Pedido{}
+	for rows.Next() {
+		var i Pedido
+		if err := rows.Scan(
+			&i.IDPedido,
+			&i.IDUsuario,
+			&i.Estado,
+			&i.Total,
+			&i.TotalReembolsado,
+			&i.Moneda,
+			&i.Tasa,
+			&i.Cupon,
+			&i.Descuento,
+			&i.DescuentoPromociones,
+			&i.Region,
+			&i.Impuestos,
+			&i.Envio,
+			&i.DireccionEnvio,
+			&i.CostoEnvio,
+			&i.Fecha,
+			&i.Actualizado,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPedidosPorEstado = `-- name: ListPedidosPorEstado :many
+SELECT id_pedido, id_usuario, estado, total, total_reembolsado, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio, fecha, actualizado FROM pedido WHERE estado = $1 ORDER BY fecha DESC
+`
+
+func (q *Queries) ListPedidosPorEstado(ctx context.Context, estado string) ([]Pedido, error) {
+	rows, err := q.db.QueryContext(ctx, listPedidosPorEstado, estado)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Pedido{}
+	for rows.Next() {
+		var i Pedido
+		if err := rows.Scan(
+			&i.IDPedido,
+			&i.IDUsuario,
+			&i.Estado,
+			&i.Total,
+			&i.TotalReembolsado,
+			&i.Moneda,
+			&i.Tasa,
+			&i.Cupon,
+			&i.Descuento,
+			&i.DescuentoPromociones,
+			&i.Region,
+			&i.Impuestos,
+			&i.Envio,
+			&i.DireccionEnvio,
+			&i.CostoEnvio,
+			&i.Fecha,
+			&i.Actualizado,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPedidosUsuario = `-- name: ListPedidosUsuario :many
+SELECT id_pedido, id_usuario, estado, total, total_reembolsado, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio, fecha, actualizado FROM pedido WHERE id_usuario = $1 ORDER BY fecha DESC
+`
+
+func (q *Queries) ListPedidosUsuario(ctx context.Context, idUsuario int32) ([]Pedido, error) {
+	rows, err := q.db.QueryContext(ctx, listPedidosUsuario, idUsuario)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Pedido{}
+	for rows.Next() {
+		var i Pedido
+		if err := rows.Scan(
+			&i.IDPedido,
+			&i.IDUsuario,
+			&i.Estado,
+			&i.Total,
+			&i.TotalReembolsado,
+			&i.Moneda,
+			&i.Tasa,
+			&i.Cupon,
+			&i.Descuento,
+			&i.DescuentoPromociones,
+			&i.Region,
+			&i.Impuestos,
+			&i.Envio,
+			&i.DireccionEnvio,
+			&i.CostoEnvio,
+			&i.Fecha,
+			&i.Actualizado,
 		); err != nil {
 			return nil, err
 		}
@@ -345,27 +2179,32 @@ func (q *Queries) ListProd(ctx context.Context) ([]Producto, error) {
 	return items, nil
 }
 
-const listProductsByPriceAsc = `-- name: ListProductsByPriceAsc :many
-SELECT id_producto, nombre_producto, descripcion, precio, stock, categoria, imagen FROM producto ORDER BY precio ASC
+const listPromociones = `-- name: ListPromociones :many
+SELECT id_promocion, nombre, tipo, id_categoria, id_producto, cantidad, paga, porcentaje, minimo, moneda, activa, creado FROM promocion ORDER BY id_promocion
 `
 
-func (q *Queries) ListProductsByPriceAsc(ctx context.Context) ([]Producto, error) {
-	rows, err := q.db.QueryContext(ctx, listProductsByPriceAsc)
+func (q *Queries) ListPromociones(ctx context.Context) ([]Promocion, error) {
+	rows, err := q.db.QueryContext(ctx, listPromociones)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Producto
+	items := []Promocion{}
 	for rows.Next() {
-		var i Producto
+		var i Promocion
 		if err := rows.Scan(
+			&i.IDPromocion,
+			&i.Nombre,
+			&i.Tipo,
+			&i.IDCategoria,
 			&i.IDProducto,
-			&i.NombreProducto,
-			&i.Descripcion,
-			&i.Precio,
-			&i.Stock,
-			&i.Categoria,
-			&i.Imagen,
+			&i.Cantidad,
+			&i.Paga,
+			&i.Porcentaje,
+			&i.Minimo,
+			&i.Moneda,
+			&i.Activa,
+			&i.Creado,
 		); err != nil {
 			return nil, err
 		}
@@ -380,27 +2219,32 @@ func (q *Queries) ListProductsByPriceAsc(ctx context.Context) ([]Producto, error
 	return items, nil
 }
 
-const listProductsByPriceDesc = `-- name: ListProductsByPriceDesc :many
-SELECT id_producto, nombre_producto, descripcion, precio, stock, categoria, imagen FROM producto ORDER BY precio DESC
+const listPromocionesActivas = `-- name: ListPromocionesActivas :many
+SELECT id_promocion, nombre, tipo, id_categoria, id_producto, cantidad, paga, porcentaje, minimo, moneda, activa, creado FROM promocion WHERE activa ORDER BY id_promocion
 `
 
-func (q *Queries) ListProductsByPriceDesc(ctx context.Context) ([]Producto, error) {
-	rows, err := q.db.QueryContext(ctx, listProductsByPriceDesc)
+func (q *Queries) ListPromocionesActivas(ctx context.Context) ([]Promocion, error) {
+	rows, err := q.db.QueryContext(ctx, listPromocionesActivas)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Producto
+	items := []Promocion{}
 	for rows.Next() {
-		var i Producto
+		var i Promocion
 		if err := rows.Scan(
+			&i.IDPromocion,
+			&i.Nombre,
+			&i.Tipo,
+			&i.IDCategoria,
 			&i.IDProducto,
-			&i.NombreProducto,
-			&i.Descripcion,
-			&i.Precio,
-			&i.Stock,
-			&i.Categoria,
-			&i.Imagen,
+			&i.Cantidad,
+			&i.Paga,
+			&i.Porcentaje,
+			&i.Minimo,
+			&i.Moneda,
+			&i.Activa,
+			&i.Creado,
 		); err != nil {
 			return nil, err
 		}
@@ -415,20 +2259,137 @@ func (q *Queries) ListProductsByPriceDesc(ctx context.Context) ([]Producto, erro
 	return items, nil
 }
 
-const listUsers = `-- name: ListUsers :many
-SELECT id_usuario, nombre_usuario, email FROM usuario ORDER BY nombre_usuario
+const listReembolsosDePedidos = `-- name: ListReembolsosDePedidos :many
+SELECT id_reembolso, id_pedido, monto, motivo, id_usuario, fecha, id_pago, estado_pago FROM reembolso WHERE id_pedido = ANY($1::int[]) ORDER BY id_pedido, fecha, id_reembolso
 `
 
-func (q *Queries) ListUsers(ctx context.Context) ([]Usuario, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers)
+func (q *Queries) ListReembolsosDePedidos(ctx context.Context, ids []int32) ([]Reembolso, error) {
+	rows, err := q.db.QueryContext(ctx, listReembolsosDePedidos, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Usuario
+	items := []Reembolso{}
 	for rows.Next() {
-		var i Usuario
-		if err := rows.Scan(&i.IDUsuario, &i.NombreUsuario, &i.Email); err != nil {
+		var i Reembolso
+		if err := rows.Scan(
+			&i.IDReembolso,
+			&i.IDPedido,
+			&i.Monto,
+			&i.Motivo,
+			&i.IDUsuario,
+			&i.Fecha,
+			&i.IDPago,
+			&i.EstadoPago,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReembolsosPendientes = `-- name: ListReembolsosPendientes :many
+SELECT r.id_reembolso, r.id_pedido, r.monto, p.id_pago, p.proveedor, p.referencia
+FROM reembolso r JOIN pago p ON p.id_pago = r.id_pago
+WHERE r.estado_pago = 'pendiente'
+  AND ($1::int = 0 OR r.id_pedido = $1)
+ORDER BY r.id_reembolso
+`
+
+type ListReembolsosPendientesRow struct {
+	IDReembolso int32        `json:"id_reembolso"`
+	IDPedido    int32        `json:"id_pedido"`
+	Monto       dinero.Monto `json:"monto"`
+	IDPago      int32        `json:"id_pago"`
+	Proveedor   string       `json:"proveedor"`
+	Referencia  string       `json:"referencia"`
+}
+
+// Con id_pedido 0 trae los de todos los pedidos
+func (q *Queries) ListReembolsosPendientes(ctx context.Context, idPedido int32) ([]ListReembolsosPendientesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listReembolsosPendientes, idPedido)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListReembolsosPendientesRow{}
+	for rows.Next() {
+		var i ListReembolsosPendientesRow
+		if err := rows.Scan(
+			&i.IDReembolso,
+			&i.IDPedido,
+			&i.Monto,
+			&i.IDPago,
+			&i.Proveedor,
+			&i.Referencia,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRegionesImpuesto = `-- name: ListRegionesImpuesto :many
+SELECT DISTINCT region::text FROM impuesto WHERE region IS NOT NULL ORDER BY region
+`
+
+func (q *Queries) ListRegionesImpuesto(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listRegionesImpuesto)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var region string
+		if err := rows.Scan(&region); err != nil {
+			return nil, err
+		}
+		items = append(items, region)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTarifasEnvio = `-- name: ListTarifasEnvio :many
+SELECT id_tarifa, id_metodo, provincia, peso_hasta, costo FROM tarifa_envio ORDER BY id_metodo, provincia NULLS LAST, peso_hasta
+`
+
+func (q *Queries) ListTarifasEnvio(ctx context.Context) ([]TarifaEnvio, error) {
+	rows, err := q.db.QueryContext(ctx, listTarifasEnvio)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TarifaEnvio{}
+	for rows.Next() {
+		var i TarifaEnvio
+		if err := rows.Scan(
+			&i.IDTarifa,
+			&i.IDMetodo,
+			&i.Provincia,
+			&i.PesoHasta,
+			&i.Costo,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -442,26 +2403,25 @@ func (q *Queries) ListUsers(ctx context.Context) ([]Usuario, error) {
 	return items, nil
 }
 
-const listVentas = `-- name: ListVentas :many
-SELECT id_venta, id_producto, id_usuario, cantidad, total, fecha FROM venta ORDER BY fecha
+const listTarifasMetodo = `-- name: ListTarifasMetodo :many
+SELECT id_tarifa, id_metodo, provincia, peso_hasta, costo FROM tarifa_envio WHERE id_metodo = $1 ORDER BY provincia NULLS LAST, peso_hasta
 `
 
-func (q *Queries) ListVentas(ctx context.Context) ([]Ventum, error) {
-	rows, err := q.db.QueryContext(ctx, listVentas)
+func (q *Queries) ListTarifasMetodo(ctx context.Context, idMetodo int32) ([]TarifaEnvio, error) {
+	rows, err := q.db.QueryContext(ctx, listTarifasMetodo, idMetodo)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Ventum
+	items := []TarifaEnvio{}
 	for rows.Next() {
-		var i Ventum
+		var i TarifaEnvio
 		if err := rows.Scan(
-			&i.IDVenta,
-			&i.IDProducto,
-			&i.IDUsuario,
-			&i.Cantidad,
-			&i.Total,
-			&i.Fecha,
+			&i.IDTarifa,
+			&i.IDMetodo,
+			&i.Provincia,
+			&i.PesoHasta,
+			&i.Costo,
 		); err != nil {
 			return nil, err
 		}
@@ -476,26 +2436,26 @@ func (q *Queries) ListVentas(ctx context.Context) ([]Ventum, error) {
 	return items, nil
 }
 
-const listVentasUsuario = `-- name: ListVentasUsuario :many
-SELECT id_venta, id_producto, id_usuario, cantidad, total, fecha FROM venta WHERE id_usuario = $1
+const listUsers = `-- name: ListUsers :many
+SELECT id_usuario, nombre_usuario, email, password_hash, rol, region FROM usuario ORDER BY nombre_usuario
 `
 
-func (q *Queries) ListVentasUsuario(ctx context.Context, idUsuario int32) ([]Ventum, error) {
-	rows, err := q.db.QueryContext(ctx, listVentasUsuario, idUsuario)
+func (q *Queries) ListUsers(ctx context.Context) ([]Usuario, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Ventum
+	items := []Usuario{}
 	for rows.Next() {
-		var i Ventum
+		var i Usuario
 		if err := rows.Scan(
-			&i.IDVenta,
-			&i.IDProducto,
 			&i.IDUsuario,
-			&i.Cantidad,
-			&i.Total,
-			&i.Fecha,
+			&i.NombreUsuario,
+			&i.Email,
+			&i.PasswordHash,
+			&i.Rol,
+			&i.Region,
 		); err != nil {
 			return nil, err
 		}
@@ -510,36 +2470,426 @@ func (q *Queries) ListVentasUsuario(ctx context.Context, idUsuario int32) ([]Ven
 	return items, nil
 }
 
-const updateCartItem = `-- name: UpdateCartItem :exec
-UPDATE carrito SET cantidad = $2 WHERE id_item = $1
+const marcarReembolsoPendiente = `-- name: MarcarReembolsoPendiente :exec
+UPDATE reembolso SET id_pago = $2, estado_pago = 'pendiente' WHERE id_reembolso = $1
 `
 
-type UpdateCartItemParams struct {
-	IDItem   int32 `json:"id_item"`
-	Cantidad int32 `json:"cantidad"`
+type MarcarReembolsoPendienteParams struct {
+	IDReembolso int32  `json:"id_reembolso"`
+	IDPago      *int32 `json:"id_pago"`
+}
+
+func (q *Queries) MarcarReembolsoPendiente(ctx context.Context, arg MarcarReembolsoPendienteParams) error {
+	_, err := q.db.ExecContext(ctx, marcarReembolsoPendiente, arg.IDReembolso, arg.IDPago)
+	return err
+}
+
+const quitarCategoriaProductos = `-- name: QuitarCategoriaProductos :exec
+UPDATE producto SET categoria = '', id_categoria = NULL WHERE id_categoria = $1
+`
+
+func (q *Queries) QuitarCategoriaProductos(ctx context.Context, idCategoria *int32) error {
+	_, err := q.db.ExecContext(ctx, quitarCategoriaProductos, idCategoria)
+	return err
+}
+
+const renombrarCategoriaProductos = `-- name: RenombrarCategoriaProductos :exec
+UPDATE producto SET categoria = $2 WHERE id_categoria = $1
+`
+
+type RenombrarCategoriaProductosParams struct {
+	IDCategoria *int32 `json:"id_categoria"`
+	Categoria   string `json:"categoria"`
+}
+
+func (q *Queries) RenombrarCategoriaProductos(ctx context.Context, arg RenombrarCategoriaProductosParams) error {
+	_, err := q.db.ExecContext(ctx, renombrarCategoriaProductos, arg.IDCategoria, arg.Categoria)
+	return err
+}
+
+const restaurarProd = `-- name: RestaurarProd :one
+UPDATE producto SET archivado = false WHERE id_producto = $1 RETURNING id_producto, nombre_producto, descripcion, precio, moneda, stock, categoria, id_categoria, imagen, peso, archivado
+`
+
+func (q *Queries) RestaurarProd(ctx context.Context, idProducto int32) (Producto, error) {
+	row := q.db.QueryRowContext(ctx, restaurarProd, idProducto)
+	var i Producto
+	err := row.Scan(
+		&i.IDProducto,
+		&i.NombreProducto,
+		&i.Descripcion,
+		&i.Precio,
+		&i.Moneda,
+		&i.Stock,
+		&i.Categoria,
+		&i.IDCategoria,
+		&i.Imagen,
+		&i.Peso,
+		&i.Archivado,
+	)
+	return i, err
+}
+
+const setCarritoCupon = `-- name: SetCarritoCupon :exec
+INSERT INTO carrito_cupon (id_usuario, codigo) VALUES ($1, $2)
+ON CONFLICT (id_usuario) DO UPDATE SET codigo = EXCLUDED.codigo
+`
+
+type SetCarritoCuponParams struct {
+	IDUsuario int32  `json:"id_usuario"`
+	Codigo    string `json:"codigo"`
+}
+
+func (q *Queries) SetCarritoCupon(ctx context.Context, arg SetCarritoCuponParams) error {
+	_, err := q.db.ExecContext(ctx, setCarritoCupon, arg.IDUsuario, arg.Codigo)
+	return err
+}
+
+const setCarritoEnvio = `-- name: SetCarritoEnvio :exec
+INSERT INTO carrito_envio (id_usuario, id_metodo, id_direccion) VALUES ($1, $2, $3)
+ON CONFLICT (id_usuario) DO UPDATE SET id_metodo = EXCLUDED.id_metodo, id_direccion = EXCLUDED.id_direccion
+`
+
+type SetCarritoEnvioParams struct {
+	IDUsuario   int32  `json:"id_usuario"`
+	IDMetodo    int32  `json:"id_metodo"`
+	IDDireccion *int32 `json:"id_direccion"`
+}
+
+func (q *Queries) SetCarritoEnvio(ctx context.Context, arg SetCarritoEnvioParams) error {
+	_, err := q.db.ExecContext(ctx, setCarritoEnvio, arg.IDUsuario, arg.IDMetodo, arg.IDDireccion)
+	return err
+}
+
+const sumarItemReembolsado = `-- name: SumarItemReembolsado :exec
+UPDATE pedido_item SET cantidad_reembolsada = cantidad_reembolsada + $2 WHERE id_item = $1
+`
+
+type SumarItemReembolsadoParams struct {
+	IDItem              int32 `json:"id_item"`
+	CantidadReembolsada int32 `json:"cantidad_reembolsada"`
+}
+
+func (q *Queries) SumarItemReembolsado(ctx context.Context, arg SumarItemReembolsadoParams) error {
+	_, err := q.db.ExecContext(ctx, sumarItemReembolsado, arg.IDItem, arg.CantidadReembolsada)
+	return err
+}
+
+const sumarPagoReembolsado = `-- name: SumarPagoReembolsado :exec
+UPDATE pago SET reembolsado = reembolsado + $2, actualizado = NOW() WHERE id_pago = $1
+`
+
+type SumarPagoReembolsadoParams struct {
+	IDPago      int32        `json:"id_pago"`
+	Reembolsado dinero.Monto `json:"reembolsado"`
+}
+
+func (q *Queries) SumarPagoReembolsado(ctx context.Context, arg SumarPagoReembolsadoParams) error {
+	_, err := q.db.ExecContext(ctx, sumarPagoReembolsado, arg.IDPago, arg.Reembolsado)
+	return err
+}
+
+const sumarReembolsoPedido = `-- name: SumarReembolsoPedido :one
+UPDATE pedido SET total_reembolsado = total_reembolsado + $2, actualizado = NOW() WHERE id_pedido = $1 RETURNING id_pedido, id_usuario, estado, total, total_reembolsado, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio, fecha, actualizado
+`
+
+type SumarReembolsoPedidoParams struct {
+	IDPedido         int32        `json:"id_pedido"`
+	TotalReembolsado dinero.Monto `json:"total_reembolsado"`
+}
+
+func (q *Queries) SumarReembolsoPedido(ctx context.Context, arg SumarReembolsoPedidoParams) (Pedido, error) {
+	row := q.db.QueryRowContext(ctx, sumarReembolsoPedido, arg.IDPedido, arg.TotalReembolsado)
+	var i Pedido
+	err := row.Scan(
+		&i.IDPedido,
+		&i.IDUsuario,
+		&i.Estado,
+		&i.Total,
+		&i.TotalReembolsado,
+		&i.Moneda,
+		&i.Tasa,
+		&i.Cupon,
+		&i.Descuento,
+		&i.DescuentoPromociones,
+		&i.Region,
+		&i.Impuestos,
+		&i.Envio,
+		&i.DireccionEnvio,
+		&i.CostoEnvio,
+		&i.Fecha,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const sumarStockProducto = `-- name: SumarStockProducto :exec
+UPDATE producto SET stock = stock + $2 WHERE id_producto = $1
+`
+
+type SumarStockProductoParams struct {
+	IDProducto int32 `json:"id_producto"`
+	Stock      int32 `json:"stock"`
 }
 
-func (q *Queries) UpdateCartItem(ctx context.Context, arg UpdateCartItemParams) error {
-	_, err := q.db.ExecContext(ctx, updateCartItem, arg.IDItem, arg.Cantidad)
+func (q *Queries) SumarStockProducto(ctx context.Context, arg SumarStockProductoParams) error {
+	_, err := q.db.ExecContext(ctx, sumarStockProducto, arg.IDProducto, arg.Stock)
 	return err
 }
 
-const updateProducto = `-- name: UpdateProducto :exec
-UPDATE producto SET nombre_producto = $2, descripcion = $3, stock = $4, precio = $5, categoria = $6, imagen = $7 WHERE id_producto = $1
+const updateCartItem = `-- name: UpdateCartItem :execrows
+UPDATE carrito SET cantidad = $3 WHERE id_item = $1 AND id_usuario = $2
+`
+
+type UpdateCartItemParams struct {
+	IDItem    int32 `json:"id_item"`
+	IDUsuario int32 `json:"id_usuario"`
+	Cantidad  int32 `json:"cantidad"`
+}
+
+func (q *Queries) UpdateCartItem(ctx context.Context, arg UpdateCartItemParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateCartItem, arg.IDItem, arg.IDUsuario, arg.Cantidad)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateCategoria = `-- name: UpdateCategoria :one
+UPDATE categoria SET nombre = $2, slug = $3, id_padre = $4 WHERE id_categoria = $1 RETURNING id_categoria, nombre, slug, id_padre
+`
+
+type UpdateCategoriaParams struct {
+	IDCategoria int32  `json:"id_categoria"`
+	Nombre      string `json:"nombre"`
+	Slug        string `json:"slug"`
+	IDPadre     *int32 `json:"id_padre"`
+}
+
+func (q *Queries) UpdateCategoria(ctx context.Context, arg UpdateCategoriaParams) (Categoria, error) {
+	row := q.db.QueryRowContext(ctx, updateCategoria,
+		arg.IDCategoria,
+		arg.Nombre,
+		arg.Slug,
+		arg.IDPadre,
+	)
+	var i Categoria
+	err := row.Scan(
+		&i.IDCategoria,
+		&i.Nombre,
+		&i.Slug,
+		&i.IDPadre,
+	)
+	return i, err
+}
+
+const updateDireccion = `-- name: UpdateDireccion :one
+UPDATE direccion SET alias = $3, destinatario = $4, calle = $5, ciudad = $6, provincia = $7, codigo_postal = $8, telefono = $9
+WHERE id_direccion = $1 AND id_usuario = $2 RETURNING id_direccion, id_usuario, alias, destinatario, calle, ciudad, provincia, codigo_postal, telefono, creada
+`
+
+type UpdateDireccionParams struct {
+	IDDireccion  int32  `json:"id_direccion"`
+	IDUsuario    int32  `json:"id_usuario"`
+	Alias        string `json:"alias"`
+	Destinatario string `json:"destinatario"`
+	Calle        string `json:"calle"`
+	Ciudad       string `json:"ciudad"`
+	Provincia    string `json:"provincia"`
+	CodigoPostal string `json:"codigo_postal"`
+	Telefono     string `json:"telefono"`
+}
+
+func (q *Queries) UpdateDireccion(ctx context.Context, arg UpdateDireccionParams) (Direccion, error) {
+	row := q.db.QueryRowContext(ctx, updateDireccion,
+		arg.IDDireccion,
+		arg.IDUsuario,
+		arg.Alias,
+		arg.Destinatario,
+		arg.Calle,
+		arg.Ciudad,
+		arg.Provincia,
+		arg.CodigoPostal,
+		arg.Telefono,
+	)
+	var i Direccion
+	err := row.Scan(
+		&i.IDDireccion,
+		&i.IDUsuario,
+		&i.Alias,
+		&i.Destinatario,
+		&i.Calle,
+		&i.Ciudad,
+		&i.Provincia,
+		&i.CodigoPostal,
+		&i.Telefono,
+		&i.Creada,
+	)
+	return i, err
+}
+
+const updateImpuesto = `-- name: UpdateImpuesto :one
+UPDATE impuesto SET nombre = $2, alicuota = $3, id_categoria = $4, region = $5, incluido = $6 WHERE id_impuesto = $1 RETURNING id_impuesto, nombre, alicuota, id_categoria, region, incluido, creado
+`
+
+type UpdateImpuestoParams struct {
+	IDImpuesto  int32             `json:"id_impuesto"`
+	Nombre      string            `json:"nombre"`
+	Alicuota    dinero.Porcentaje `json:"alicuota"`
+	IDCategoria *int32            `json:"id_categoria"`
+	Region      *string           `json:"region"`
+	Incluido    bool              `json:"incluido"`
+}
+
+func (q *Queries) UpdateImpuesto(ctx context.Context, arg UpdateImpuestoParams) (Impuesto, error) {
+	row := q.db.QueryRowContext(ctx, updateImpuesto,
+		arg.IDImpuesto,
+		arg.Nombre,
+		arg.Alicuota,
+		arg.IDCategoria,
+		arg.Region,
+		arg.Incluido,
+	)
+	var i Impuesto
+	err := row.Scan(
+		&i.IDImpuesto,
+		&i.Nombre,
+		&i.Alicuota,
+		&i.IDCategoria,
+		&i.Region,
+		&i.Incluido,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const updateMetodoEnvioActivo = `-- name: UpdateMetodoEnvioActivo :one
+UPDATE metodo_envio SET activo = $2 WHERE id_metodo = $1 RETURNING id_metodo, nombre, tipo, costo, moneda, activo, creado
+`
+
+type UpdateMetodoEnvioActivoParams struct {
+	IDMetodo int32 `json:"id_metodo"`
+	Activo   bool  `json:"activo"`
+}
+
+func (q *Queries) UpdateMetodoEnvioActivo(ctx context.Context, arg UpdateMetodoEnvioActivoParams) (MetodoEnvio, error) {
+	row := q.db.QueryRowContext(ctx, updateMetodoEnvioActivo, arg.IDMetodo, arg.Activo)
+	var i MetodoEnvio
+	err := row.Scan(
+		&i.IDMetodo,
+		&i.Nombre,
+		&i.Tipo,
+		&i.Costo,
+		&i.Moneda,
+		&i.Activo,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const updateMonedaTasa = `-- name: UpdateMonedaTasa :one
+UPDATE moneda SET tasa = $2, actualizado = NOW() WHERE codigo = $1 RETURNING codigo, nombre, simbolo, tasa, actualizado
+`
+
+type UpdateMonedaTasaParams struct {
+	Codigo string      `json:"codigo"`
+	Tasa   dinero.Tasa `json:"tasa"`
+}
+
+func (q *Queries) UpdateMonedaTasa(ctx context.Context, arg UpdateMonedaTasaParams) (Moneda, error) {
+	row := q.db.QueryRowContext(ctx, updateMonedaTasa, arg.Codigo, arg.Tasa)
+	var i Moneda
+	err := row.Scan(
+		&i.Codigo,
+		&i.Nombre,
+		&i.Simbolo,
+		&i.Tasa,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const updatePagoEstado = `-- name: UpdatePagoEstado :one
+UPDATE pago SET estado = $2, actualizado = NOW() WHERE id_pago = $1 RETURNING id_pago, id_pedido, proveedor, referencia, monto, moneda, estado, url, reembolsado, creado, actualizado
+`
+
+type UpdatePagoEstadoParams struct {
+	IDPago int32  `json:"id_pago"`
+	Estado string `json:"estado"`
+}
+
+func (q *Queries) UpdatePagoEstado(ctx context.Context, arg UpdatePagoEstadoParams) (Pago, error) {
+	row := q.db.QueryRowContext(ctx, updatePagoEstado, arg.IDPago, arg.Estado)
+	var i Pago
+	err := row.Scan(
+		&i.IDPago,
+		&i.IDPedido,
+		&i.Proveedor,
+		&i.Referencia,
+		&i.Monto,
+		&i.Moneda,
+		&i.Estado,
+		&i.Url,
+		&i.Reembolsado,
+		&i.Creado,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const updatePedidoEstado = `-- name: UpdatePedidoEstado :one
+UPDATE pedido SET estado = $2, actualizado = NOW() WHERE id_pedido = $1 RETURNING id_pedido, id_usuario, estado, total, total_reembolsado, moneda, tasa, cupon, descuento, descuento_promociones, region, impuestos, envio, direccion_envio, costo_envio, fecha, actualizado
+`
+
+type UpdatePedidoEstadoParams struct {
+	IDPedido int32  `json:"id_pedido"`
+	Estado   string `json:"estado"`
+}
+
+func (q *Queries) UpdatePedidoEstado(ctx context.Context, arg UpdatePedidoEstadoParams) (Pedido, error) {
+	row := q.db.QueryRowContext(ctx, updatePedidoEstado, arg.IDPedido, arg.Estado)
+	var i Pedido
+	err := row.Scan(
+		&i.IDPedido,
+		&i.IDUsuario,
+		&i.Estado,
+		&i.Total,
+		&i.TotalReembolsado,
+		&i.Moneda,
+		&i.Tasa,
+		&i.Cupon,
+		&i.Descuento,
+		&i.DescuentoPromociones,
+		&i.Region,
+		&i.Impuestos,
+		&i.Envio,
+		&i.DireccionEnvio,
+		&i.CostoEnvio,
+		&i.Fecha,
+		&i.Actualizado,
+	)
+	return i, err
+}
+
+const updateProducto = `-- name: UpdateProducto :one
+UPDATE producto SET nombre_producto = $2, descripcion = $3, stock = $4, precio = $5, categoria = $6, imagen = $7, moneda = $8, peso = $9, id_categoria = $10 WHERE id_producto = $1 RETURNING id_producto, nombre_producto, descripcion, precio, moneda, stock, categoria, id_categoria, imagen, peso, archivado
 `
 
 type UpdateProductoParams struct {
-	IDProducto     int32  `json:"id_producto"`
-	NombreProducto string `json:"nombre_producto"`
-	Descripcion    string `json:"descripcion"`
-	Stock          int32  `json:"stock"`
-	Precio         string `json:"precio"`
-	Categoria      string `json:"categoria"`
-	Imagen         string `json:"imagen"`
+	IDProducto     int32        `json:"id_producto"`
+	NombreProducto string       `json:"nombre_producto"`
+	Descripcion    string       `json:"descripcion"`
+	Stock          int32        `json:"stock"`
+	Precio         dinero.Monto `json:"precio"`
+	Categoria      string       `json:"categoria"`
+	Imagen         string       `json:"imagen"`
+	Moneda         string       `json:"moneda"`
+	Peso           int32        `json:"peso"`
+	IDCategoria    *int32       `json:"id_categoria"`
 }
 
-func (q *Queries) UpdateProducto(ctx context.Context, arg UpdateProductoParams) error {
-	_, err := q.db.ExecContext(ctx, updateProducto,
+func (q *Queries) UpdateProducto(ctx context.Context, arg UpdateProductoParams) (Producto, error) {
+	row := q.db.QueryRowContext(ctx, updateProducto,
 		arg.IDProducto,
 		arg.NombreProducto,
 		arg.Descripcion,
@@ -547,8 +2897,25 @@ func (q *Queries) UpdateProducto(ctx context.Context, arg UpdateProductoParams)
 		arg.Precio,
 		arg.Categoria,
 		arg.Imagen,
+		arg.Moneda,
+		arg.Peso,
+		arg.IDCategoria,
 	)
-	return err
+	var i Producto
+	err := row.Scan(
+		&i.IDProducto,
+		&i.NombreProducto,
+		&i.Descripcion,
+		&i.Precio,
+		&i.Moneda,
+		&i.Stock,
+		&i.Categoria,
+		&i.IDCategoria,
+		&i.Imagen,
+		&i.Peso,
+		&i.Archivado,
+	)
+	return i, err
 }
 
 const updateProductoPrecio = `-- name: UpdateProductoPrecio :exec
@@ -556,8 +2923,8 @@ UPDATE producto SET precio = $2 WHERE id_producto = $1
 `
 
 type UpdateProductoPrecioParams struct {
-	IDProducto int32  `json:"id_producto"`
-	Precio     string `json:"precio"`
+	IDProducto int32        `json:"id_producto"`
+	Precio     dinero.Monto `json:"precio"`
 }
 
 func (q *Queries) UpdateProductoPrecio(ctx context.Context, arg UpdateProductoPrecioParams) error {
@@ -579,8 +2946,117 @@ func (q *Queries) UpdateProductoStock(ctx context.Context, arg UpdateProductoSto
 	return err
 }
 
-const updateUser = `-- name: UpdateUser :exec
-UPDATE usuario SET nombre_usuario = $2, email = $3 WHERE id_usuario = $1
+const updatePromocion = `-- name: UpdatePromocion :one
+UPDATE promocion SET nombre = $2, tipo = $3, id_categoria = $4, id_producto = $5, cantidad = $6,
+    paga = $7, porcentaje = $8, minimo = $9, moneda = $10, activa = $11
+WHERE id_promocion = $1 RETURNING id_promocion, nombre, tipo, id_categoria, id_producto, cantidad, paga, porcentaje, minimo, moneda, activa, creado
+`
+
+type UpdatePromocionParams struct {
+	IDPromocion int32        `json:"id_promocion"`
+	Nombre      string       `json:"nombre"`
+	Tipo        string       `json:"tipo"`
+	IDCategoria *int32       `json:"id_categoria"`
+	IDProducto  *int32       `json:"id_producto"`
+	Cantidad    int32        `json:"cantidad"`
+	Paga        int32        `json:"paga"`
+	Porcentaje  int32        `json:"porcentaje"`
+	Minimo      dinero.Monto `json:"minimo"`
+	Moneda      string       `json:"moneda"`
+	Activa      bool         `json:"activa"`
+}
+
+func (q *Queries) UpdatePromocion(ctx context.Context, arg UpdatePromocionParams) (Promocion, error) {
+	row := q.db.QueryRowContext(ctx, updatePromocion,
+		arg.IDPromocion,
+		arg.Nombre,
+		arg.Tipo,
+		arg.IDCategoria,
+		arg.IDProducto,
+		arg.Cantidad,
+		arg.Paga,
+		arg.Porcentaje,
+		arg.Minimo,
+		arg.Moneda,
+		arg.Activa,
+	)
+	var i Promocion
+	err := row.Scan(
+		&i.IDPromocion,
+		&i.Nombre,
+		&i.Tipo,
+		&i.IDCategoria,
+		&i.IDProducto,
+		&i.Cantidad,
+		&i.Paga,
+		&i.Porcentaje,
+		&i.Minimo,
+		&i.Moneda,
+		&i.Activa,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const updatePromocionActiva = `-- name: UpdatePromocionActiva :one
+UPDATE promocion SET activa = $2 WHERE id_promocion = $1 RETURNING id_promocion, nombre, tipo, id_categoria, id_producto, cantidad, paga, porcentaje, minimo, moneda, activa, creado
+`
+
+type UpdatePromocionActivaParams struct {
+	IDPromocion int32 `json:"id_promocion"`
+	Activa      bool  `json:"activa"`
+}
+
+func (q *Queries) UpdatePromocionActiva(ctx context.Context, arg UpdatePromocionActivaParams) (Promocion, error) {
+	row := q.db.QueryRowContext(ctx, updatePromocionActiva, arg.IDPromocion, arg.Activa)
+	var i Promocion
+	err := row.Scan(
+		&i.IDPromocion,
+		&i.Nombre,
+		&i.Tipo,
+		&i.IDCategoria,
+		&i.IDProducto,
+		&i.Cantidad,
+		&i.Paga,
+		&i.Porcentaje,
+		&i.Minimo,
+		&i.Moneda,
+		&i.Activa,
+		&i.Creado,
+	)
+	return i, err
+}
+
+const updateReembolsoEstadoPago = `-- name: UpdateReembolsoEstadoPago :exec
+UPDATE reembolso SET estado_pago = $2 WHERE id_reembolso = $1
+`
+
+type UpdateReembolsoEstadoPagoParams struct {
+	IDReembolso int32  `json:"id_reembolso"`
+	EstadoPago  string `json:"estado_pago"`
+}
+
+func (q *Queries) UpdateReembolsoEstadoPago(ctx context.Context, arg UpdateReembolsoEstadoPagoParams) error {
+	_, err := q.db.ExecContext(ctx, updateReembolsoEstadoPago, arg.IDReembolso, arg.EstadoPago)
+	return err
+}
+
+const updateSesionMoneda = `-- name: UpdateSesionMoneda :exec
+UPDATE sesion SET moneda = $2 WHERE token_hash = $1
+`
+
+type UpdateSesionMonedaParams struct {
+	TokenHash string `json:"token_hash"`
+	Moneda    string `json:"moneda"`
+}
+
+func (q *Queries) UpdateSesionMoneda(ctx context.Context, arg UpdateSesionMonedaParams) error {
+	_, err := q.db.ExecContext(ctx, updateSesionMoneda, arg.TokenHash, arg.Moneda)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE usuario SET nombre_usuario = $2, email = $3 WHERE id_usuario = $1 RETURNING id_usuario, nombre_usuario, email, password_hash, rol, region
 `
 
 type UpdateUserParams struct {
@@ -589,28 +3065,89 @@ type UpdateUserParams struct {
 	Email         string `json:"email"`
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) error {
-	_, err := q.db.ExecContext(ctx, updateUser, arg.IDUsuario, arg.NombreUsuario, arg.Email)
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (Usuario, error) {
+	row := q.db.QueryRowContext(ctx, updateUser, arg.IDUsuario, arg.NombreUsuario, arg.Email)
+	var i Usuario
+	err := row.Scan(
+		&i.IDUsuario,
+		&i.NombreUsuario,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Rol,
+		&i.Region,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE usuario SET password_hash = $2 WHERE id_usuario = $1
+`
+
+type UpdateUserPasswordParams struct {
+	IDUsuario    int32  `json:"id_usuario"`
+	PasswordHash string `json:"-"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.IDUsuario, arg.PasswordHash)
 	return err
 }
 
-const updateVenta = `-- name: UpdateVenta :exec
-UPDATE venta SET cantidad = $2, total = $3, fecha = $4 WHERE id_venta = $1
+const updateUserRegion = `-- name: UpdateUserRegion :exec
+UPDATE usuario SET region = $2 WHERE id_usuario = $1
 `
 
-type UpdateVentaParams struct {
-	IDVenta  int32        `json:"id_venta"`
-	Cantidad int32        `json:"cantidad"`
-	Total    string       `json:"total"`
-	Fecha    sql.NullTime `json:"fecha"`
+type UpdateUserRegionParams struct {
+	IDUsuario int32  `json:"id_usuario"`
+	Region    string `json:"region"`
 }
 
-func (q *Queries) UpdateVenta(ctx context.Context, arg UpdateVentaParams) error {
-	_, err := q.db.ExecContext(ctx, updateVenta,
-		arg.IDVenta,
-		arg.Cantidad,
-		arg.Total,
-		arg.Fecha,
-	)
+func (q *Queries) UpdateUserRegion(ctx context.Context, arg UpdateUserRegionParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserRegion, arg.IDUsuario, arg.Region)
+	return err
+}
+
+const updateUserRol = `-- name: UpdateUserRol :exec
+UPDATE usuario SET rol = $2 WHERE id_usuario = $1
+`
+
+type UpdateUserRolParams struct {
+	IDUsuario int32  `json:"id_usuario"`
+	Rol       string `json:"rol"`
+}
+
+func (q *Queries) UpdateUserRol(ctx context.Context, arg UpdateUserRolParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserRol, arg.IDUsuario, arg.Rol)
 	return err
 }
+
+const upsertMoneda = `-- name: UpsertMoneda :one
+INSERT INTO moneda (codigo, nombre, simbolo, tasa) VALUES ($1, $2, $3, $4)
+ON CONFLICT (codigo) DO UPDATE SET nombre = EXCLUDED.nombre, simbolo = EXCLUDED.simbolo, tasa = EXCLUDED.tasa, actualizado = NOW()
+RETURNING codigo, nombre, simbolo, tasa, actualizado
+`
+
+type UpsertMonedaParams struct {
+	Codigo  string      `json:"codigo"`
+	Nombre  string      `json:"nombre"`
+	Simbolo string      `json:"simbolo"`
+	Tasa    dinero.Tasa `json:"tasa"`
+}
+
+func (q *Queries) UpsertMoneda(ctx context.Context, arg UpsertMonedaParams) (Moneda, error) {
+	row := q.db.QueryRowContext(ctx, upsertMoneda,
+		arg.Codigo,
+		arg.Nombre,
+		arg.Simbolo,
+		arg.Tasa,
+	)
+	var i Moneda
+	err := row.Scan(
+		&i.Codigo,
+		&i.Nombre,
+		&i.Simbolo,
+		&i.Tasa,
+		&i.Actualizado,
+	)
+	return i, err
+}
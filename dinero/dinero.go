@@ -0,0 +1,199 @@
+// Package dinero representa importes exactos en centavos. Reemplaza a los
+// string y float64 con los que se manejaban los DECIMAL(10,2) de la base.
+package dinero
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Monto es un importe en centavos
+type Monto int64
+
+// Máximo que entra en un DECIMAL(10,2)
+const Maximo Monto = 99_999_999_99
+
+// ErrFormato se devuelve cuando un texto no es un importe válido
+var ErrFormato = errors.New("importe inválido: se espera un número con hasta 2 decimales")
+
+// Parse interpreta importes como "1500", "1500.5" o "1500.50". Acepta coma
+// como separador decimal, pero no separadores de miles ni más de 2 decimales.
+func Parse(s string) (Monto, error) {
+	s = strings.TrimSpace(s)
+	negativo := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	s = strings.Replace(s, ",", ".", 1)
+
+	entero, decimales, _ := strings.Cut(s, ".")
+	if entero == "" || len(decimales) > 2 || !soloDigitos(entero) || !soloDigitos(decimales) {
+		return 0, ErrFormato
+	}
+	for len(decimales) < 2 {
+		decimales += "0"
+	}
+
+	pesos, err := strconv.ParseInt(entero, 10, 64)
+	if err != nil || pesos > int64(Maximo/100) {
+		return 0, ErrFormato
+	}
+	centavos, _ := strconv.ParseInt(decimales, 10, 64)
+
+	m := Monto(pesos*100 + centavos)
+	if negativo {
+		m = -m
+	}
+	return m, nil
+}
+
+func soloDigitos(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Por multiplica el monto por una cantidad de unidades
+func (m Monto) Por(cantidad int32) Monto {
+	return m * Monto(cantidad)
+}
+
+// Proporcion devuelve m * parte / total truncado al centavo. Sirve para
+// porcentajes (parte/100) y para repartir un monto entre líneas; al truncar,
+// la suma de las partes nunca supera al monto.
+func (m Monto) Proporcion(parte, total int64) Monto {
+	if total == 0 {
+		return 0
+	}
+	num := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(parte))
+	return Monto(num.Quo(num, big.NewInt(total)).Int64())
+}
+
+// dividir calcula v * num / den redondeando al centavo; la mitad se aleja del cero
+func dividir(v Monto, num, den int64) Monto {
+	n := new(big.Int).Mul(big.NewInt(int64(v)), big.NewInt(num))
+	d := big.NewInt(den)
+
+	cociente, resto := new(big.Int).QuoRem(n, d, new(big.Int))
+	// |resto| * 2 >= den redondea hacia afuera
+	resto.Abs(resto).Mul(resto, big.NewInt(2))
+	if resto.Cmp(d) >= 0 {
+		if n.Sign() < 0 {
+			cociente.Sub(cociente, big.NewInt(1))
+		} else {
+			cociente.Add(cociente, big.NewInt(1))
+		}
+	}
+	return Monto(cociente.Int64())
+}
+
+// String devuelve el importe con punto decimal y sin separador de miles,
+// "1500.00". Es el formato que se guarda en la base y se usa en JSON.
+func (m Monto) String() string {
+	signo := ""
+	if m < 0 {
+		signo = "-"
+		m = -m
+	}
+	return fmt.Sprintf("%s%d.%02d", signo, m/100, m%100)
+}
+
+// Formato devuelve el importe para mostrar en pesos: "$ 1.500,00"
+func (m Monto) Formato() string {
+	return m.FormatoCon("$")
+}
+
+// FormatoCon devuelve el importe para mostrar con el símbolo indicado,
+// separador de miles "." y decimal ",": "US$ 1.500,00"
+func (m Monto) FormatoCon(simbolo string) string {
+	signo := ""
+	if m < 0 {
+		signo = "-"
+		m = -m
+	}
+
+	pesos := strconv.FormatInt(int64(m/100), 10)
+	var b strings.Builder
+	for i, r := range pesos {
+		if i > 0 && (len(pesos)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	return fmt.Sprintf("%s%s %s,%02d", signo, simbolo, b.String(), m%100)
+}
+
+// Scan lee un NUMERIC de Postgres, que lib/pq entrega como texto
+func (m *Monto) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.parseDB(string(v))
+	case string:
+		return m.parseDB(v)
+	case int64:
+		*m = Monto(v * 100)
+		return nil
+	case nil:
+		*m = 0
+		return nil
+	}
+	return fmt.Errorf("dinero: no se puede leer %T como Monto", src)
+}
+
+// parseDB acepta más de 2 decimales por si una columna NUMERIC no tiene
+// escala fija; lo que sobra se redondea al centavo
+func (m *Monto) parseDB(s string) error {
+	entero, decimales, ok := strings.Cut(s, ".")
+	if ok && len(decimales) > 2 {
+		redondeo := decimales[2] >= '5'
+		v, err := Parse(entero + "." + decimales[:2])
+		if err != nil {
+			return err
+		}
+		if redondeo {
+			if v < 0 || strings.HasPrefix(s, "-") {
+				v--
+			} else {
+				v++
+			}
+		}
+		*m = v
+		return nil
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value guarda el monto como texto para que Postgres lo convierta a NUMERIC sin pasar por float
+func (m Monto) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// MarshalJSON escribe el monto como string, "1500.00", igual que antes del cambio
+func (m Monto) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON acepta tanto "1500.00" como 1500.00
+func (m *Monto) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -0,0 +1,209 @@
+package dinero
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	casos := []struct {
+		texto string
+		monto Monto
+		err   bool
+	}{
+		{texto: "1500", monto: 150000},
+		{texto: "1500.5", monto: 150050},
+		{texto: "1500,50", monto: 150050},
+		{texto: " 7 ", monto: 700},
+		{texto: "0.01", monto: 1},
+		{texto: "-12.34", monto: -1234},
+		{texto: "-0,5", monto: -50},
+		{texto: "99999999.99", monto: Maximo},
+		{texto: "-99999999.99", monto: -Maximo},
+		{texto: "100000000", err: true},
+		{texto: "99999999999999999999", err: true},
+		{texto: "1.234", err: true},
+		{texto: "1.000,00", err: true},
+		{texto: ".5", err: true},
+		{texto: "1e3", err: true},
+		{texto: "", err: true},
+		{texto: "-", err: true},
+	}
+	for _, c := range casos {
+		t.Run(c.texto, func(t *testing.T) {
+			m, err := Parse(c.texto)
+			if c.err {
+				if !errors.Is(err, ErrFormato) {
+					t.Fatalf("Parse(%q) = %v, %v; se esperaba ErrFormato", c.texto, m, err)
+				}
+				return
+			}
+			if err != nil || m != c.monto {
+				t.Fatalf("Parse(%q) = %v, %v; se esperaba %v", c.texto, m, err, c.monto)
+			}
+		})
+	}
+}
+
+func TestScanRedondeaTercerDecimal(t *testing.T) {
+	casos := []struct {
+		valor any
+		monto Monto
+	}{
+		{valor: []byte("10.00"), monto: 1000},
+		{valor: []byte("5.1"), monto: 510},
+		{valor: []byte("10.004"), monto: 1000},
+		{valor: []byte("10.005"), monto: 1001},
+		{valor: []byte("10.999"), monto: 1100},
+		{valor: []byte("12.3456"), monto: 1235},
+		{valor: []byte("-10.004"), monto: -1000},
+		{valor: []byte("-10.005"), monto: -1001},
+		// "-0.00" se lee como 0: el signo sale del texto
+		{valor: []byte("-0.005"), monto: -1},
+		{valor: "3.50", monto: 350},
+		{valor: int64(3), monto: 300},
+		{valor: nil, monto: 0},
+	}
+	for _, c := range casos {
+		var m Monto
+		if err := m.Scan(c.valor); err != nil || m != c.monto {
+			t.Errorf("Scan(%v) = %v, %v; se esperaba %v", c.valor, m, err, c.monto)
+		}
+	}
+
+	var m Monto
+	if err := m.Scan(1.5); err == nil {
+		t.Errorf("Scan(float64) no devolvió error")
+	}
+	if err := m.Scan([]byte("100000000.00")); err == nil {
+		t.Errorf("Scan de un importe mayor al máximo no devolvió error")
+	}
+}
+
+func TestDividirRedondeaHaciaAfuera(t *testing.T) {
+	casos := []struct {
+		v        Monto
+		num, den int64
+		monto    Monto
+	}{
+		{v: 1, num: 1, den: 2, monto: 1},
+		{v: -1, num: 1, den: 2, monto: -1},
+		{v: 1, num: 1, den: 3, monto: 0},
+		{v: 2, num: 1, den: 3, monto: 1},
+		{v: -2, num: 1, den: 3, monto: -1},
+		{v: 5, num: 1, den: 4, monto: 1},
+		{v: -5, num: 1, den: 4, monto: -1},
+		{v: 7, num: 1, den: 2, monto: 4},
+		{v: -7, num: 1, den: 2, monto: -4},
+		{v: Maximo, num: 3, den: 1, monto: 3 * Maximo},
+	}
+	for _, c := range casos {
+		if got := dividir(c.v, c.num, c.den); got != c.monto {
+			t.Errorf("dividir(%d, %d, %d) = %d; se esperaba %d", c.v, c.num, c.den, got, c.monto)
+		}
+	}
+}
+
+func TestProporcionTrunca(t *testing.T) {
+	casos := []struct {
+		m            Monto
+		parte, total int64
+		monto        Monto
+	}{
+		{m: 1000, parte: 1, total: 3, monto: 333},
+		{m: 1000, parte: 2, total: 3, monto: 666},
+		{m: -1000, parte: 1, total: 3, monto: -333},
+		{m: 1000, parte: 10, total: 100, monto: 100},
+		{m: 99, parte: 1, total: 2, monto: 49},
+		{m: 1000, parte: 1, total: 0, monto: 0},
+		// Sin desbordar aunque el producto no entre en un int64
+		{m: Maximo, parte: int64(Maximo), total: int64(Maximo), monto: Maximo},
+	}
+	for _, c := range casos {
+		if got := c.m.Proporcion(c.parte, c.total); got != c.monto {
+			t.Errorf("%d.Proporcion(%d, %d) = %d; se esperaba %d", c.m, c.parte, c.total, got, c.monto)
+		}
+	}
+}
+
+func TestImpuestos(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		got, esp Monto
+	}{
+		{"21% sobre 100", Monto(10000).Aplicar(2100), 2100},
+		{"10,5% sobre 9,99", Monto(999).Aplicar(1050), 105},
+		{"21% incluido en 121", Monto(12100).Incluido(2100), 2100},
+		{"21% incluido en 10", Monto(1000).Incluido(2100), 174},
+		{"21% sobre un reembolso", Monto(-10000).Aplicar(2100), -2100},
+	}
+	for _, c := range casos {
+		if c.got != c.esp {
+			t.Errorf("%s = %d; se esperaba %d", c.nombre, c.got, c.esp)
+		}
+	}
+}
+
+func TestConvertir(t *testing.T) {
+	dolar := Tasa(1000 * TasaUno)
+	euro := Tasa(1100 * TasaUno)
+	tasa, err := ParseTasa("1050.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	casos := []struct {
+		nombre       string
+		m            Monto
+		desde, hacia Tasa
+		monto        Monto
+	}{
+		{"misma moneda", 12345, dolar, dolar, 12345},
+		{"US$ 10,50 a pesos", 1050, dolar, TasaUno, 1050000},
+		{"$ 10.500 a dólares", 1050000, TasaUno, dolar, 1050},
+		{"un centavo de peso no llega a un centavo de dólar", 1, TasaUno, dolar, 0},
+		{"medio centavo redondea hacia afuera", 500, TasaUno, dolar, 1},
+		{"medio centavo negativo", -500, TasaUno, dolar, -1},
+		{"tasa con decimales", 100, tasa, TasaUno, 105025},
+		{"entre dos monedas que no son la base", 10000, euro, dolar, 11000},
+	}
+	for _, c := range casos {
+		if got := Convertir(c.m, c.desde, c.hacia); got != c.monto {
+			t.Errorf("%s: Convertir(%d) = %d; se esperaba %d", c.nombre, c.m, got, c.monto)
+		}
+	}
+}
+
+func TestUnmarshalJSONRespetaMaximo(t *testing.T) {
+	var m Monto
+	for _, js := range []string{`"1500.00"`, `1500.5`} {
+		if err := m.UnmarshalJSON([]byte(js)); err != nil {
+			t.Errorf("UnmarshalJSON(%s): %v", js, err)
+		}
+	}
+	for _, js := range []string{`"100000000.00"`, `1e3`, `"1.999"`} {
+		if err := m.UnmarshalJSON([]byte(js)); !errors.Is(err, ErrFormato) {
+			t.Errorf("UnmarshalJSON(%s) = %v; se esperaba ErrFormato", js, err)
+		}
+	}
+}
+
+func TestFormato(t *testing.T) {
+	casos := []struct {
+		m              Monto
+		texto, formato string
+	}{
+		{m: 0, texto: "0.00", formato: "$ 0,00"},
+		{m: 150000, texto: "1500.00", formato: "$ 1.500,00"},
+		{m: -1234567, texto: "-12345.67", formato: "-$ 12.345,67"},
+		{m: Maximo, texto: "99999999.99", formato: "$ 99.999.999,99"},
+	}
+	for _, c := range casos {
+		if got := c.m.String(); got != c.texto {
+			t.Errorf("String(%d) = %q; se esperaba %q", c.m, got, c.texto)
+		}
+		if got := c.m.Formato(); got != c.formato {
+			t.Errorf("Formato(%d) = %q; se esperaba %q", c.m, got, c.formato)
+		}
+	}
+}
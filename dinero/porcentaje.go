@@ -0,0 +1,86 @@
+package dinero
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Porcentaje es una alícuota con 2 decimales fijos, guardada en centésimas de
+// punto: 2100 es 21% y 1050 es 10,5%.
+type Porcentaje int64
+
+// Cien es el 100%
+const Cien Porcentaje = 100_00
+
+// ErrPorcentaje se devuelve cuando un texto no es un porcentaje válido
+var ErrPorcentaje = errors.New("porcentaje inválido: se espera un número entre 0 y 100 con hasta 2 decimales")
+
+// ParsePorcentaje interpreta porcentajes como "21" o "10,5"
+func ParsePorcentaje(s string) (Porcentaje, error) {
+	m, err := Parse(s)
+	if err != nil || m < 0 || Porcentaje(m) > Cien {
+		return 0, ErrPorcentaje
+	}
+	return Porcentaje(m), nil
+}
+
+// String devuelve el porcentaje con punto decimal, "10.50", como se guarda
+func (p Porcentaje) String() string {
+	return Monto(p).String()
+}
+
+// Formato devuelve el porcentaje para mostrar, sin decimales de más: "21%", "10,5%"
+func (p Porcentaje) Formato() string {
+	s := strings.TrimRight(strings.TrimRight(p.String(), "0"), ".")
+	return strings.Replace(s, ".", ",", 1) + "%"
+}
+
+// Aplicar devuelve el p por ciento de m, redondeado al centavo: el impuesto
+// que se suma a un precio que no lo incluye
+func (m Monto) Aplicar(p Porcentaje) Monto {
+	return dividir(m, int64(p), int64(Cien))
+}
+
+// Incluido devuelve la parte de m que corresponde a un impuesto de p por
+// ciento ya incluido en el precio, m * p / (100 + p), redondeada al centavo
+func (m Monto) Incluido(p Porcentaje) Monto {
+	return dividir(m, int64(p), int64(Cien+p))
+}
+
+// Scan lee un NUMERIC de Postgres
+func (p *Porcentaje) Scan(src any) error {
+	var m Monto
+	if err := m.Scan(src); err != nil {
+		return fmt.Errorf("dinero: no se puede leer %T como Porcentaje", src)
+	}
+	*p = Porcentaje(m)
+	return nil
+}
+
+// Value guarda el porcentaje como texto para no pasar por float
+func (p Porcentaje) Value() (driver.Value, error) {
+	return p.String(), nil
+}
+
+// MarshalJSON escribe el porcentaje como string, igual que los montos
+func (p Porcentaje) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON acepta tanto "10.5" como 10.5
+func (p *Porcentaje) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParsePorcentaje(s)
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
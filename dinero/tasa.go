@@ -0,0 +1,108 @@
+package dinero
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tasa es un tipo de cambio con 6 decimales fijos, guardado en millonésimas:
+// cuántas unidades de la moneda base vale una unidad de otra moneda.
+type Tasa int64
+
+// TasaUno es la tasa de la moneda base contra sí misma
+const TasaUno Tasa = 1_000_000
+
+// ErrTasa se devuelve cuando un texto no es una tasa válida
+var ErrTasa = errors.New("tasa inválida: se espera un número positivo con hasta 6 decimales")
+
+// ParseTasa interpreta tasas como "1050" o "1050.25"
+func ParseTasa(s string) (Tasa, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", ".", 1)
+	entero, decimales, _ := strings.Cut(s, ".")
+	if entero == "" || len(decimales) > 6 || !soloDigitos(entero) || !soloDigitos(decimales) {
+		return 0, ErrTasa
+	}
+	for len(decimales) < 6 {
+		decimales += "0"
+	}
+
+	unidades, err := strconv.ParseInt(entero, 10, 64)
+	if err != nil || unidades > 999_999_999_999 {
+		return 0, ErrTasa
+	}
+	millonesimas, _ := strconv.ParseInt(decimales, 10, 64)
+	t := Tasa(unidades*1_000_000 + millonesimas)
+	if t <= 0 {
+		return 0, ErrTasa
+	}
+	return t, nil
+}
+
+// String devuelve la tasa sin ceros de más, con al menos 2 decimales: "1050.25"
+func (t Tasa) String() string {
+	s := fmt.Sprintf("%d.%06d", t/1_000_000, t%1_000_000)
+	s = strings.TrimRight(s, "0")
+	if i := strings.IndexByte(s, '.'); len(s)-i-1 < 2 {
+		s += strings.Repeat("0", 2-(len(s)-i-1))
+	}
+	return s
+}
+
+// Convertir pasa un monto de una moneda a otra a partir de sus tasas contra
+// la moneda base, redondeando al centavo (la mitad se aleja del cero)
+func Convertir(m Monto, desde, hacia Tasa) Monto {
+	if desde == hacia {
+		return m
+	}
+	return dividir(m, int64(desde), int64(hacia))
+}
+
+// Scan lee un NUMERIC de Postgres; lo que exceda los 6 decimales se trunca
+func (t *Tasa) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("dinero: no se puede leer %T como Tasa", src)
+	}
+	if entero, decimales, ok := strings.Cut(s, "."); ok && len(decimales) > 6 {
+		s = entero + "." + decimales[:6]
+	}
+	v, err := ParseTasa(s)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
+// Value guarda la tasa como texto para no pasar por float
+func (t Tasa) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+// MarshalJSON escribe la tasa como string, igual que los montos
+func (t Tasa) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON acepta tanto "1050.25" como 1050.25
+func (t *Tasa) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParseTasa(s)
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
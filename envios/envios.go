@@ -0,0 +1,125 @@
+// Package envios cotiza el costo de envío de un pedido. Como en promociones,
+// cada tipo de metodo_envio es un Metodo registrado en tipos, así que sumar
+// una forma de cotizar es escribir su Metodo, registrarlo y agregarlo al
+// CHECK de metodo_envio.tipo.
+package envios
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+// ErrEnvio indica que el envío elegido no se puede cotizar: falta la
+// dirección, no hay tarifa para el peso, etc. Motivo se muestra al cliente.
+type ErrEnvio struct {
+	Motivo string
+}
+
+func (e ErrEnvio) Error() string {
+	return e.Motivo
+}
+
+// Envio es lo que se cotiza: el peso total en gramos y la dirección de
+// entrega, nil si no se eligió
+type Envio struct {
+	Peso      int64
+	Direccion *sqlc.Direccion
+}
+
+// Metodo es un método de envío ya configurado
+type Metodo interface {
+	// RequiereDireccion indica si hay que elegir dónde entregar
+	RequiereDireccion() bool
+	// Cotizar devuelve el costo en la moneda del método
+	Cotizar(e Envio) (dinero.Monto, error)
+}
+
+// Constructor arma el Metodo de una fila de metodo_envio con sus tarifas
+type Constructor func(m sqlc.MetodoEnvio, tarifas []sqlc.TarifaEnvio) Metodo
+
+var tipos = map[string]Constructor{}
+
+// Registrar asocia un tipo de metodo_envio.tipo con su constructor
+func Registrar(tipo string, constructor Constructor) {
+	tipos[tipo] = constructor
+}
+
+// Tipos devuelve los tipos registrados
+func Tipos() []string {
+	lista := make([]string, 0, len(tipos))
+	for t := range tipos {
+		lista = append(lista, t)
+	}
+	sort.Strings(lista)
+	return lista
+}
+
+// Cotizacion es el envío elegido con su costo en la moneda del pedido
+type Cotizacion struct {
+	Metodo    sqlc.MetodoEnvio `json:"metodo"`
+	Direccion *sqlc.Direccion  `json:"direccion"`
+	Costo     dinero.Monto     `json:"costo"`
+}
+
+// Destino es la dirección como se guarda en el pedido; vacía si se retira
+func (c Cotizacion) Destino() string {
+	if c.Direccion == nil {
+		return ""
+	}
+	return Describir(*c.Direccion)
+}
+
+// Cotizar calcula el costo del método para el envío y lo pasa a moneda.
+// tarifas puede traer las de otros métodos; se usan solo las propias. Si el
+// método no lleva dirección, la elegida se ignora.
+func Cotizar(cot *monedas.Cotizaciones, moneda string, m sqlc.MetodoEnvio, tarifas []sqlc.TarifaEnvio, e Envio) (Cotizacion, error) {
+	constructor, ok := tipos[m.Tipo]
+	if !ok {
+		return Cotizacion{}, fmt.Errorf("método de envío %d: tipo desconocido %q", m.IDMetodo, m.Tipo)
+	}
+	propias := make([]sqlc.TarifaEnvio, 0, len(tarifas))
+	for _, t := range tarifas {
+		if t.IDMetodo == m.IDMetodo {
+			propias = append(propias, t)
+		}
+	}
+
+	metodo := constructor(m, propias)
+	if !metodo.RequiereDireccion() {
+		e.Direccion = nil
+	} else if e.Direccion == nil {
+		return Cotizacion{}, ErrEnvio{Motivo: "elegí una dirección de entrega para " + m.Nombre}
+	}
+	costo, err := metodo.Cotizar(e)
+	if err != nil {
+		return Cotizacion{}, err
+	}
+	costo, err = cot.Convertir(costo, m.Moneda, moneda)
+	if err != nil {
+		return Cotizacion{}, err
+	}
+	return Cotizacion{Metodo: m, Direccion: e.Direccion, Costo: costo}, nil
+}
+
+// Describir arma la dirección en una línea, como queda en pedido.direccion_envio
+func Describir(d sqlc.Direccion) string {
+	partes := []string{d.Destinatario, d.Calle, d.Ciudad + " (" + d.CodigoPostal + ")", d.Provincia}
+	if d.Telefono != "" {
+		partes = append(partes, "Tel. "+d.Telefono)
+	}
+	return strings.Join(partes, ", ")
+}
+
+// FormatoPeso muestra gramos como "750 g" o "2,5 kg"
+func FormatoPeso(gramos int64) string {
+	if gramos < 1000 {
+		return fmt.Sprintf("%d g", gramos)
+	}
+	kg := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%d.%03d", gramos/1000, gramos%1000), "0"), ".")
+	return strings.Replace(kg, ".", ",", 1) + " kg"
+}
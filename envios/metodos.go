@@ -0,0 +1,139 @@
+package envios
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+// Tipos de método de envío (ver CHECK en schema.sql)
+const (
+	TipoRetiro = "retiro"
+	TipoFijo   = "fijo"
+	TipoTabla  = "tabla"
+)
+
+func init() {
+	Registrar(TipoRetiro, func(m sqlc.MetodoEnvio, _ []sqlc.TarifaEnvio) Metodo { return retiro{m} })
+	Registrar(TipoFijo, func(m sqlc.MetodoEnvio, _ []sqlc.TarifaEnvio) Metodo { return fijo{m} })
+	Registrar(TipoTabla, func(m sqlc.MetodoEnvio, tarifas []sqlc.TarifaEnvio) Metodo { return tabla{m, tarifas} })
+}
+
+// retiro: el cliente lo busca en el local, no lleva dirección
+type retiro struct {
+	m sqlc.MetodoEnvio
+}
+
+func (r retiro) RequiereDireccion() bool { return false }
+
+func (r retiro) Cotizar(Envio) (dinero.Monto, error) {
+	return r.m.Costo, nil
+}
+
+// fijo: el mismo costo a cualquier dirección
+type fijo struct {
+	m sqlc.MetodoEnvio
+}
+
+func (f fijo) RequiereDireccion() bool { return true }
+
+func (f fijo) Cotizar(Envio) (dinero.Monto, error) {
+	return f.m.Costo, nil
+}
+
+// tabla: el costo depende de la provincia y del peso. Se busca primero entre
+// las tarifas de la provincia y después entre las generales (sin provincia);
+// en cada grupo gana la de menor peso_hasta que alcance.
+type tabla struct {
+	m       sqlc.MetodoEnvio
+	tarifas []sqlc.TarifaEnvio
+}
+
+func (t tabla) RequiereDireccion() bool { return true }
+
+func (t tabla) Cotizar(e Envio) (dinero.Monto, error) {
+	provincia := e.Direccion.Provincia
+	if tarifa := t.buscar(e.Peso, func(p *string) bool { return p != nil && strings.EqualFold(*p, provincia) }); tarifa != nil {
+		return tarifa.Costo, nil
+	}
+	if tarifa := t.buscar(e.Peso, func(p *string) bool { return p == nil }); tarifa != nil {
+		return tarifa.Costo, nil
+	}
+	return 0, ErrEnvio{Motivo: fmt.Sprintf("%s no llega a %s con %s", t.m.Nombre, provincia, FormatoPeso(e.Peso))}
+}
+
+func (t tabla) buscar(peso int64, provincia func(*string) bool) *sqlc.TarifaEnvio {
+	var elegida *sqlc.TarifaEnvio
+	for i, tarifa := range t.tarifas {
+		if !provincia(tarifa.Provincia) || int64(tarifa.PesoHasta) < peso {
+			continue
+		}
+		if elegida == nil || tarifa.PesoHasta < elegida.PesoHasta {
+			elegida = &t.tarifas[i]
+		}
+	}
+	return elegida
+}
+
+// Validar normaliza y revisa un método antes de guardarlo. La moneda vacía se
+// completa con la base.
+func Validar(cot *monedas.Cotizaciones, m *sqlc.CreateMetodoEnvioParams) error {
+	m.Nombre = strings.TrimSpace(m.Nombre)
+	if m.Nombre == "" {
+		return errors.New("el nombre es requerido: es lo que elige el cliente")
+	}
+	if _, ok := tipos[m.Tipo]; !ok {
+		return fmt.Errorf("tipo de envío inválido: use %s", strings.Join(Tipos(), ", "))
+	}
+	if m.Moneda == "" {
+		m.Moneda = monedas.Base
+	}
+	if _, ok := cot.Buscar(m.Moneda); !ok {
+		return errors.New("moneda desconocida: " + m.Moneda)
+	}
+	if m.Costo < 0 {
+		return errors.New("el costo no puede ser negativo")
+	}
+	if m.Costo > dinero.Maximo {
+		return errors.New("el costo supera el máximo permitido")
+	}
+	if m.Tipo == TipoTabla {
+		// El costo sale de las tarifas
+		m.Costo = 0
+	}
+	return nil
+}
+
+// ValidarTarifa revisa una fila de tarifa; la provincia vacía vale para el resto del país
+func ValidarTarifa(t *sqlc.CreateTarifaEnvioParams) error {
+	if t.Provincia != nil {
+		if *t.Provincia = strings.TrimSpace(*t.Provincia); *t.Provincia == "" {
+			t.Provincia = nil
+		}
+	}
+	if t.PesoHasta < 1 {
+		return errors.New("el peso máximo (en gramos) tiene que ser mayor a cero")
+	}
+	if t.Costo < 0 {
+		return errors.New("el costo no puede ser negativo")
+	}
+	if t.Costo > dinero.Maximo {
+		return errors.New("el costo supera el máximo permitido")
+	}
+	return nil
+}
+
+// ValidarDireccion revisa los campos obligatorios de una dirección
+func ValidarDireccion(d *sqlc.CreateDireccionParams) error {
+	for _, campo := range []*string{&d.Alias, &d.Destinatario, &d.Calle, &d.Ciudad, &d.Provincia, &d.CodigoPostal, &d.Telefono} {
+		*campo = strings.TrimSpace(*campo)
+	}
+	if d.Destinatario == "" || d.Calle == "" || d.Ciudad == "" || d.Provincia == "" || d.CodigoPostal == "" {
+		return errors.New("destinatario, calle, ciudad, provincia y código postal son requeridos")
+	}
+	return nil
+}
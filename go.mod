@@ -6,6 +6,7 @@ require (
 	github.com/a-h/templ v0.3.960
 	github.com/jackc/pgx/v5 v5.7.6
 	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.37.0
 )
 
 require github.com/rs/cors v1.11.1 // indirect
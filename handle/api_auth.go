@@ -0,0 +1,76 @@
+package handle
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"carrito.com/auth"
+	sqlc "carrito.com/db/sqlc"
+)
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Token   string       `json:"token"`
+	Expira  time.Time    `json:"expira"`
+	Usuario sqlc.Usuario `json:"usuario"`
+}
+
+// APILoginHandler: POST /api/v1/login
+// Devuelve un token para usar como Authorization: Bearer <token>
+func APILoginHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req loginRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+
+		user, err := queries.GetUserByEmail(r.Context(), req.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			errorDB(w, err, "usuario")
+			return
+		}
+		// Con err == sql.ErrNoRows el hash queda vacío y se compara contra el ficticio
+		if !auth.VerificarPassword(user.PasswordHash, req.Password) {
+			errorJSON(w, http.StatusUnauthorized, "credenciales inválidas")
+			return
+		}
+
+		token, expira, err := nuevaSesion(r.Context(), queries, user)
+		if err != nil {
+			log.Printf("Error creando sesión: %v", err)
+			errorJSON(w, http.StatusInternalServerError, "error interno")
+			return
+		}
+
+		escribirJSON(w, http.StatusOK, loginResponse{Token: token, Expira: expira, Usuario: user})
+	}
+}
+
+// APILogoutHandler: POST /api/v1/logout revoca el token usado en la request
+func APILogoutHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := cerrarSesion(w, r, queries); err != nil {
+			errorDB(w, err, "sesión")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
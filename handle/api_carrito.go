@@ -0,0 +1,214 @@
+package handle
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"carrito.com/cupones"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/envios"
+)
+
+type itemCarritoRequest struct {
+	IDProducto int32 `json:"id_producto"`
+	Cantidad   int32 `json:"cantidad"`
+}
+
+// APICartHandler maneja /api/v1/cart (carrito del usuario autenticado)
+func APICartHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			apiGetCartHandler(queries)(w, r) // GET /api/v1/cart
+		case http.MethodDelete:
+			apiDeleteCartHandler(queries)(w, r) // DELETE /api/v1/cart
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APICartItemsHandler maneja /api/v1/cart/items y /api/v1/cart/items/{id}
+func APICartItemsHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			apiAddCartItemHandler(queries)(w, r) // POST /api/v1/cart/items
+		case http.MethodPut:
+			apiUpdateCartItemHandler(queries)(w, r) // PUT /api/v1/cart/items/{id}
+		case http.MethodDelete:
+			apiDeleteCartItemHandler(queries)(w, r) // DELETE /api/v1/cart/items/{id}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APICheckoutHandler: POST /api/v1/cart/checkout convierte el carrito en un pedido
+func APICheckoutHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		pedido, err := registrarCompra(r.Context(), db, queries, usuario.IDUsuario)
+		if err != nil {
+			var (
+				errStock errStockInsuficiente
+				errCupon cupones.ErrCupon
+				errEnvio envios.ErrEnvio
+			)
+			if errors.Is(err, errCarritoVacio) || errors.Is(err, errMonedaDesconocida) {
+				errorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if errors.As(err, &errStock) || errors.As(err, &errCupon) || errors.As(err, &errEnvio) {
+				errorJSON(w, http.StatusConflict, err.Error())
+				return
+			}
+			errorDB(w, err, "pedido")
+			return
+		}
+		pedido, _ = cobrarCompra(r.Context(), db, queries, pedido, usuario.IDUsuario)
+		escribirJSON(w, http.StatusCreated, pedido)
+	}
+}
+
+func apiGetCartHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+		responderCarritoJSON(w, r, queries, usuario.IDUsuario, http.StatusOK)
+	}
+}
+
+func apiDeleteCartHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		if err := queries.DeleteCart(r.Context(), usuario.IDUsuario); err != nil {
+			errorDB(w, err, "carrito")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func apiAddCartItemHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		var req itemCarritoRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		if req.Cantidad == 0 {
+			req.Cantidad = 1
+		}
+		if req.IDProducto <= 0 || req.Cantidad < 0 {
+			errorJSON(w, http.StatusBadRequest, "id_producto y cantidad deben ser positivos")
+			return
+		}
+
+		if err := agregarAlCarrito(r.Context(), queries, usuario.IDUsuario, req.IDProducto, req.Cantidad); err != nil {
+			errorDB(w, err, "producto")
+			return
+		}
+		responderCarritoJSON(w, r, queries, usuario.IDUsuario, http.StatusCreated)
+	}
+}
+
+func apiUpdateCartItemHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := idDeRuta(r, "/api/v1/cart/items/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var req itemCarritoRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		if req.Cantidad < 1 {
+			errorJSON(w, http.StatusBadRequest, "cantidad inválida")
+			return
+		}
+
+		filas, err := queries.UpdateCartItem(r.Context(), sqlc.UpdateCartItemParams{
+			IDItem:    id,
+			IDUsuario: usuario.IDUsuario,
+			Cantidad:  req.Cantidad,
+		})
+		if err != nil {
+			errorDB(w, err, "item")
+			return
+		}
+		if filas == 0 {
+			errorJSON(w, http.StatusNotFound, "item no encontrado")
+			return
+		}
+		responderCarritoJSON(w, r, queries, usuario.IDUsuario, http.StatusOK)
+	}
+}
+
+func apiDeleteCartItemHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := idDeRuta(r, "/api/v1/cart/items/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filas, err := queries.DeleteProdCarrito(r.Context(), sqlc.DeleteProdCarritoParams{
+			IDItem:    id,
+			IDUsuario: usuario.IDUsuario,
+		})
+		if err != nil {
+			errorDB(w, err, "item")
+			return
+		}
+		if filas == 0 {
+			errorJSON(w, http.StatusNotFound, "item no encontrado")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// responderCarritoJSON devuelve el carrito completo del usuario
+func responderCarritoJSON(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, idUsuario int32, status int) {
+	items, err := queries.GetCartItems(r.Context(), idUsuario)
+	if err != nil {
+		errorDB(w, err, "carrito")
+		return
+	}
+	escribirJSON(w, status, items)
+}
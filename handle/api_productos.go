@@ -0,0 +1,250 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"carrito.com/auth"
+	"carrito.com/catalogo"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+// APIProductsHandler maneja /api/v1/products
+func APIProductsHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			apiListProdHandler(db)(w, r) // GET /api/v1/products
+		case http.MethodPost:
+			RequirePermiso(auth.PermisoProductos, apiCreateProdHandler(queries)).ServeHTTP(w, r) // POST /api/v1/products
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APIProductHandler maneja /api/v1/product/{id}
+func APIProductHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/restaurar") {
+			RequirePermiso(auth.PermisoProductos, apiRestaurarProdHandler(queries)).ServeHTTP(w, r) // POST /api/v1/product/{id}/restaurar
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			apiGetProdHandler(queries)(w, r) // GET /api/v1/product/{id}
+		case http.MethodPut:
+			RequirePermiso(auth.PermisoProductos, apiUpdateProdHandler(queries)).ServeHTTP(w, r) // PUT /api/v1/product/{id}
+		case http.MethodDelete:
+			RequirePermiso(auth.PermisoProductos, apiDeleteProdHandler(db, queries)).ServeHTTP(w, r) // DELETE /api/v1/product/{id}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// apiListProdHandler lista los productos publicados con los filtros de la
+// tienda (ver catalogo.Leer); con ?archivados=1, y permiso de productos, los
+// archivados. Devuelve una página, con la URL de la siguiente en el header Link.
+func apiListProdHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f := catalogo.Leer(r.URL.Query())
+		if r.URL.Query().Get("archivados") != "" {
+			if !auth.Puede(r.Context(), auth.PermisoProductos) {
+				errorJSON(w, http.StatusForbidden, "no tienes permiso para ver los productos archivados")
+				return
+			}
+			productos, despues, err := listarAdmin(r.Context(), db, f, true)
+			if errors.Is(err, catalogo.ErrCursor) {
+				errorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if err != nil {
+				errorDB(w, err, "producto")
+				return
+			}
+			enlaceSiguiente(w, r, despues)
+			escribirJSON(w, http.StatusOK, productos)
+			return
+		}
+
+		// ?q=texto devuelve los resultados de la búsqueda con su relevancia
+		listado, err := listarProductos(r.Context(), db, f)
+		if errors.Is(err, catalogo.ErrCursor) {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			errorDB(w, err, "producto")
+			return
+		}
+		enlaceSiguiente(w, r, listado.Despues)
+		escribirJSON(w, http.StatusOK, listadoJSON(listado))
+	}
+}
+
+func apiCreateProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req sqlc.CreateProdParams
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+
+		if err := validarProducto(req.NombreProducto, req.Precio, req.Stock, req.Peso); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := monedaProducto(r.Context(), &req.Moneda); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var err error
+		if req.IDCategoria, req.Categoria, err = categoriaProducto(r.Context(), queries, req.IDCategoria, req.Categoria); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		producto, err := queries.CreateProd(r.Context(), req)
+		if err != nil {
+			errorDB(w, err, "producto")
+			return
+		}
+		escribirJSON(w, http.StatusCreated, producto)
+	}
+}
+
+func apiGetProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/product/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		producto, err := queries.GetProd(r.Context(), id)
+		if err == nil && producto.Archivado && !auth.Puede(r.Context(), auth.PermisoProductos) {
+			// Para los clientes un producto archivado ya no existe
+			err = sql.ErrNoRows
+		}
+		if err != nil {
+			errorDB(w, err, "producto")
+			return
+		}
+		escribirJSON(w, http.StatusOK, producto)
+	}
+}
+
+func apiUpdateProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/product/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var req sqlc.UpdateProductoParams
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		req.IDProducto = id
+
+		if err := validarProducto(req.NombreProducto, req.Precio, req.Stock, req.Peso); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := monedaProducto(r.Context(), &req.Moneda); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.IDCategoria, req.Categoria, err = categoriaProducto(r.Context(), queries, req.IDCategoria, req.Categoria); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		producto, err := queries.UpdateProducto(r.Context(), req)
+		if err != nil {
+			errorDB(w, err, "producto")
+			return
+		}
+		escribirJSON(w, http.StatusOK, producto)
+	}
+}
+
+// apiDeleteProdHandler archiva el producto (ver archivarProducto)
+func apiDeleteProdHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/product/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		existe, err := archivarProducto(r.Context(), db, queries, id)
+		if err != nil {
+			errorDB(w, err, "producto")
+			return
+		}
+		if !existe {
+			errorJSON(w, http.StatusNotFound, "producto no encontrado")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func apiRestaurarProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.TrimSuffix(r.URL.Path, "/restaurar")
+		id, err := idDeRuta(r, "/api/v1/product/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		producto, err := queries.RestaurarProd(r.Context(), id)
+		if err != nil {
+			errorDB(w, err, "producto")
+			return
+		}
+		escribirJSON(w, http.StatusOK, producto)
+	}
+}
+
+// validarProducto aplica las mismas reglas al formulario y a la API
+func validarProducto(nombre string, precio dinero.Monto, stock, peso int32) error {
+	if nombre == "" {
+		return errors.New("el nombre es requerido")
+	}
+	if precio <= 0 {
+		return errors.New("el precio debe ser mayor a cero")
+	}
+	if precio > dinero.Maximo {
+		return errors.New("el precio supera el máximo permitido")
+	}
+	if stock < 0 {
+		return errors.New("stock inválido")
+	}
+	if peso < 0 {
+		return errors.New("el peso (en gramos) no puede ser negativo")
+	}
+	return nil
+}
+
+// monedaProducto completa la moneda del precio con la base si no vino y
+// verifica que exista
+func monedaProducto(ctx context.Context, codigo *string) error {
+	if *codigo == "" {
+		*codigo = monedas.Base
+	}
+	if _, ok := monedas.De(ctx).Buscar(*codigo); !ok {
+		return errors.New("moneda desconocida: " + *codigo)
+	}
+	return nil
+}
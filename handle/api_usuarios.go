@@ -0,0 +1,277 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"carrito.com/auth"
+	sqlc "carrito.com/db/sqlc"
+)
+
+// usuarioRequest es el body de POST /users y PUT /user/{id}.
+// Password, Rol y Region son opcionales: sin password el usuario no puede
+// iniciar sesión hasta que se le asigne una, y sin rol o región se mantiene
+// el actual (cliente y sin región al crear).
+type usuarioRequest struct {
+	NombreUsuario string  `json:"nombre_usuario"`
+	Email         string  `json:"email"`
+	Password      string  `json:"password"`
+	Rol           string  `json:"rol"`
+	Region        *string `json:"region"`
+}
+
+func (u usuarioRequest) validar() error {
+	if u.NombreUsuario == "" || u.Email == "" {
+		return errors.New("nombre_usuario y email son requeridos")
+	}
+	if u.Password != "" && len(u.Password) < auth.PasswordMinLen {
+		return errors.New("la contraseña debe tener al menos 8 caracteres")
+	}
+	if u.Rol != "" && !auth.RolValido(u.Rol) {
+		return errors.New("rol inválido")
+	}
+	return nil
+}
+
+// APIUsersHandler maneja /api/v1/users
+func APIUsersHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			apiListUsersHandler(queries)(w, r) // GET /api/v1/users
+		case http.MethodPost:
+			apiCreateUserHandler(queries)(w, r) // POST /api/v1/users
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APIUserHandler maneja /api/v1/user/{id}
+func APIUserHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			apiGetUserHandler(queries)(w, r) // GET /api/v1/user/{id}
+		case http.MethodPut:
+			apiUpdateUserHandler(db, queries)(w, r) // PUT /api/v1/user/{id}
+		case http.MethodDelete:
+			apiDeleteUserHandler(queries)(w, r) // DELETE /api/v1/user/{id}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func apiListUsersHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuarios, err := queries.ListUsers(r.Context())
+		if err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+		escribirJSON(w, http.StatusOK, usuarios)
+	}
+}
+
+func apiCreateUserHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req usuarioRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		if err := req.validar(); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		params := sqlc.CreateUserParams{
+			NombreUsuario: req.NombreUsuario,
+			Email:         req.Email,
+		}
+		if req.Password != "" {
+			hash, err := auth.HashPassword(req.Password)
+			if err != nil {
+				errorJSON(w, http.StatusInternalServerError, "error interno")
+				return
+			}
+			params.PasswordHash = hash
+		}
+
+		usuario, err := queries.CreateUser(r.Context(), params)
+		if err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+
+		if usuario, err = aplicarRol(r.Context(), queries, usuario, req.Rol); err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+		if usuario, err = aplicarRegion(r.Context(), queries, usuario, req.Region); err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+		escribirJSON(w, http.StatusCreated, usuario)
+	}
+}
+
+func apiGetUserHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/user/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		usuario, err := queries.GetUser(r.Context(), id)
+		if err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+		escribirJSON(w, http.StatusOK, usuario)
+	}
+}
+
+// apiUpdateUserHandler modifica el usuario. Si cambia la contraseña o el rol
+// cierra todas sus sesiones en la misma transacción, así un admin degradado o
+// una sesión robada no conservan el acceso.
+func apiUpdateUserHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/user/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var req usuarioRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		if err := req.validar(); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var hash string
+		if req.Password != "" {
+			if hash, err = auth.HashPassword(req.Password); err != nil {
+				errorJSON(w, http.StatusInternalServerError, "error interno")
+				return
+			}
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+		defer tx.Rollback()
+		qtx := queries.WithTx(tx)
+
+		usuario, err := qtx.UpdateUser(r.Context(), sqlc.UpdateUserParams{
+			IDUsuario:     id,
+			NombreUsuario: req.NombreUsuario,
+			Email:         req.Email,
+		})
+		if err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+
+		if hash != "" {
+			err = qtx.UpdateUserPassword(r.Context(), sqlc.UpdateUserPasswordParams{
+				IDUsuario:    id,
+				PasswordHash: hash,
+			})
+			if err != nil {
+				errorDB(w, err, "usuario")
+				return
+			}
+		}
+
+		rolAnterior := usuario.Rol
+		if usuario, err = aplicarRol(r.Context(), qtx, usuario, req.Rol); err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+		if usuario, err = aplicarRegion(r.Context(), qtx, usuario, req.Region); err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+
+		if hash != "" || usuario.Rol != rolAnterior {
+			if err := qtx.DeleteSesionesUsuario(r.Context(), id); err != nil {
+				errorDB(w, err, "usuario")
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+		escribirJSON(w, http.StatusOK, usuario)
+	}
+}
+
+func apiDeleteUserHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/user/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		filas, err := queries.DeleteUser(r.Context(), id)
+		if err != nil {
+			errorDB(w, err, "usuario")
+			return
+		}
+		if filas == 0 {
+			errorJSON(w, http.StatusNotFound, "usuario no encontrado")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// aplicarRol cambia el rol si se pidió uno distinto al actual
+func aplicarRol(ctx context.Context, queries *sqlc.Queries, usuario sqlc.Usuario, rol string) (sqlc.Usuario, error) {
+	if rol == "" || rol == usuario.Rol {
+		return usuario, nil
+	}
+	err := queries.UpdateUserRol(ctx, sqlc.UpdateUserRolParams{
+		IDUsuario: usuario.IDUsuario,
+		Rol:       rol,
+	})
+	if err != nil {
+		return usuario, err
+	}
+	usuario.Rol = rol
+	return usuario, nil
+}
+
+// aplicarRegion cambia la región fiscal del usuario si se indicó una
+func aplicarRegion(ctx context.Context, queries *sqlc.Queries, usuario sqlc.Usuario, region *string) (sqlc.Usuario, error) {
+	if region == nil {
+		return usuario, nil
+	}
+	nueva := strings.TrimSpace(*region)
+	if nueva == usuario.Region {
+		return usuario, nil
+	}
+	err := queries.UpdateUserRegion(ctx, sqlc.UpdateUserRegionParams{
+		IDUsuario: usuario.IDUsuario,
+		Region:    nueva,
+	})
+	if err != nil {
+		return usuario, err
+	}
+	usuario.Region = nueva
+	return usuario, nil
+}
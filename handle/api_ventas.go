@@ -0,0 +1,283 @@
+package handle
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"carrito.com/cupones"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/envios"
+	"carrito.com/monedas"
+	"carrito.com/pedidos"
+)
+
+// pedidoRequest es el body de POST /sales: un pedido cargado por un administrador
+// a nombre de un usuario, con precios y stock tomados de los productos.
+// Moneda es opcional y por defecto es la base; Cupon también es opcional.
+// Envio elige el método y una dirección del usuario; vacío usa el primer
+// método activo y la primera dirección.
+type pedidoRequest struct {
+	IDUsuario int32          `json:"id_usuario"`
+	Moneda    string         `json:"moneda"`
+	Cupon     string         `json:"cupon"`
+	Envio     seleccionEnvio `json:"envio"`
+	Items     []lineaPedido  `json:"items"`
+}
+
+func (p pedidoRequest) validar() error {
+	if p.IDUsuario <= 0 {
+		return errors.New("id_usuario inválido")
+	}
+	if len(p.Items) == 0 {
+		return errors.New("el pedido no tiene items")
+	}
+	for _, item := range p.Items {
+		if item.IDProducto <= 0 || item.Cantidad < 1 {
+			return errors.New("id_producto y cantidad deben ser positivos")
+		}
+	}
+	return nil
+}
+
+// APISalesHandler maneja /api/v1/sales (administración de pedidos)
+func APISalesHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			apiListPedidosHandler(queries)(w, r) // GET /api/v1/sales
+		case http.MethodPost:
+			apiCreatePedidoHandler(db, queries)(w, r) // POST /api/v1/sales
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APISaleHandler maneja /api/v1/sale/{id}
+func APISaleHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			apiGetPedidoHandler(queries)(w, r) // GET /api/v1/sale/{id}
+		case http.MethodPost:
+			if !strings.HasSuffix(r.URL.Path, "/reembolso") {
+				errorJSON(w, http.StatusNotFound, "ruta no encontrada")
+				return
+			}
+			r.URL.Path = strings.TrimSuffix(r.URL.Path, "/reembolso")
+			apiReembolsoPedidoHandler(db, queries)(w, r) // POST /api/v1/sale/{id}/reembolso
+		case http.MethodPatch:
+			apiEstadoPedidoHandler(db, queries)(w, r) // PATCH /api/v1/sale/{id}
+		case http.MethodDelete:
+			apiCancelarPedidoHandler(db, queries)(w, r) // DELETE /api/v1/sale/{id}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func apiListPedidosHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lista, err := queries.ListPedidos(r.Context())
+		if err != nil {
+			errorDB(w, err, "pedido")
+			return
+		}
+		detalles, err := detallesPedidos(r.Context(), queries, lista)
+		if err != nil {
+			errorDB(w, err, "pedido")
+			return
+		}
+		escribirJSON(w, http.StatusOK, detalles)
+	}
+}
+
+func apiCreatePedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pedidoRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		if err := req.validar(); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx := r.Context()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			errorDB(w, err, "pedido")
+			return
+		}
+		defer tx.Rollback()
+
+		if req.Moneda == "" {
+			req.Moneda = monedas.Base
+		}
+		pedido, err := crearPedido(ctx, queries.WithTx(tx), req.IDUsuario, req.Moneda, cupones.Normalizar(req.Cupon), req.Envio, req.Items)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			var errStock errStockInsuficiente
+			if errors.As(err, &errStock) {
+				errorJSON(w, http.StatusConflict, err.Error())
+				return
+			}
+			var (
+				errCupon cupones.ErrCupon
+				errEnvio envios.ErrEnvio
+			)
+			if errors.Is(err, errMonedaDesconocida) || errors.As(err, &errCupon) || errors.As(err, &errEnvio) {
+				errorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			// Un producto inexistente llega como ErrNoRows desde GetProdForUpdate
+			// y un usuario inexistente como violación de FK
+			errorDB(w, err, "producto")
+			return
+		}
+		escribirJSON(w, http.StatusCreated, pedido)
+	}
+}
+
+func apiGetPedidoHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/sale/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		pedido, err := queries.GetPedido(r.Context(), id)
+		if err != nil {
+			errorDB(w, err, "pedido")
+			return
+		}
+		responderPedidoJSON(w, r, queries, pedido, http.StatusOK)
+	}
+}
+
+// estadoRequest es el body de PATCH /sale/{id}
+type estadoRequest struct {
+	Estado pedidos.Estado `json:"estado"`
+}
+
+func apiEstadoPedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := idDeRuta(r, "/api/v1/sale/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var req estadoRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		if !req.Estado.Valido() {
+			errorJSON(w, http.StatusBadRequest, "estado inválido")
+			return
+		}
+
+		pedido, err := cambiarEstadoPedido(r.Context(), db, queries, id, req.Estado, usuario.IDUsuario)
+		if err != nil {
+			var errTransicion pedidos.ErrTransicion
+			if errors.As(err, &errTransicion) {
+				errorJSON(w, http.StatusConflict, err.Error())
+				return
+			}
+			errorDB(w, err, "pedido")
+			return
+		}
+		responderPedidoJSON(w, r, queries, pedido, http.StatusOK)
+	}
+}
+
+// apiCancelarPedidoHandler: DELETE /sale/{id} no borra el pedido, lo cancela
+// para que quede en el historial con su stock y su dinero devueltos
+func apiCancelarPedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := idDeRuta(r, "/api/v1/sale/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		pedido, err := cambiarEstadoPedido(r.Context(), db, queries, id, pedidos.EstadoCancelado, usuario.IDUsuario)
+		if err != nil {
+			var errTransicion pedidos.ErrTransicion
+			if errors.As(err, &errTransicion) {
+				errorJSON(w, http.StatusConflict, err.Error())
+				return
+			}
+			errorDB(w, err, "pedido")
+			return
+		}
+		responderPedidoJSON(w, r, queries, pedido, http.StatusOK)
+	}
+}
+
+// reembolsoRequest es el body de POST /sale/{id}/reembolso. Sin items se
+// reembolsa todo lo pendiente.
+type reembolsoRequest struct {
+	Items  []lineaReembolso `json:"items"`
+	Motivo string           `json:"motivo"`
+}
+
+func apiReembolsoPedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := idDeRuta(r, "/api/v1/sale/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var req reembolsoRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+
+		reembolso, err := reembolsarPedido(r.Context(), db, queries, id, req.Items, req.Motivo, usuario.IDUsuario)
+		if err != nil {
+			var errR errReembolso
+			if errors.As(err, &errR) {
+				errorJSON(w, http.StatusConflict, err.Error())
+				return
+			}
+			errorDB(w, err, "pedido")
+			return
+		}
+		escribirJSON(w, http.StatusCreated, reembolso)
+	}
+}
+
+// responderPedidoJSON devuelve el pedido con sus líneas, historial y reembolsos
+func responderPedidoJSON(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, pedido sqlc.Pedido, status int) {
+	detalles, err := detallesPedidos(r.Context(), queries, []sqlc.Pedido{pedido})
+	if err != nil {
+		errorDB(w, err, "pedido")
+		return
+	}
+	escribirJSON(w, status, detalles[0])
+}
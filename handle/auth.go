@@ -1,15 +1,17 @@
 package handle
 
 import (
+	"database/sql"
 	"log"
 	"net/http"
-	"strconv"
-	"time"
 
+	"carrito.com/auth"
 	sqlc "carrito.com/db/sqlc"
 	"carrito.com/views"
 )
 
+const credencialesInvalidas = "Credenciales inválidas. Intenta de nuevo."
+
 // --- LOGIN ---
 func LoginHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -35,46 +37,72 @@ func ProcessLoginHandler(queries *sqlc.Queries) http.HandlerFunc {
 		}
 
 		email := r.FormValue("email")
-		usuario := r.FormValue("usuario")
-
-		// Validación Mock
-		usuarioValido := false
-		if (email == "admin@test.com" && usuario == "1234") || email != "" {
-			usuarioValido = true
-		}
+		password := r.FormValue("password")
 
-		// Verificar si el usuario existe en la base de datos
+		// Mismo mensaje para email inexistente y password incorrecta,
+		// así no se puede averiguar qué emails están registrados.
 		user, err := queries.GetUserByEmail(r.Context(), email)
-		if err != nil || user.Email == "" {
-			views.AlertError("Usuario no encontrado. Regístrate primero.").Render(r.Context(), w)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Printf("Error buscando usuario: %v", err)
+				views.AlertError("Error al iniciar sesión. Intenta de nuevo.").Render(r.Context(), w)
+				return
+			}
+			// Comparamos igual contra un hash ficticio para que el tiempo de
+			// respuesta no delate si el email existe.
+			auth.VerificarPassword("", password)
+			views.AlertError(credencialesInvalidas).Render(r.Context(), w)
 			return
 		}
 
-		if !usuarioValido {
-			views.AlertError("Credenciales incorrectas. Intenta de nuevo.").Render(r.Context(), w)
+		if !auth.VerificarPassword(user.PasswordHash, password) {
+			views.AlertError(credencialesInvalidas).Render(r.Context(), w)
 			return
 		}
 
-		CrearSesion(w, user)
+		if err := CrearSesion(w, r, queries, user); err != nil {
+			log.Printf("Error creando sesión: %v", err)
+			views.AlertError("Error al iniciar sesión. Intenta de nuevo.").Render(r.Context(), w)
+			return
+		}
 
 		w.Header().Set("HX-Redirect", "/")
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
-func LogoutHandler() http.HandlerFunc {
+func LogoutHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie := http.Cookie{
-			Name:    "session_token",
-			Value:   "",
-			Expires: time.Now().Add(-1 * time.Hour),
-			Path:    "/",
+		if err := cerrarSesion(w, r, queries); err != nil {
+			log.Printf("Error cerrando sesión: %v", err)
 		}
-		http.SetCookie(w, &cookie)
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 	}
 }
 
+// LogoutAllHandler revoca todas las sesiones del usuario (todos los dispositivos)
+func LogoutAllHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		if err := queries.DeleteSesionesUsuario(r.Context(), usuario.IDUsuario); err != nil {
+			http.Error(w, "Error al cerrar sesiones: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		borrarCookieSesion(w)
+
+		redirigir(w, r, "/login")
+	}
+}
+
 // --- REGISTRO ---
 func RegisterHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -101,15 +129,34 @@ func ProcessRegisterHandler(queries *sqlc.Queries) http.HandlerFunc {
 
 		nombre := r.FormValue("usuario")
 		email := r.FormValue("email")
+		password := r.FormValue("password")
 
 		if nombre == "" || email == "" {
 			views.AlertError("Nombre y Email son requeridos").Render(r.Context(), w)
 			return
 		}
 
+		if len(password) < auth.PasswordMinLen {
+			views.AlertError("La contraseña debe tener al menos 8 caracteres").Render(r.Context(), w)
+			return
+		}
+
+		if password != r.FormValue("password_confirmacion") {
+			views.AlertError("Las contraseñas no coinciden").Render(r.Context(), w)
+			return
+		}
+
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			log.Printf("Error generando hash de password: %v", err)
+			views.AlertError("Error al registrar. Intenta de nuevo.").Render(r.Context(), w)
+			return
+		}
+
 		params := sqlc.CreateUserParams{
 			NombreUsuario: nombre,
 			Email:         email,
+			PasswordHash:  hash,
 		}
 
 		user, err := queries.CreateUser(r.Context(), params)
@@ -117,24 +164,13 @@ func ProcessRegisterHandler(queries *sqlc.Queries) http.HandlerFunc {
 			views.AlertError("Error al registrar: prueba con otro usuario/email.").Render(r.Context(), w)
 			return
 		}
-		log.Print(user)
-		CrearSesion(w, user)
+		if err := CrearSesion(w, r, queries, user); err != nil {
+			log.Printf("Error creando sesión: %v", err)
+			views.AlertError("Error al iniciar sesión. Intenta de nuevo.").Render(r.Context(), w)
+			return
+		}
 
 		w.Header().Set("HX-Redirect", "/")
 		w.WriteHeader(http.StatusOK)
 	}
 }
-
-func CrearSesion(w http.ResponseWriter, usuario sqlc.Usuario) {
-	expiration := time.Now().Add(24 * time.Hour)
-
-	cookie := http.Cookie{
-		Name:     "session_token",
-		Value:    strconv.Itoa(int(usuario.IDUsuario)),
-		Expires:  expiration,
-		Path:     "/",
-		HttpOnly: true,
-	}
-
-	http.SetCookie(w, &cookie)
-}
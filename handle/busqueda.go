@@ -0,0 +1,29 @@
+package handle
+
+import (
+	"carrito.com/busqueda"
+	"carrito.com/catalogo"
+	sqlc "carrito.com/db/sqlc"
+)
+
+// resultadoBusqueda es un producto encontrado en las respuestas JSON. Los
+// campos resaltados son HTML escapado con las palabras encontradas entre <mark>.
+type resultadoBusqueda struct {
+	sqlc.Producto
+	Rango                float32 `json:"rango"`
+	NombreResaltado      string  `json:"nombre_resaltado"`
+	DescripcionResaltada string  `json:"descripcion_resaltada"`
+}
+
+func resultadosJSON(filas []catalogo.Resultado) []resultadoBusqueda {
+	resultados := make([]resultadoBusqueda, len(filas))
+	for i, f := range filas {
+		resultados[i] = resultadoBusqueda{
+			Producto:             f.Producto,
+			Rango:                f.Rango,
+			NombreResaltado:      busqueda.HTML(f.NombreResaltado),
+			DescripcionResaltada: busqueda.HTML(f.DescripcionResaltada),
+		}
+	}
+	return resultados
+}
@@ -1,10 +1,13 @@
 package handle
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"carrito.com/auth"
 	sqlc "carrito.com/db/sqlc"
 	"carrito.com/views"
 )
@@ -26,64 +29,33 @@ func CartHandler(queries *sqlc.Queries) http.HandlerFunc {
 // getCartHandler obtiene los items de un carrito por su ID
 func getCartHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_token")
-		if err != nil {
-			http.Error(w, "No hay sesión activa", http.StatusUnauthorized)
-			return
-		}
-
-		id, err := strconv.Atoi(cookie.Value)
-		if err != nil {
-			http.Error(w, "ID de usuario inválido", http.StatusBadRequest)
-			return
-		}
-
-		// Obtener items del carrito
-		carritoItems, err := queries.GetCartItems(r.Context(), int32(id))
-		if err != nil {
-			if err == sql.ErrNoRows {
-				http.NotFound(w, r)
-			} else {
-				http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
-			}
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
 			return
 		}
 
-		componente := views.CarritoList(carritoItems)
-		componente.Render(r.Context(), w)
+		renderCarrito(w, r, queries, usuario.IDUsuario)
 	}
 }
 
 func deleteCartHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr, err := r.Cookie("session_token")
-		id, err := strconv.Atoi(idStr.Value)
-		if err != nil {
-			http.Error(w, "ID de carrito inválido", http.StatusBadRequest)
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
 			return
 		}
 
-		err = queries.DeleteCart(r.Context(), int32(id))
+		err := queries.DeleteCart(r.Context(), usuario.IDUsuario)
 		if err != nil {
 			http.Error(w, "Error al eliminar carrito: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// Obtener items del carrito
-		carritoItems, err := queries.GetCartItems(r.Context(), int32(id))
-		if err != nil {
-			if err == sql.ErrNoRows {
-				http.NotFound(w, r)
-			} else {
-				http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
-			}
+		if quiereJSON(r) {
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-
-		componente := views.CarritoList(carritoItems)
-		componente.Render(r.Context(), w)
-
-		w.WriteHeader(http.StatusNoContent)
+		renderCarrito(w, r, queries, usuario.IDUsuario)
 	}
 }
 
@@ -106,8 +78,10 @@ func CartItemHandler(queries *sqlc.Queries) http.HandlerFunc {
 
 func addCartHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idUsuarioStr, err := r.Cookie("session_token")
-		idUsuario, err := strconv.Atoi(idUsuarioStr.Value)
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
 
 		idStr := r.URL.Path[len("/carrito/items/"):]
 		idProducto, err := strconv.Atoi(idStr)
@@ -116,80 +90,96 @@ func addCartHandler(queries *sqlc.Queries) http.HandlerFunc {
 			return
 		}
 
-		// Intento obtener el item del carrito
-		item, err := queries.GetCartItemByUserAndProduct(
-			r.Context(),
-			sqlc.GetCartItemByUserAndProductParams{
-				IDUsuario:  int32(idUsuario),
-				IDProducto: int32(idProducto),
-			},
-		)
-
-		if err == nil {
-			// Existe → sumo cantidad
-			update := sqlc.UpdateCartItemParams{
-				IDItem:   item.IDItem,
-				Cantidad: item.Cantidad + 1,
-			}
-
-			if err := queries.UpdateCartItem(r.Context(), update); err != nil {
-				http.Error(w, "Error al actualizar cantidad", http.StatusInternalServerError)
+		// Desde la tienda se agrega de a uno; por JSON se puede indicar la cantidad
+		cantidad := 1
+		if esJSON(r) {
+			var req itemCarritoRequest
+			if err := leerJSON(w, r, &req); err != nil {
+				http.Error(w, "JSON inválido", http.StatusBadRequest)
 				return
 			}
-
-		} else {
-			// No existe → creo nuevo
-			req := sqlc.AddToCartParams{
-				IDUsuario:  int32(idUsuario),
-				IDProducto: int32(idProducto),
-				Cantidad:   1,
-			}
-
-			if _, err := queries.AddToCart(r.Context(), req); err != nil {
-				http.Error(w, "Error al agregar producto", http.StatusInternalServerError)
-				return
+			if req.Cantidad != 0 {
+				cantidad = int(req.Cantidad)
 			}
 		}
+		if cantidad < 1 {
+			http.Error(w, "Cantidad inválida", http.StatusBadRequest)
+			return
+		}
 
-		// 🔹 Renderizo solo el carrito actualizado
-		carritoItems, err := queries.GetCartItems(r.Context(), int32(idUsuario))
+		err = agregarAlCarrito(r.Context(), queries, usuario.IDUsuario, int32(idProducto), int32(cantidad))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "El producto ya no está disponible", http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Error cargando carrito", http.StatusInternalServerError)
+			http.Error(w, "Error al agregar producto", http.StatusInternalServerError)
 			return
 		}
 
-		componente := views.CarritoList(carritoItems)
-		componente.Render(r.Context(), w)
+		// 🔹 Renderizo solo el carrito actualizado
+		renderCarrito(w, r, queries, usuario.IDUsuario)
 	}
 }
 
 func updateItemHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
 		idStr := r.URL.Path[len("/carrito/items/"):]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
 			http.Error(w, "ID del item inválido", http.StatusBadRequest)
 			return
 		}
-		var req sqlc.UpdateCartItemParams
 
-		req.IDItem = int32(id)
+		var cantidad int
+		if esJSON(r) {
+			var req itemCarritoRequest
+			if err := leerJSON(w, r, &req); err != nil {
+				http.Error(w, "JSON inválido", http.StatusBadRequest)
+				return
+			}
+			cantidad = int(req.Cantidad)
+		} else {
+			cantidad, _ = strconv.Atoi(r.FormValue("cantidad"))
+		}
+		if cantidad < 1 {
+			http.Error(w, "Cantidad inválida", http.StatusBadRequest)
+			return
+		}
+
+		req := sqlc.UpdateCartItemParams{
+			IDItem:    int32(id),
+			IDUsuario: usuario.IDUsuario,
+			Cantidad:  int32(cantidad),
+		}
 
 		// Ejecutar la actualización en la base de datos
-		if err := queries.UpdateCartItem(r.Context(), req); err != nil {
+		filas, err := queries.UpdateCartItem(r.Context(), req)
+		if err != nil {
 			http.Error(w, "Error al actualizar item: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if filas == 0 {
+			http.NotFound(w, r)
+			return
+		}
 
-		// Respuesta exitosa
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
+		renderCarrito(w, r, queries, usuario.IDUsuario)
 	}
 }
 
 func deleteCartItemsHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
 		idStr := r.URL.Path[len("/carrito/items/"):]
 		id, err := strconv.Atoi(idStr)
 		if err != nil {
@@ -197,29 +187,136 @@ func deleteCartItemsHandler(queries *sqlc.Queries) http.HandlerFunc {
 			return
 		}
 
-		err = queries.DeleteProdCarrito(r.Context(), int32(id))
+		// Solo se borra si el item pertenece al usuario de la sesión
+		_, err = queries.DeleteProdCarrito(r.Context(), sqlc.DeleteProdCarritoParams{
+			IDItem:    int32(id),
+			IDUsuario: usuario.IDUsuario,
+		})
 		if err != nil {
 			http.Error(w, "Error al eliminar producto del carrito: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		cookie, _ := r.Cookie("session_token")
-		id_usuario, _ := strconv.Atoi(cookie.Value)
+		renderCarrito(w, r, queries, usuario.IDUsuario)
+	}
+}
+
+// renderCarrito responde el carrito del usuario como JSON o como fragmento templ
+// según el header Accept de la request.
+func renderCarrito(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, idUsuario int32) {
+	renderCarritoConAviso(w, r, queries, idUsuario, "")
+}
+
+// renderCarritoConAviso es renderCarrito con un mensaje sobre el cupón o el
+// envío, por ejemplo cuando el código ingresado no aplica
+func renderCarritoConAviso(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, idUsuario int32, aviso string) {
+	// Obtener items del carrito
+	carritoItems, err := queries.GetCartItems(r.Context(), idUsuario)
+	if err != nil {
+		http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if quiereJSON(r) {
+		escribirJSON(w, http.StatusOK, carritoItems)
+		return
+	}
 
-		// Obtener items del carrito
-		carritoItems, err := queries.GetCartItems(r.Context(), int32(id_usuario))
+	// Promociones, cupón, impuestos y envío se recalculan en cada render porque el carrito pudo
+	// cambiar; el checkout repite el mismo cálculo
+	promos, err := promocionesCarrito(r.Context(), queries, carritoItems)
+	if err != nil {
+		http.Error(w, "Error al calcular las promociones: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	aplicado, motivo, err := cuponDelCarrito(r.Context(), queries, idUsuario, promos)
+	if err != nil {
+		http.Error(w, "Error al calcular el descuento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if aviso == "" {
+		aviso = motivo
+	}
+
+	cotizacion, avisoEnvio, err := envioDelCarrito(r.Context(), queries, idUsuario, carritoItems)
+	if err != nil {
+		http.Error(w, "Error al calcular el envío: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	usuario, _ := auth.UsuarioActual(r.Context())
+	conImpuestos, err := impuestosCarrito(r.Context(), queries, regionFiscal(cotizacion, usuario.Region), promos, aplicado)
+	if err != nil {
+		http.Error(w, "Error al calcular los impuestos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Con dirección de entrega la región es su provincia: no hay nada que elegir
+	var regiones []string
+	if cotizacion == nil || cotizacion.Direccion == nil {
+		regiones, err = queries.ListRegionesImpuesto(r.Context())
 		if err != nil {
-			if err == sql.ErrNoRows {
-				http.NotFound(w, r)
-			} else {
-				http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
-			}
+			http.Error(w, "Error al leer las regiones: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+	}
+	metodos, err := queries.ListMetodosEnvioActivos(r.Context())
+	if err != nil {
+		http.Error(w, "Error al leer los métodos de envío: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	direcciones, err := queries.ListDirecciones(r.Context(), idUsuario)
+	if err != nil {
+		http.Error(w, "Error al leer las direcciones: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		componente := views.CarritoList(carritoItems)
-		componente.Render(r.Context(), w)
+	componente := views.CarritoList(views.ResumenCarrito{
+		Items:       carritoItems,
+		Promos:      promos,
+		Cupon:       aplicado,
+		Impuestos:   conImpuestos,
+		Regiones:    regiones,
+		Envio:       cotizacion,
+		Metodos:     metodos,
+		Direcciones: direcciones,
+		Aviso:       aviso,
+		AvisoEnvio:  avisoEnvio,
+	})
+	componente.Render(r.Context(), w)
+}
+
+// agregarAlCarrito suma la cantidad si el producto ya está en el carrito o crea el item.
+// Devuelve sql.ErrNoRows si el producto no existe o está archivado.
+func agregarAlCarrito(ctx context.Context, queries *sqlc.Queries, idUsuario, idProducto, cantidad int32) error {
+	// Intento obtener el item del carrito
+	item, err := queries.GetCartItemByUserAndProduct(
+		ctx,
+		sqlc.GetCartItemByUserAndProductParams{
+			IDUsuario:  idUsuario,
+			IDProducto: idProducto,
+		},
+	)
+
+	if err == nil {
+		// Existe → sumo cantidad
+		update := sqlc.UpdateCartItemParams{
+			IDItem:    item.IDItem,
+			IDUsuario: idUsuario,
+			Cantidad:  item.Cantidad + cantidad,
+		}
+		_, err = queries.UpdateCartItem(ctx, update)
+		return err
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
 
-		w.WriteHeader(http.StatusNoContent)
+	// No existe → creo nuevo
+	req := sqlc.AddToCartParams{
+		IDUsuario:  idUsuario,
+		IDProducto: idProducto,
+		Cantidad:   cantidad,
 	}
+	_, err = queries.AddToCart(ctx, req)
+	return err
 }
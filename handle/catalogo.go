@@ -0,0 +1,168 @@
+package handle
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"carrito.com/busqueda"
+	"carrito.com/catalogo"
+	"carrito.com/categorias"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/monedas"
+	"carrito.com/views"
+)
+
+// consultaFiltro pasa el filtro de la URL a la consulta de la lista: el rango
+// de precios de la moneda de la sesión a la base y las categorías elegidas a
+// sus ids con los de sus subcategorías. Pide un producto más que el tamaño de
+// la página para saber si hay otra.
+func consultaFiltro(ctx context.Context, f catalogo.Filtro, archivados bool) (catalogo.Consulta, error) {
+	cot := monedas.De(ctx)
+	min, err := cot.Convertir(f.Min, monedas.Actual(ctx), monedas.Base)
+	if err != nil {
+		return catalogo.Consulta{}, err
+	}
+	max, err := cot.Convertir(f.Max, monedas.Actual(ctx), monedas.Base)
+	if err != nil {
+		return catalogo.Consulta{}, err
+	}
+	return catalogo.Consulta{
+		Archivados:   archivados,
+		PrecioMin:    min,
+		PrecioMax:    max,
+		PorCategoria: len(f.Categorias) > 0,
+		IDs:          f.IDs(categorias.De(ctx)),
+		ConStock:     f.ConStock,
+		Orden:        f.Orden,
+		Despues:      f.Despues,
+		Limite:       tamPagina(f) + 1,
+	}, nil
+}
+
+// tamPagina es la cantidad de productos por página del filtro
+func tamPagina(f catalogo.Filtro) int32 {
+	if f.Limite <= 0 {
+		return catalogo.TamPagina
+	}
+	return f.Limite
+}
+
+// paginar deja las filas de la página y, si sobró la de más que se pidió,
+// devuelve el id de la última para pedir la siguiente; 0 si no hay más
+func paginar(filas []catalogo.Resultado, f catalogo.Filtro) ([]catalogo.Resultado, int32) {
+	n := int(tamPagina(f))
+	if len(filas) <= n {
+		return filas, 0
+	}
+	filas = filas[:n]
+	return filas, filas[n-1].IDProducto
+}
+
+// productosDe quita de los resultados lo que es de la búsqueda
+func productosDe(filas []catalogo.Resultado) []sqlc.Producto {
+	productos := make([]sqlc.Producto, len(filas))
+	for i, f := range filas {
+		productos[i] = f.Producto
+	}
+	return productos
+}
+
+// enlaceSiguiente agrega a las respuestas JSON el header Link con la URL de
+// la página siguiente: la misma consulta con despues en el último producto
+func enlaceSiguiente(w http.ResponseWriter, r *http.Request, despues int32) {
+	if despues == 0 {
+		return
+	}
+	v := r.URL.Query()
+	v.Set("despues", strconv.Itoa(int(despues)))
+	w.Header().Set("Link", "<"+r.URL.Path+"?"+v.Encode()+">; rel=\"next\"")
+}
+
+// listarProductos devuelve una página de los productos publicados que pasan
+// el filtro. Con texto en el buscador son los resultados de la búsqueda, con
+// las palabras encontradas marcadas.
+func listarProductos(ctx context.Context, db sqlc.DBTX, f catalogo.Filtro) (views.Listado, error) {
+	c, err := consultaFiltro(ctx, f, false)
+	if err != nil {
+		return views.Listado{}, err
+	}
+	c.Texto = busqueda.Consulta(f.Q)
+	filas, err := c.Listar(ctx, db)
+	if err != nil {
+		return views.Listado{}, err
+	}
+
+	listado := views.Listado{Q: f.Q}
+	filas, listado.Despues = paginar(filas, f)
+	if c.Texto != "" {
+		listado.Busqueda = true
+		listado.Resultados = filas
+	} else {
+		listado.Productos = productosDe(filas)
+	}
+	if listado.Despues != 0 {
+		listado.Siguiente = f.Siguiente("/list-products", listado.Despues)
+	}
+	return listado, nil
+}
+
+// listarAdmin devuelve una página de la lista del admin, publicados o
+// archivados, con el id del último producto si hay otra página
+func listarAdmin(ctx context.Context, db sqlc.DBTX, f catalogo.Filtro, archivados bool) ([]sqlc.Producto, int32, error) {
+	c, err := consultaFiltro(ctx, f, archivados)
+	if err != nil {
+		return nil, 0, err
+	}
+	filas, err := c.Listar(ctx, db)
+	if err != nil {
+		return nil, 0, err
+	}
+	filas, despues := paginar(filas, f)
+	return productosDe(filas), despues, nil
+}
+
+// siguienteAdmin es la URL de la página siguiente de la lista del admin
+func siguienteAdmin(f catalogo.Filtro, archivados bool, despues int32) string {
+	if despues == 0 {
+		return ""
+	}
+	url := f.Siguiente("/list-products-view", despues)
+	if archivados {
+		url += "&archivados=1"
+	}
+	return url
+}
+
+// contarFacetas cuenta los productos de cada categoría con el resto del filtro
+func contarFacetas(ctx context.Context, queries *sqlc.Queries, f catalogo.Filtro) ([]catalogo.Faceta, error) {
+	c, err := consultaFiltro(ctx, f, false)
+	if err != nil {
+		return nil, err
+	}
+	filas, err := queries.ContarProdPorCategoria(ctx, sqlc.ContarProdPorCategoriaParams{
+		PrecioMin: c.PrecioMin,
+		PrecioMax: c.PrecioMax,
+		ConStock:  c.ConStock,
+		Consulta:  busqueda.Consulta(f.Q),
+	})
+	if err != nil {
+		return nil, err
+	}
+	cantidades := make(map[int32]int64, len(filas))
+	for _, fila := range filas {
+		if fila.IDCategoria != nil {
+			cantidades[*fila.IDCategoria] = fila.Cantidad
+		}
+	}
+	return catalogo.Facetas(categorias.De(ctx), cantidades, f), nil
+}
+
+// listadoJSON es lo que devuelven las rutas JSON: los productos, o los
+// resultados con su relevancia si se buscó
+func listadoJSON(l views.Listado) any {
+	if l.Busqueda {
+		return resultadosJSON(l.Resultados)
+	}
+	return l.Productos
+}
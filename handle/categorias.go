@@ -0,0 +1,378 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"carrito.com/auth"
+	"carrito.com/catalogo"
+	"carrito.com/categorias"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/views"
+	"github.com/lib/pq"
+)
+
+var (
+	// errCategoriaConHijas impide borrar una categoría que tiene subcategorías
+	errCategoriaConHijas = errors.New("la categoría tiene subcategorías: muévelas o bórralas primero")
+	// errCategoriaEnUso impide borrar una categoría a la que apuntan cupones,
+	// promociones o impuestos
+	errCategoriaEnUso = errors.New("la categoría la usan cupones, promociones o impuestos, que sin ella valdrían para todo")
+)
+
+// categoriaProducto resuelve la categoría de un producto: por id si vino, o
+// por el texto libre de categoria. Un texto que no corresponde a ninguna
+// categoría crea una nueva de primer nivel, así la API sigue aceptando
+// nombres como antes. Devuelve el id y el nombre con que se guarda.
+func categoriaProducto(ctx context.Context, queries *sqlc.Queries, id *int32, texto string) (*int32, string, error) {
+	arbol := categorias.De(ctx)
+	if id != nil && *id != 0 {
+		c, ok := arbol.Buscar(*id)
+		if !ok {
+			return nil, "", errors.New("categoría desconocida")
+		}
+		return &c.IDCategoria, c.Nombre, nil
+	}
+	if strings.TrimSpace(texto) == "" {
+		return nil, "", nil
+	}
+	if c, ok := arbol.Resolver(texto); ok {
+		return &c.IDCategoria, c.Nombre, nil
+	}
+
+	nueva := sqlc.CreateCategoriaParams{Nombre: texto}
+	if err := arbol.Validar(0, &nueva); err != nil {
+		return nil, "", err
+	}
+	c, err := queries.CreateCategoria(ctx, nueva)
+	if err := arbol.Recargar(ctx, queries); err != nil {
+		return nil, "", err
+	}
+	if err != nil {
+		// Otra request pudo crearla al mismo tiempo
+		if c, ok := arbol.Resolver(texto); ok {
+			return &c.IDCategoria, c.Nombre, nil
+		}
+		return nil, "", err
+	}
+	return &c.IDCategoria, c.Nombre, nil
+}
+
+// categoriaRegla resuelve la categoría de un cupón, promoción o impuesto: por
+// id si vino, o por el slug o nombre en texto. A diferencia de los productos,
+// un texto que no es ninguna categoría es un error. Sin ninguno de los dos
+// devuelve nil: la regla vale para todas.
+func categoriaRegla(ctx context.Context, id *int32, texto string) (*int32, error) {
+	arbol := categorias.De(ctx)
+	if id != nil && *id != 0 {
+		c, ok := arbol.Buscar(*id)
+		if !ok {
+			return nil, errors.New("categoría desconocida")
+		}
+		return &c.IDCategoria, nil
+	}
+	if strings.TrimSpace(texto) == "" {
+		return nil, nil
+	}
+	c, ok := arbol.Resolver(texto)
+	if !ok {
+		return nil, errors.New("categoría desconocida: " + texto)
+	}
+	return &c.IDCategoria, nil
+}
+
+// idCategoriaDelForm lee el select id_categoria de los formularios de cupones,
+// promociones e impuestos; vacío es sin categoría
+func idCategoriaDelForm(r *http.Request) *int32 {
+	id, err := strconv.Atoi(r.FormValue("id_categoria"))
+	if err != nil || id == 0 {
+		return nil
+	}
+	idCategoria := int32(id)
+	return &idCategoria
+}
+
+// guardarCategoria crea (id 0) o actualiza una categoría ya validada y recarga
+// el árbol. Al renombrarla también cambia el nombre que guardan sus productos.
+func guardarCategoria(ctx context.Context, db *sql.DB, queries *sqlc.Queries, id int32, req sqlc.CreateCategoriaParams) (sqlc.Categoria, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqlc.Categoria{}, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	var categoria sqlc.Categoria
+	if id == 0 {
+		categoria, err = qtx.CreateCategoria(ctx, req)
+	} else {
+		categoria, err = qtx.UpdateCategoria(ctx, sqlc.UpdateCategoriaParams{
+			IDCategoria: id,
+			Nombre:      req.Nombre,
+			Slug:        req.Slug,
+			IDPadre:     req.IDPadre,
+		})
+		if err == nil {
+			err = qtx.RenombrarCategoriaProductos(ctx, sqlc.RenombrarCategoriaProductosParams{
+				IDCategoria: &id,
+				Categoria:   categoria.Nombre,
+			})
+		}
+	}
+	if err != nil {
+		return sqlc.Categoria{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return sqlc.Categoria{}, err
+	}
+	return categoria, categorias.De(ctx).Recargar(ctx, queries)
+}
+
+// borrarCategoria borra una categoría sin subcategorías ni reglas que la
+// usen; sus productos quedan sin categoría. Devuelve false si no existía.
+func borrarCategoria(ctx context.Context, db *sql.DB, queries *sqlc.Queries, id int32) (bool, error) {
+	arbol := categorias.De(ctx)
+	if len(arbol.Hijos(id)) > 0 {
+		return false, errCategoriaConHijas
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	if err := qtx.QuitarCategoriaProductos(ctx, &id); err != nil {
+		return false, err
+	}
+	filas, err := qtx.DeleteCategoria(ctx, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return false, errCategoriaEnUso
+	}
+	if err != nil || filas == 0 {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, arbol.Recargar(ctx, queries)
+}
+
+// categoriaDelForm lee los campos del formulario de categorías
+func categoriaDelForm(r *http.Request) sqlc.CreateCategoriaParams {
+	req := sqlc.CreateCategoriaParams{
+		Nombre: r.FormValue("nombre"),
+		Slug:   r.FormValue("slug"),
+	}
+	if padre, err := strconv.Atoi(r.FormValue("id_padre")); err == nil && padre != 0 {
+		id := int32(padre)
+		req.IDPadre = &id
+	}
+	return req
+}
+
+// AdminCategoriasHandler maneja /admin/categorias: GET muestra el árbol y
+// POST crea una categoría
+func AdminCategoriasHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			views.CategoriasAdmin().Render(r.Context(), w) // GET /admin/categorias
+		case http.MethodPost:
+			// POST /admin/categorias
+			req := categoriaDelForm(r)
+			err := categorias.De(r.Context()).Validar(0, &req)
+			if err == nil {
+				_, err = guardarCategoria(r.Context(), db, queries, 0, req)
+			}
+			if err != nil {
+				views.CategoriasAdminTabla("No se pudo crear la categoría: "+err.Error(), true).Render(r.Context(), w)
+				return
+			}
+			views.CategoriasAdminTabla("Categoría "+req.Nombre+" creada", false).Render(r.Context(), w)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminCategoriaHandler maneja /admin/categorias/{id}: PUT la modifica (nombre,
+// slug y padre) y DELETE la borra
+func AdminCategoriaHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/admin/categorias/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := categorias.De(r.Context()).Buscar(id); !ok {
+			http.Error(w, "Categoría no encontrada", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			// PUT /admin/categorias/{id}
+			req := categoriaDelForm(r)
+			err := categorias.De(r.Context()).Validar(id, &req)
+			if err == nil {
+				_, err = guardarCategoria(r.Context(), db, queries, id, req)
+			}
+			if err != nil {
+				views.CategoriasAdminTabla("No se pudo guardar la categoría: "+err.Error(), true).Render(r.Context(), w)
+				return
+			}
+			views.CategoriasAdminTabla("Categoría "+req.Nombre+" guardada", false).Render(r.Context(), w)
+		case http.MethodDelete:
+			// DELETE /admin/categorias/{id}
+			if _, err := borrarCategoria(r.Context(), db, queries, id); err != nil {
+				views.CategoriasAdminTabla("No se pudo borrar la categoría: "+err.Error(), true).Render(r.Context(), w)
+				return
+			}
+			views.CategoriasAdminTabla("Categoría borrada", false).Render(r.Context(), w)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// CategoriaHandler: GET /categoria/{slug} muestra los productos de la
+// categoría y de todas sus subcategorías
+func CategoriaHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		arbol := categorias.De(r.Context())
+		categoria, ok := arbol.BuscarSlug(strings.TrimPrefix(r.URL.Path, "/categoria/"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		// La página admite los filtros de la tienda, siempre dentro de su categoría
+		f := catalogo.Leer(r.URL.Query())
+		f.Q = ""
+		f.Categorias = []string{categoria.Slug}
+		listado, err := listarProductos(r.Context(), db, f)
+		if errors.Is(err, catalogo.ErrCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if quiereJSON(r) {
+			enlaceSiguiente(w, r, listado.Despues)
+			escribirJSON(w, http.StatusOK, listado.Productos)
+			return
+		}
+		views.CategoriaView(categoria, listado).Render(r.Context(), w)
+	}
+}
+
+// APICategoriasHandler maneja /api/v1/categorias: GET lista el árbol (cada
+// categoría con su id_padre) y POST crea una categoría (staff/admin)
+func APICategoriasHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// GET /api/v1/categorias
+			lista := []sqlc.Categoria{}
+			for _, n := range categorias.De(r.Context()).Lista() {
+				lista = append(lista, n.Categoria)
+			}
+			escribirJSON(w, http.StatusOK, lista)
+		case http.MethodPost:
+			RequirePermiso(auth.PermisoProductos, apiGuardarCategoriaHandler(db, queries, 0)).ServeHTTP(w, r) // POST /api/v1/categorias
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APICategoriaHandler maneja /api/v1/categoria/{id}: GET, y PUT y DELETE
+// (staff/admin)
+func APICategoriaHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/categoria/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			// GET /api/v1/categoria/{id}
+			categoria, ok := categorias.De(r.Context()).Buscar(id)
+			if !ok {
+				errorJSON(w, http.StatusNotFound, "categoría no encontrada")
+				return
+			}
+			escribirJSON(w, http.StatusOK, categoria)
+		case http.MethodPut:
+			RequirePermiso(auth.PermisoProductos, apiGuardarCategoriaHandler(db, queries, id)).ServeHTTP(w, r) // PUT /api/v1/categoria/{id}
+		case http.MethodDelete:
+			RequirePermiso(auth.PermisoProductos, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				existe, err := borrarCategoria(r.Context(), db, queries, id) // DELETE /api/v1/categoria/{id}
+				if errors.Is(err, errCategoriaConHijas) || errors.Is(err, errCategoriaEnUso) {
+					errorJSON(w, http.StatusConflict, err.Error())
+					return
+				}
+				if err != nil {
+					errorDB(w, err, "categoría")
+					return
+				}
+				if !existe {
+					errorJSON(w, http.StatusNotFound, "categoría no encontrada")
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// apiGuardarCategoriaHandler crea (id 0) o modifica una categoría desde JSON
+func apiGuardarCategoriaHandler(db *sql.DB, queries *sqlc.Queries, id int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req sqlc.CreateCategoriaParams
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		arbol := categorias.De(r.Context())
+		if id != 0 {
+			if _, ok := arbol.Buscar(id); !ok {
+				errorJSON(w, http.StatusNotFound, "categoría no encontrada")
+				return
+			}
+		}
+		if err := arbol.Validar(id, &req); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		categoria, err := guardarCategoria(r.Context(), db, queries, id, req)
+		if err != nil {
+			errorDB(w, err, "categoría")
+			return
+		}
+		estado := http.StatusOK
+		if id == 0 {
+			estado = http.StatusCreated
+		}
+		escribirJSON(w, estado, categoria)
+	}
+}
@@ -0,0 +1,284 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"carrito.com/cupones"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+	"carrito.com/promociones"
+)
+
+// aplicarCupon revisa vigencia y límites de uso del cupón para el usuario y
+// calcula el descuento sobre las líneas, que están en la moneda indicada
+func aplicarCupon(ctx context.Context, queries *sqlc.Queries, cupon sqlc.Cupon, idUsuario int32, moneda string, lineas []cupones.Linea) (cupones.Aplicacion, error) {
+	if err := cupones.Vigente(cupon, time.Now()); err != nil {
+		return cupones.Aplicacion{}, err
+	}
+	usos, err := queries.ContarUsosCupon(ctx, &cupon.Codigo)
+	if err != nil {
+		return cupones.Aplicacion{}, err
+	}
+	usosUsuario, err := queries.ContarUsosCuponUsuario(ctx, sqlc.ContarUsosCuponUsuarioParams{
+		Cupon:     &cupon.Codigo,
+		IDUsuario: idUsuario,
+	})
+	if err != nil {
+		return cupones.Aplicacion{}, err
+	}
+	if err := cupones.VerificarUsos(cupon, usos, usosUsuario); err != nil {
+		return cupones.Aplicacion{}, err
+	}
+	return cupones.Aplicar(monedas.De(ctx), moneda, cupon, lineas)
+}
+
+// lineasCupon arma las líneas sobre las que se calcula el cupón: lo que queda
+// de cada una después de las promociones, igual que en el checkout
+func lineasCupon(promos promociones.Resultado) []cupones.Linea {
+	lineas := make([]cupones.Linea, len(promos.Lineas))
+	for i, l := range promos.Lineas {
+		lineas[i] = cupones.Linea{Categorias: l.Categorias, Subtotal: l.Total()}
+	}
+	return lineas
+}
+
+// cuponDelCarrito aplica el cupón cargado en el carrito del usuario. Devuelve
+// nil si no hay cupón, y el motivo si ya no se puede usar (venció, el carrito
+// quedó por debajo del mínimo, etc.) para avisarlo sin sacarlo del carrito.
+func cuponDelCarrito(ctx context.Context, queries *sqlc.Queries, idUsuario int32, promos promociones.Resultado) (*cupones.Aplicacion, string, error) {
+	cupon, err := queries.GetCarritoCupon(ctx, idUsuario)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	aplicacion, err := aplicarCupon(ctx, queries, cupon, idUsuario, monedas.Actual(ctx), lineasCupon(promos))
+	var errCupon cupones.ErrCupon
+	if errors.As(err, &errCupon) {
+		return nil, errCupon.Error(), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &aplicacion, "", nil
+}
+
+// cargarCupon valida el código contra el carrito actual y, si aplica, lo deja
+// guardado en el carrito del usuario
+func cargarCupon(ctx context.Context, queries *sqlc.Queries, idUsuario int32, codigo string) (cupones.Aplicacion, error) {
+	codigo = cupones.Normalizar(codigo)
+	if codigo == "" {
+		return cupones.Aplicacion{}, cupones.ErrCupon{Motivo: "ingresá un código de descuento"}
+	}
+	cupon, err := queries.GetCupon(ctx, codigo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cupones.Aplicacion{}, cupones.ErrCupon{Motivo: "el cupón " + codigo + " no existe"}
+	}
+	if err != nil {
+		return cupones.Aplicacion{}, err
+	}
+
+	items, err := queries.GetCartItems(ctx, idUsuario)
+	if err != nil {
+		return cupones.Aplicacion{}, err
+	}
+	promos, err := promocionesCarrito(ctx, queries, items)
+	if err != nil {
+		return cupones.Aplicacion{}, err
+	}
+	aplicacion, err := aplicarCupon(ctx, queries, cupon, idUsuario, monedas.Actual(ctx), lineasCupon(promos))
+	if err != nil {
+		return cupones.Aplicacion{}, err
+	}
+
+	err = queries.SetCarritoCupon(ctx, sqlc.SetCarritoCuponParams{IDUsuario: idUsuario, Codigo: cupon.Codigo})
+	if err != nil {
+		return cupones.Aplicacion{}, err
+	}
+	return aplicacion, nil
+}
+
+// CartCuponHandler maneja /carrito/cupon: POST aplica un código y DELETE lo quita
+func CartCuponHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			// POST /carrito/cupon
+			_, err := cargarCupon(r.Context(), queries, usuario.IDUsuario, r.FormValue("codigo"))
+			var errCupon cupones.ErrCupon
+			if errors.As(err, &errCupon) {
+				renderCarritoConAviso(w, r, queries, usuario.IDUsuario, "No se pudo aplicar: "+errCupon.Error())
+				return
+			}
+			if err != nil {
+				http.Error(w, "Error al aplicar el cupón: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		case http.MethodDelete:
+			// DELETE /carrito/cupon
+			if err := queries.DeleteCarritoCupon(r.Context(), usuario.IDUsuario); err != nil {
+				http.Error(w, "Error al quitar el cupón: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		renderCarrito(w, r, queries, usuario.IDUsuario)
+	}
+}
+
+// cuponCarritoRequest es el body de POST /api/v1/cart/cupon
+type cuponCarritoRequest struct {
+	Codigo string `json:"codigo"`
+}
+
+// resumenCupon es la respuesta de POST /api/v1/cart/cupon
+type resumenCupon struct {
+	Codigo    string       `json:"codigo"`
+	Moneda    string       `json:"moneda"`
+	Subtotal  dinero.Monto `json:"subtotal"`
+	Descuento dinero.Monto `json:"descuento"`
+	Total     dinero.Monto `json:"total"`
+}
+
+// APICartCuponHandler maneja /api/v1/cart/cupon: POST aplica un código al
+// carrito y devuelve el descuento; DELETE lo quita
+func APICartCuponHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			var req cuponCarritoRequest
+			if err := leerJSON(w, r, &req); err != nil {
+				errorLeerJSON(w, err)
+				return
+			}
+			aplicacion, err := cargarCupon(r.Context(), queries, usuario.IDUsuario, req.Codigo)
+			var errCupon cupones.ErrCupon
+			if errors.As(err, &errCupon) {
+				errorJSON(w, http.StatusBadRequest, errCupon.Error())
+				return
+			}
+			if err != nil {
+				errorDB(w, err, "cupón")
+				return
+			}
+			escribirJSON(w, http.StatusOK, resumenCupon{
+				Codigo:    aplicacion.Cupon.Codigo,
+				Moneda:    monedas.Actual(r.Context()),
+				Subtotal:  aplicacion.Subtotal,
+				Descuento: aplicacion.Descuento,
+				Total:     aplicacion.Total(),
+			})
+		case http.MethodDelete:
+			if err := queries.DeleteCarritoCupon(r.Context(), usuario.IDUsuario); err != nil {
+				errorDB(w, err, "cupón")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APICuponesHandler maneja /api/v1/cupones: GET lista y POST crea
+func APICuponesHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			lista, err := queries.ListCupones(r.Context()) // GET /api/v1/cupones
+			if err != nil {
+				errorDB(w, err, "cupón")
+				return
+			}
+			escribirJSON(w, http.StatusOK, lista)
+		case http.MethodPost:
+			apiCreateCuponHandler(queries)(w, r) // POST /api/v1/cupones
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// cuponRequest es el body de POST /cupones: la categoría va por
+// id_categoria o por su slug o nombre en categoria
+type cuponRequest struct {
+	sqlc.CreateCuponParams
+	Categoria string `json:"categoria"`
+}
+
+func apiCreateCuponHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req cuponRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		var err error
+		if req.IDCategoria, err = categoriaRegla(r.Context(), req.IDCategoria, req.Categoria); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := cupones.Validar(monedas.De(r.Context()), &req.CreateCuponParams); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		cupon, err := queries.CreateCupon(r.Context(), req.CreateCuponParams)
+		if err != nil {
+			errorDB(w, err, "cupón")
+			return
+		}
+		escribirJSON(w, http.StatusCreated, cupon)
+	}
+}
+
+// APICuponHandler maneja /api/v1/cupon/{codigo}: GET lo devuelve y DELETE lo
+// desactiva. No se borra porque los pedidos que lo usaron lo referencian.
+func APICuponHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		codigo := cupones.Normalizar(strings.TrimPrefix(r.URL.Path, "/api/v1/cupon/"))
+
+		switch r.Method {
+		case http.MethodGet:
+			cupon, err := queries.GetCupon(r.Context(), codigo) // GET /api/v1/cupon/{codigo}
+			if err != nil {
+				errorDB(w, err, "cupón")
+				return
+			}
+			escribirJSON(w, http.StatusOK, cupon)
+		case http.MethodDelete:
+			filas, err := queries.DesactivarCupon(r.Context(), codigo) // DELETE /api/v1/cupon/{codigo}
+			if err != nil {
+				errorDB(w, err, "cupón")
+				return
+			}
+			if filas == 0 {
+				errorJSON(w, http.StatusNotFound, "cupón no encontrado")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package handle
+
+import (
+	"net/http"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/envios"
+	"carrito.com/views"
+)
+
+// DireccionesHandler maneja /direcciones: la libreta de direcciones del usuario
+func DireccionesHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			// GET /direcciones
+			lista, err := queries.ListDirecciones(r.Context(), usuario.IDUsuario)
+			if err != nil {
+				http.Error(w, "Error al listar las direcciones: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			views.Direcciones(lista).Render(r.Context(), w)
+		case http.MethodPost:
+			// POST /direcciones
+			req := sqlc.CreateDireccionParams{
+				IDUsuario:    usuario.IDUsuario,
+				Alias:        r.FormValue("alias"),
+				Destinatario: r.FormValue("destinatario"),
+				Calle:        r.FormValue("calle"),
+				Ciudad:       r.FormValue("ciudad"),
+				Provincia:    r.FormValue("provincia"),
+				CodigoPostal: r.FormValue("codigo_postal"),
+				Telefono:     r.FormValue("telefono"),
+			}
+			if err := envios.ValidarDireccion(&req); err != nil {
+				renderDirecciones(w, r, queries, usuario.IDUsuario, err.Error())
+				return
+			}
+			if _, err := queries.CreateDireccion(r.Context(), req); err != nil {
+				renderDirecciones(w, r, queries, usuario.IDUsuario, "No se pudo guardar la dirección: "+err.Error())
+				return
+			}
+			renderDirecciones(w, r, queries, usuario.IDUsuario, "")
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// DireccionHandler maneja /direcciones/{id}: DELETE borra una dirección
+// propia. Los pedidos que ya la usaron guardan su copia.
+func DireccionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+		id, err := idDeRuta(r, "/direcciones/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		_, err = queries.DeleteDireccion(r.Context(), sqlc.DeleteDireccionParams{
+			IDDireccion: id,
+			IDUsuario:   usuario.IDUsuario,
+		})
+		if err != nil {
+			http.Error(w, "Error al borrar la dirección: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderDirecciones(w, r, queries, usuario.IDUsuario, "")
+	}
+}
+
+// renderDirecciones vuelve a dibujar la lista después de un cambio
+func renderDirecciones(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, idUsuario int32, mensaje string) {
+	lista, err := queries.ListDirecciones(r.Context(), idUsuario)
+	if err != nil {
+		http.Error(w, "Error al listar las direcciones: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	views.DireccionesLista(lista, mensaje).Render(r.Context(), w)
+}
+
+// APIDireccionesHandler maneja /api/v1/direcciones: GET lista y POST crea
+// direcciones del usuario autenticado
+func APIDireccionesHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			lista, err := queries.ListDirecciones(r.Context(), usuario.IDUsuario) // GET /api/v1/direcciones
+			if err != nil {
+				errorDB(w, err, "dirección")
+				return
+			}
+			escribirJSON(w, http.StatusOK, lista)
+		case http.MethodPost:
+			// POST /api/v1/direcciones
+			var req sqlc.CreateDireccionParams
+			if err := leerJSON(w, r, &req); err != nil {
+				errorLeerJSON(w, err)
+				return
+			}
+			req.IDUsuario = usuario.IDUsuario
+			if err := envios.ValidarDireccion(&req); err != nil {
+				errorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			direccion, err := queries.CreateDireccion(r.Context(), req)
+			if err != nil {
+				errorDB(w, err, "dirección")
+				return
+			}
+			escribirJSON(w, http.StatusCreated, direccion)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APIDireccionHandler maneja /api/v1/direccion/{id}. Una dirección ajena
+// responde igual que una inexistente.
+func APIDireccionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+		id, err := idDeRuta(r, "/api/v1/direccion/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			// GET /api/v1/direccion/{id}
+			direccion, err := queries.GetDireccion(r.Context(), sqlc.GetDireccionParams{IDDireccion: id, IDUsuario: usuario.IDUsuario})
+			if err != nil {
+				errorDB(w, err, "dirección")
+				return
+			}
+			escribirJSON(w, http.StatusOK, direccion)
+		case http.MethodPut:
+			// PUT /api/v1/direccion/{id}
+			var req sqlc.CreateDireccionParams
+			if err := leerJSON(w, r, &req); err != nil {
+				errorLeerJSON(w, err)
+				return
+			}
+			if err := envios.ValidarDireccion(&req); err != nil {
+				errorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			direccion, err := queries.UpdateDireccion(r.Context(), sqlc.UpdateDireccionParams{
+				IDDireccion:  id,
+				IDUsuario:    usuario.IDUsuario,
+				Alias:        req.Alias,
+				Destinatario: req.Destinatario,
+				Calle:        req.Calle,
+				Ciudad:       req.Ciudad,
+				Provincia:    req.Provincia,
+				CodigoPostal: req.CodigoPostal,
+				Telefono:     req.Telefono,
+			})
+			if err != nil {
+				errorDB(w, err, "dirección")
+				return
+			}
+			escribirJSON(w, http.StatusOK, direccion)
+		case http.MethodDelete:
+			// DELETE /api/v1/direccion/{id}
+			filas, err := queries.DeleteDireccion(r.Context(), sqlc.DeleteDireccionParams{IDDireccion: id, IDUsuario: usuario.IDUsuario})
+			if err != nil {
+				errorDB(w, err, "dirección")
+				return
+			}
+			if filas == 0 {
+				errorJSON(w, http.StatusNotFound, "dirección no encontrada")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,555 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"carrito.com/auth"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/envios"
+	"carrito.com/monedas"
+	"carrito.com/views"
+)
+
+// seleccionEnvio es el método y la dirección que eligió el comprador. En cero
+// se usa el primer método activo y, si lleva dirección, la primera del usuario.
+type seleccionEnvio struct {
+	IDMetodo    int32 `json:"id_metodo"`
+	IDDireccion int32 `json:"id_direccion"`
+}
+
+// cotizarEnvio resuelve la selección contra los métodos activos y las
+// direcciones del usuario y calcula el costo en moneda. Devuelve nil si no
+// hay ningún método activo: el pedido sale sin envío.
+func cotizarEnvio(ctx context.Context, queries *sqlc.Queries, idUsuario int32, sel seleccionEnvio, peso int64, moneda string) (*envios.Cotizacion, error) {
+	metodos, err := queries.ListMetodosEnvioActivos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(metodos) == 0 {
+		return nil, nil
+	}
+
+	metodo := metodos[0]
+	if sel.IDMetodo != 0 {
+		encontrado := false
+		for _, m := range metodos {
+			if m.IDMetodo == sel.IDMetodo {
+				metodo, encontrado = m, true
+			}
+		}
+		if !encontrado {
+			return nil, envios.ErrEnvio{Motivo: "el método de envío elegido ya no está disponible"}
+		}
+	}
+
+	var direccion *sqlc.Direccion
+	if sel.IDDireccion != 0 {
+		d, err := queries.GetDireccion(ctx, sqlc.GetDireccionParams{IDDireccion: sel.IDDireccion, IDUsuario: idUsuario})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, envios.ErrEnvio{Motivo: "la dirección elegida no existe"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		direccion = &d
+	} else {
+		lista, err := queries.ListDirecciones(ctx, idUsuario)
+		if err != nil {
+			return nil, err
+		}
+		if len(lista) > 0 {
+			direccion = &lista[0]
+		}
+	}
+
+	tarifas, err := queries.ListTarifasMetodo(ctx, metodo.IDMetodo)
+	if err != nil {
+		return nil, err
+	}
+	cotizacion, err := envios.Cotizar(monedas.De(ctx), moneda, metodo, tarifas, envios.Envio{Peso: peso, Direccion: direccion})
+	if err != nil {
+		return nil, err
+	}
+	return &cotizacion, nil
+}
+
+// seleccionDelCarrito devuelve el envío que el usuario eligió en el carrito,
+// en cero si todavía no eligió
+func seleccionDelCarrito(ctx context.Context, queries *sqlc.Queries, idUsuario int32) (seleccionEnvio, error) {
+	elegido, err := queries.GetCarritoEnvio(ctx, idUsuario)
+	if errors.Is(err, sql.ErrNoRows) {
+		return seleccionEnvio{}, nil
+	}
+	if err != nil {
+		return seleccionEnvio{}, err
+	}
+	sel := seleccionEnvio{IDMetodo: elegido.IDMetodo}
+	if elegido.IDDireccion != nil {
+		sel.IDDireccion = *elegido.IDDireccion
+	}
+	return sel, nil
+}
+
+// envioDelCarrito cotiza el envío elegido para los items del carrito. Si no
+// se puede (falta la dirección, no hay tarifa) devuelve el motivo para
+// mostrarlo, igual que con el cupón.
+func envioDelCarrito(ctx context.Context, queries *sqlc.Queries, idUsuario int32, items []sqlc.GetCartItemsRow) (*envios.Cotizacion, string, error) {
+	sel, err := seleccionDelCarrito(ctx, queries, idUsuario)
+	if err != nil {
+		return nil, "", err
+	}
+	var peso int64
+	for _, item := range items {
+		peso += int64(item.Peso) * int64(item.Cantidad)
+	}
+
+	cotizacion, err := cotizarEnvio(ctx, queries, idUsuario, sel, peso, monedas.Actual(ctx))
+	var errEnvio envios.ErrEnvio
+	if errors.As(err, &errEnvio) {
+		return nil, errEnvio.Error(), nil
+	}
+	return cotizacion, "", err
+}
+
+// guardarEnvio valida que el método y la dirección existan y los deja como
+// elegidos en el carrito. La cotización se hace al mostrar el carrito.
+func guardarEnvio(ctx context.Context, queries *sqlc.Queries, idUsuario int32, sel seleccionEnvio) error {
+	metodo, err := queries.GetMetodoEnvio(ctx, sel.IDMetodo)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !metodo.Activo) {
+		return envios.ErrEnvio{Motivo: "el método de envío elegido no está disponible"}
+	}
+	if err != nil {
+		return err
+	}
+
+	params := sqlc.SetCarritoEnvioParams{IDUsuario: idUsuario, IDMetodo: metodo.IDMetodo}
+	if sel.IDDireccion != 0 {
+		_, err := queries.GetDireccion(ctx, sqlc.GetDireccionParams{IDDireccion: sel.IDDireccion, IDUsuario: idUsuario})
+		if errors.Is(err, sql.ErrNoRows) {
+			return envios.ErrEnvio{Motivo: "la dirección elegida no existe"}
+		}
+		if err != nil {
+			return err
+		}
+		params.IDDireccion = &sel.IDDireccion
+	}
+	return queries.SetCarritoEnvio(ctx, params)
+}
+
+// CartEnvioHandler: POST /carrito/envio guarda el método y la dirección
+// elegidos en el carrito y lo redibuja con el costo
+func CartEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		idMetodo, _ := strconv.Atoi(r.FormValue("id_metodo"))
+		idDireccion, _ := strconv.Atoi(r.FormValue("id_direccion"))
+		err := guardarEnvio(r.Context(), queries, usuario.IDUsuario, seleccionEnvio{IDMetodo: int32(idMetodo), IDDireccion: int32(idDireccion)})
+		var errEnvio envios.ErrEnvio
+		if errors.As(err, &errEnvio) {
+			renderCarritoConAviso(w, r, queries, usuario.IDUsuario, errEnvio.Error())
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error al guardar el envío: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderCarrito(w, r, queries, usuario.IDUsuario)
+	}
+}
+
+// APICartEnvioHandler maneja /api/v1/cart/envio: GET cotiza el envío elegido
+// y PUT {"id_metodo", "id_direccion"} lo cambia
+func APICartEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req seleccionEnvio
+			if err := leerJSON(w, r, &req); err != nil {
+				errorLeerJSON(w, err)
+				return
+			}
+			err := guardarEnvio(r.Context(), queries, usuario.IDUsuario, req)
+			var errEnvio envios.ErrEnvio
+			if errors.As(err, &errEnvio) {
+				errorJSON(w, http.StatusBadRequest, errEnvio.Error())
+				return
+			}
+			if err != nil {
+				errorDB(w, err, "envío")
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		items, err := queries.GetCartItems(r.Context(), usuario.IDUsuario)
+		if err != nil {
+			errorDB(w, err, "carrito")
+			return
+		}
+		cotizacion, motivo, err := envioDelCarrito(r.Context(), queries, usuario.IDUsuario, items)
+		if err != nil {
+			errorDB(w, err, "envío")
+			return
+		}
+		if motivo != "" {
+			errorJSON(w, http.StatusConflict, motivo)
+			return
+		}
+		escribirJSON(w, http.StatusOK, cotizacion)
+	}
+}
+
+// AdminEnviosHandler maneja /admin/envios: listado y alta de métodos
+func AdminEnviosHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// GET /admin/envios
+			metodos, tarifas, err := listarEnvios(r.Context(), queries)
+			if err != nil {
+				http.Error(w, "Error al listar los envíos: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			views.EnviosAdmin(metodos, tarifas).Render(r.Context(), w)
+		case http.MethodPost:
+			createMetodoEnvioHandler(queries)(w, r) // POST /admin/envios
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminEnvioHandler maneja /admin/envios/{id}: POST .../activo lo prende o
+// apaga, POST .../tarifas agrega una tarifa y DELETE lo borra. Las tarifas se
+// borran con DELETE /admin/envios/tarifas/{id}.
+func AdminEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/admin/envios/tarifas/"):
+			deleteTarifaEnvioHandler(queries)(w, r) // DELETE /admin/envios/tarifas/{id}
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/activo"):
+			r.URL.Path = strings.TrimSuffix(r.URL.Path, "/activo")
+			activarMetodoEnvioHandler(queries)(w, r) // POST /admin/envios/{id}/activo
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/tarifas"):
+			r.URL.Path = strings.TrimSuffix(r.URL.Path, "/tarifas")
+			createTarifaEnvioHandler(queries)(w, r) // POST /admin/envios/{id}/tarifas
+		case r.Method == http.MethodDelete:
+			deleteMetodoEnvioHandler(queries)(w, r) // DELETE /admin/envios/{id}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func createMetodoEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := sqlc.CreateMetodoEnvioParams{
+			Nombre: r.FormValue("nombre"),
+			Tipo:   r.FormValue("tipo"),
+			Moneda: r.FormValue("moneda"),
+			Activo: true,
+		}
+		var err error
+		if costo := r.FormValue("costo"); costo != "" {
+			req.Costo, err = dinero.Parse(costo)
+		}
+		if err == nil {
+			err = envios.Validar(monedas.De(r.Context()), &req)
+		}
+		if err != nil {
+			renderEnviosAdmin(w, r, queries, err.Error())
+			return
+		}
+
+		if _, err := queries.CreateMetodoEnvio(r.Context(), req); err != nil {
+			renderEnviosAdmin(w, r, queries, "No se pudo crear el método: "+err.Error())
+			return
+		}
+		renderEnviosAdmin(w, r, queries, "")
+	}
+}
+
+func createTarifaEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/admin/envios/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req := sqlc.CreateTarifaEnvioParams{IDMetodo: id}
+		if provincia := r.FormValue("provincia"); provincia != "" {
+			req.Provincia = &provincia
+		}
+		peso, _ := strconv.Atoi(r.FormValue("peso_hasta"))
+		req.PesoHasta = int32(peso)
+		req.Costo, err = dinero.Parse(r.FormValue("costo"))
+		if err == nil {
+			err = envios.ValidarTarifa(&req)
+		}
+		if err != nil {
+			renderEnviosAdmin(w, r, queries, err.Error())
+			return
+		}
+
+		if _, err := queries.CreateTarifaEnvio(r.Context(), req); err != nil {
+			renderEnviosAdmin(w, r, queries, "No se pudo agregar la tarifa: "+err.Error())
+			return
+		}
+		renderEnviosAdmin(w, r, queries, "")
+	}
+}
+
+func activarMetodoEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/admin/envios/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		metodo, err := queries.GetMetodoEnvio(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Método de envío no encontrado", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error al leer el método de envío: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, err = queries.UpdateMetodoEnvioActivo(r.Context(), sqlc.UpdateMetodoEnvioActivoParams{
+			IDMetodo: id,
+			Activo:   !metodo.Activo,
+		})
+		if err != nil {
+			http.Error(w, "Error al actualizar el método de envío: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderEnviosAdmin(w, r, queries, "")
+	}
+}
+
+func deleteMetodoEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/admin/envios/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := queries.DeleteMetodoEnvio(r.Context(), id); err != nil {
+			http.Error(w, "Error al borrar el método de envío: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderEnviosAdmin(w, r, queries, "")
+	}
+}
+
+func deleteTarifaEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/admin/envios/tarifas/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := queries.DeleteTarifaEnvio(r.Context(), id); err != nil {
+			http.Error(w, "Error al borrar la tarifa: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderEnviosAdmin(w, r, queries, "")
+	}
+}
+
+func listarEnvios(ctx context.Context, queries *sqlc.Queries) ([]sqlc.MetodoEnvio, []sqlc.TarifaEnvio, error) {
+	metodos, err := queries.ListMetodosEnvio(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tarifas, err := queries.ListTarifasEnvio(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return metodos, tarifas, nil
+}
+
+// renderEnviosAdmin vuelve a dibujar la tabla después de un cambio
+func renderEnviosAdmin(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, mensaje string) {
+	metodos, tarifas, err := listarEnvios(r.Context(), queries)
+	if err != nil {
+		http.Error(w, "Error al listar los envíos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	views.EnviosAdminTabla(metodos, tarifas, mensaje).Render(r.Context(), w)
+}
+
+// metodoEnvioRequest es el body de POST /api/v1/envios: el método con sus
+// tarifas, que solo usan los de tipo tabla
+type metodoEnvioRequest struct {
+	sqlc.CreateMetodoEnvioParams
+	Tarifas []sqlc.CreateTarifaEnvioParams `json:"tarifas"`
+}
+
+// metodoEnvioResponse es un método con sus tarifas
+type metodoEnvioResponse struct {
+	sqlc.MetodoEnvio
+	Tarifas []sqlc.TarifaEnvio `json:"tarifas"`
+}
+
+// APIEnviosHandler maneja /api/v1/envios: GET lista los métodos activos con
+// sus tarifas y POST crea uno (staff/admin)
+func APIEnviosHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			// GET /api/v1/envios
+			metodos, err := queries.ListMetodosEnvioActivos(r.Context())
+			if err != nil {
+				errorDB(w, err, "envío")
+				return
+			}
+			tarifas, err := queries.ListTarifasEnvio(r.Context())
+			if err != nil {
+				errorDB(w, err, "envío")
+				return
+			}
+			lista := make([]metodoEnvioResponse, len(metodos))
+			for i, m := range metodos {
+				lista[i] = conTarifas(m, tarifas)
+			}
+			escribirJSON(w, http.StatusOK, lista)
+		case http.MethodPost:
+			RequirePermiso(auth.PermisoVentas, apiCreateMetodoEnvioHandler(db, queries)).ServeHTTP(w, r) // POST /api/v1/envios
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APIEnvioHandler maneja /api/v1/envio/{id}: GET y DELETE (staff/admin)
+func APIEnvioHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/envio/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			// GET /api/v1/envio/{id}
+			metodo, err := queries.GetMetodoEnvio(r.Context(), id)
+			if err != nil {
+				errorDB(w, err, "envío")
+				return
+			}
+			tarifas, err := queries.ListTarifasMetodo(r.Context(), id)
+			if err != nil {
+				errorDB(w, err, "envío")
+				return
+			}
+			escribirJSON(w, http.StatusOK, conTarifas(metodo, tarifas))
+		case http.MethodDelete:
+			RequirePermiso(auth.PermisoVentas, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				filas, err := queries.DeleteMetodoEnvio(r.Context(), id) // DELETE /api/v1/envio/{id}
+				if err != nil {
+					errorDB(w, err, "envío")
+					return
+				}
+				if filas == 0 {
+					errorJSON(w, http.StatusNotFound, "método de envío no encontrado")
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})).ServeHTTP(w, r)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func apiCreateMetodoEnvioHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Si el body no dice "activo", el método se crea activo
+		req := metodoEnvioRequest{CreateMetodoEnvioParams: sqlc.CreateMetodoEnvioParams{Activo: true}}
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+		if err := envios.Validar(monedas.De(r.Context()), &req.CreateMetodoEnvioParams); err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.Tipo == envios.TipoTabla && len(req.Tarifas) == 0 {
+			errorJSON(w, http.StatusBadRequest, "un envío por tabla lleva al menos una tarifa")
+			return
+		}
+		if req.Tipo != envios.TipoTabla {
+			req.Tarifas = nil
+		}
+		for i := range req.Tarifas {
+			if err := envios.ValidarTarifa(&req.Tarifas[i]); err != nil {
+				errorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			errorDB(w, err, "envío")
+			return
+		}
+		defer tx.Rollback()
+		qtx := queries.WithTx(tx)
+
+		metodo, err := qtx.CreateMetodoEnvio(r.Context(), req.CreateMetodoEnvioParams)
+		if err != nil {
+			errorDB(w, err, "envío")
+			return
+		}
+		res := metodoEnvioResponse{MetodoEnvio: metodo, Tarifas: []sqlc.TarifaEnvio{}}
+		for _, t := range req.Tarifas {
+			t.IDMetodo = metodo.IDMetodo
+			tarifa, err := qtx.CreateTarifaEnvio(r.Context(), t)
+			if err != nil {
+				errorDB(w, err, "envío")
+				return
+			}
+			res.Tarifas = append(res.Tarifas, tarifa)
+		}
+		if err := tx.Commit(); err != nil {
+			errorDB(w, err, "envío")
+			return
+		}
+		escribirJSON(w, http.StatusCreated, res)
+	}
+}
+
+// conTarifas junta un método con sus tarifas
+func conTarifas(m sqlc.MetodoEnvio, tarifas []sqlc.TarifaEnvio) metodoEnvioResponse {
+	res := metodoEnvioResponse{MetodoEnvio: m, Tarifas: []sqlc.TarifaEnvio{}}
+	for _, t := range tarifas {
+		if t.IDMetodo == m.IDMetodo {
+			res.Tarifas = append(res.Tarifas, t)
+		}
+	}
+	return res
+}
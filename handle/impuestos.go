@@ -0,0 +1,350 @@
+package handle
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"carrito.com/auth"
+	"carrito.com/cupones"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/envios"
+	"carrito.com/impuestos"
+	"carrito.com/promociones"
+	"carrito.com/views"
+)
+
+// impuestosCarrito calcula los impuestos de cada línea sobre lo que queda
+// después de las promociones y del cupón (aplicado puede ser nil)
+func impuestosCarrito(ctx context.Context, queries *sqlc.Queries, region string, promos promociones.Resultado, aplicado *cupones.Aplicacion) (impuestos.Resultado, error) {
+	reglas, err := queries.ListImpuestos(ctx)
+	if err != nil {
+		return impuestos.Resultado{}, err
+	}
+	lineas := make([]impuestos.Linea, len(promos.Lineas))
+	for i, l := range promos.Lineas {
+		lineas[i] = impuestos.Linea{Categorias: l.Categorias, Total: l.Total()}
+		if aplicado != nil && i < len(aplicado.PorLinea) {
+			lineas[i].Total -= aplicado.PorLinea[i]
+		}
+	}
+	return impuestos.Calcular(reglas, region, lineas), nil
+}
+
+// regionFiscal es la región con la que se buscan las alícuotas: la provincia
+// de la dirección de entrega si el envío lleva una, y si no la que el usuario
+// eligió en su perfil. Así no se puede elegir una región con menos impuestos
+// y recibir el pedido en otra.
+func regionFiscal(cotizacion *envios.Cotizacion, perfil string) string {
+	if cotizacion != nil && cotizacion.Direccion != nil {
+		return strings.TrimSpace(cotizacion.Direccion.Provincia)
+	}
+	return perfil
+}
+
+// CartRegionHandler: POST /carrito/region guarda la región fiscal del usuario,
+// que decide qué impuestos se le cobran cuando retira el pedido, y redibuja el
+// carrito
+func CartRegionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		regiones, err := queries.ListRegionesImpuesto(r.Context())
+		if err != nil {
+			http.Error(w, "Error al leer las regiones: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		region, ok := buscarRegion(regiones, r.FormValue("region"))
+		if !ok {
+			http.Error(w, "Región desconocida", http.StatusBadRequest)
+			return
+		}
+
+		err = queries.UpdateUserRegion(r.Context(), sqlc.UpdateUserRegionParams{
+			IDUsuario: usuario.IDUsuario,
+			Region:    region,
+		})
+		if err != nil {
+			http.Error(w, "Error al guardar la región: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// El usuario del contexto se cargó antes del cambio
+		usuario.Region = region
+		r = r.WithContext(auth.ConUsuario(r.Context(), usuario))
+		renderCarrito(w, r, queries, usuario.IDUsuario)
+	}
+}
+
+// buscarRegion devuelve la región tal como está escrita en las alícuotas. La
+// región vacía siempre es válida: solo aplican las reglas generales.
+func buscarRegion(regiones []string, region string) (string, bool) {
+	region = strings.TrimSpace(region)
+	if region == "" {
+		return "", true
+	}
+	for _, r := range regiones {
+		if strings.EqualFold(r, region) {
+			return r, true
+		}
+	}
+	return "", false
+}
+
+// AdminImpuestosHandler maneja /admin/impuestos: listado y alta de alícuotas
+func AdminImpuestosHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			lista, err := queries.ListImpuestos(r.Context()) // GET /admin/impuestos
+			if err != nil {
+				http.Error(w, "Error al listar impuestos: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			views.ImpuestosAdmin(lista).Render(r.Context(), w)
+		case http.MethodPost:
+			createImpuestoHandler(queries)(w, r) // POST /admin/impuestos
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminImpuestoHandler maneja /admin/impuestos/{id}: DELETE borra la alícuota.
+// Los pedidos ya hechos no cambian porque cada línea guarda su impuesto.
+func AdminImpuestoHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := idDeRuta(r, "/admin/impuestos/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := queries.DeleteImpuesto(r.Context(), id); err != nil {
+			http.Error(w, "Error al borrar el impuesto: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderImpuestosAdmin(w, r, queries, "")
+	}
+}
+
+func createImpuestoHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := sqlc.CreateImpuestoParams{
+			Nombre:   r.FormValue("nombre"),
+			Incluido: r.FormValue("incluido") != "",
+		}
+		if region := r.FormValue("region"); region != "" {
+			req.Region = &region
+		}
+		idCategoria, err := categoriaRegla(r.Context(), idCategoriaDelForm(r), "")
+		if err == nil {
+			req.IDCategoria = idCategoria
+			req.Alicuota, err = dinero.ParsePorcentaje(r.FormValue("alicuota"))
+		}
+		if err == nil {
+			err = impuestos.Validar(&req)
+		}
+		if err != nil {
+			renderImpuestosAdmin(w, r, queries, err.Error())
+			return
+		}
+
+		if _, err := queries.CreateImpuesto(r.Context(), req); err != nil {
+			renderImpuestosAdmin(w, r, queries, "No se pudo crear el impuesto (¿ya hay uno para esa categoría y región?): "+err.Error())
+			return
+		}
+		renderImpuestosAdmin(w, r, queries, "")
+	}
+}
+
+// renderImpuestosAdmin vuelve a dibujar la tabla después de un cambio
+func renderImpuestosAdmin(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, mensaje string) {
+	lista, err := queries.ListImpuestos(r.Context())
+	if err != nil {
+		http.Error(w, "Error al listar impuestos: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	views.ImpuestosAdminTabla(lista, mensaje).Render(r.Context(), w)
+}
+
+// ReporteImpuestosHandler maneja GET /admin/impuestos/reporte y
+// /api/v1/impuestos/reporte: impuestos cobrados entre ?desde= y ?hasta=
+// (AAAA-MM-DD, por defecto el mes en curso). Con ?formato=csv devuelve la
+// planilla para el contador; por la API, JSON.
+func ReporteImpuestosHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var (
+			reporte impuestos.Reporte
+			err     error
+		)
+		reporte.Desde, reporte.Hasta, err = impuestos.Periodo(r.URL.Query().Get("desde"), r.URL.Query().Get("hasta"), time.Now())
+		if err != nil {
+			if esPeticionAPI(r) {
+				errorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		items, err := queries.ListItemsImpuestos(r.Context(), reporte.Params())
+		if err != nil {
+			if esPeticionAPI(r) {
+				errorDB(w, err, "impuesto")
+				return
+			}
+			http.Error(w, "Error al armar el reporte: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		reporte.Armar(items)
+
+		switch {
+		case r.URL.Query().Get("formato") == "csv":
+			nombre := "impuestos_" + reporte.Desde.Format(impuestos.FormatoFecha) + "_" + reporte.Hasta.Format(impuestos.FormatoFecha) + ".csv"
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+nombre+`"`)
+			impuestos.EscribirCSV(w, reporte)
+		case esPeticionAPI(r):
+			escribirJSON(w, http.StatusOK, reporte)
+		default:
+			views.ReporteImpuestos(reporte).Render(r.Context(), w)
+		}
+	}
+}
+
+// APIImpuestosHandler maneja /api/v1/impuestos: GET lista y POST crea
+func APIImpuestosHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			lista, err := queries.ListImpuestos(r.Context()) // GET /api/v1/impuestos
+			if err != nil {
+				errorDB(w, err, "impuesto")
+				return
+			}
+			escribirJSON(w, http.StatusOK, lista)
+		case http.MethodPost:
+			apiCreateImpuestoHandler(queries)(w, r) // POST /api/v1/impuestos
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APIImpuestoHandler maneja /api/v1/impuesto/{id}
+func APIImpuestoHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/impuesto/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			impuesto, err := queries.GetImpuesto(r.Context(), id) // GET /api/v1/impuesto/{id}
+			if err != nil {
+				errorDB(w, err, "impuesto")
+				return
+			}
+			escribirJSON(w, http.StatusOK, impuesto)
+		case http.MethodPut:
+			apiUpdateImpuestoHandler(queries, id)(w, r) // PUT /api/v1/impuesto/{id}
+		case http.MethodDelete:
+			filas, err := queries.DeleteImpuesto(r.Context(), id) // DELETE /api/v1/impuesto/{id}
+			if err != nil {
+				errorDB(w, err, "impuesto")
+				return
+			}
+			if filas == 0 {
+				errorJSON(w, http.StatusNotFound, "impuesto no encontrado")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// impuestoRequest es el body de POST /impuestos y PUT /impuesto/{id}: la
+// categoría va por id_categoria o por su slug o nombre en categoria
+type impuestoRequest struct {
+	sqlc.CreateImpuestoParams
+	Categoria string `json:"categoria"`
+}
+
+// leerImpuesto lee y valida el body de un alta o modificación. Si el body no
+// dice "incluido", el precio se toma con el impuesto adentro.
+func leerImpuesto(w http.ResponseWriter, r *http.Request) (sqlc.CreateImpuestoParams, bool) {
+	req := impuestoRequest{CreateImpuestoParams: sqlc.CreateImpuestoParams{Incluido: true}}
+	if err := leerJSON(w, r, &req); err != nil {
+		errorLeerJSON(w, err)
+		return req.CreateImpuestoParams, false
+	}
+	var err error
+	if req.IDCategoria, err = categoriaRegla(r.Context(), req.IDCategoria, req.Categoria); err == nil {
+		err = impuestos.Validar(&req.CreateImpuestoParams)
+	}
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return req.CreateImpuestoParams, false
+	}
+	return req.CreateImpuestoParams, true
+}
+
+func apiCreateImpuestoHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := leerImpuesto(w, r)
+		if !ok {
+			return
+		}
+
+		impuesto, err := queries.CreateImpuesto(r.Context(), req)
+		if err != nil {
+			errorDB(w, err, "impuesto")
+			return
+		}
+		escribirJSON(w, http.StatusCreated, impuesto)
+	}
+}
+
+func apiUpdateImpuestoHandler(queries *sqlc.Queries, id int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := leerImpuesto(w, r)
+		if !ok {
+			return
+		}
+
+		impuesto, err := queries.UpdateImpuesto(r.Context(), sqlc.UpdateImpuestoParams{
+			IDImpuesto:  id,
+			Nombre:      req.Nombre,
+			Alicuota:    req.Alicuota,
+			IDCategoria: req.IDCategoria,
+			Region:      req.Region,
+			Incluido:    req.Incluido,
+		})
+		if err != nil {
+			errorDB(w, err, "impuesto")
+			return
+		}
+		escribirJSON(w, http.StatusOK, impuesto)
+	}
+}
@@ -0,0 +1,88 @@
+package handle
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"carrito.com/dinero"
+	"github.com/lib/pq"
+)
+
+// Tamaño máximo aceptado para un body JSON
+const maxBodyJSON = 1 << 20
+
+// escribirJSON serializa v con el status indicado
+func escribirJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error escribiendo JSON: %v", err)
+	}
+}
+
+// errorJSON responde {"error": mensaje}
+func errorJSON(w http.ResponseWriter, status int, mensaje string) {
+	escribirJSON(w, status, map[string]string{"error": mensaje})
+}
+
+// leerJSON decodifica el body de la request en dst
+func leerJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyJSON)
+	return json.NewDecoder(r.Body).Decode(dst)
+}
+
+// errorLeerJSON responde 400 por un body que leerJSON no pudo decodificar.
+// Los importes mal escritos se informan como tales en lugar de "JSON inválido".
+func errorLeerJSON(w http.ResponseWriter, err error) {
+	mensaje := "JSON inválido"
+	if errors.Is(err, dinero.ErrFormato) {
+		mensaje = err.Error()
+	}
+	errorJSON(w, http.StatusBadRequest, mensaje)
+}
+
+// idDeRuta extrae el ID numérico que sigue al prefijo, p. ej. /api/v1/product/{id}
+func idDeRuta(r *http.Request, prefijo string) (int32, error) {
+	idStr := strings.TrimPrefix(r.URL.Path, prefijo)
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, errors.New("ID inválido")
+	}
+	return int32(id), nil
+}
+
+// errorDB traduce errores de la base al status HTTP adecuado:
+// 404 si no hay filas, 409 para claves duplicadas o referencias, 500 para el resto.
+func errorDB(w http.ResponseWriter, err error, recurso string) {
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		errorJSON(w, http.StatusNotFound, "no se encontró el recurso: "+recurso)
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		errorJSON(w, http.StatusConflict, "ya existe un registro igual: "+recurso)
+	case errors.As(err, &pqErr) && pqErr.Code == "23503":
+		errorJSON(w, http.StatusConflict, "el recurso está referenciado por otros registros: "+recurso)
+	default:
+		log.Printf("Error de base de datos (%s): %v", recurso, err)
+		errorJSON(w, http.StatusInternalServerError, "error interno")
+	}
+}
+
+// quiereJSON indica si el cliente pidió JSON con el header Accept.
+// Las requests de HTMX siempre reciben HTML.
+func quiereJSON(r *http.Request) bool {
+	if r.Header.Get("HX-Request") == "true" {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// esJSON indica si el body de la request viene en JSON
+func esJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
@@ -0,0 +1,156 @@
+package handle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"carrito.com/auth"
+	"carrito.com/categorias"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/monedas"
+	"carrito.com/views"
+)
+
+// RequireAuth resuelve la sesión en un sqlc.Usuario y lo guarda en el contexto.
+// Si no hay sesión válida corta la cadena con noAutorizado.
+func RequireAuth(queries *sqlc.Queries, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sesion, err := sesionActual(r, queries)
+		if err != nil {
+			noAutorizado(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(conSesion(r.Context(), sesion)))
+	})
+}
+
+// SesionOpcional carga la sesión como RequireAuth si la hay, pero deja pasar
+// igual a los visitantes anónimos. Sirve para páginas públicas que muestran
+// precios en la moneda elegida por el usuario.
+func SesionOpcional(queries *sqlc.Queries, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sesion, err := sesionActual(r, queries); err == nil {
+			r = r.WithContext(conSesion(r.Context(), sesion))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func conSesion(ctx context.Context, sesion sqlc.GetUsuarioSesionRow) context.Context {
+	ctx = auth.ConUsuario(ctx, sesion.Usuario)
+	ctx = auth.ConCSRF(ctx, sesion.CsrfToken)
+	return monedas.ConMoneda(ctx, sesion.Moneda)
+}
+
+// ConCotizaciones deja las monedas y sus tasas en el contexto de todas las requests
+func ConCotizaciones(cotizaciones *monedas.Cotizaciones, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(monedas.ConCotizaciones(r.Context(), cotizaciones)))
+	})
+}
+
+// ConCategorias deja el árbol de categorías en el contexto de todas las requests
+func ConCategorias(arbol *categorias.Arbol, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(categorias.ConArbol(r.Context(), arbol)))
+	})
+}
+
+// RequireCSRF valida el token CSRF de la sesión en los métodos que modifican estado.
+// El token llega en el header X-CSRF-Token (hx-headers del layout) o en el campo
+// csrf_token de un formulario. Debe ir dentro de RequireAuth.
+func RequireCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Con Authorization: Bearer la sesión no viaja sola en el navegador
+		// (no es una credencial ambiente), así que no hay CSRF posible.
+		if tokenBearer(r) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		enviado := r.Header.Get(auth.CSRFHeader)
+		if enviado == "" {
+			enviado = r.PostFormValue(auth.CSRFCampo)
+		}
+
+		if !auth.CSRFValido(r.Context(), enviado) {
+			prohibido(w, r, "El token de seguridad falta o expiró. Recarga la página e intenta de nuevo.")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// noAutorizado responde según quién hizo la request:
+// JSON 401 para la API, HX-Redirect para HTMX y redirect al login para el navegador.
+func noAutorizado(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case esPeticionAPI(r):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": errSinSesion.Error()})
+	case r.Header.Get("HX-Request") == "true":
+		w.Header().Set("HX-Redirect", "/login")
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	}
+}
+
+// usuarioActual lee el usuario del contexto; si falta ya respondió 401
+func usuarioActual(w http.ResponseWriter, r *http.Request) (sqlc.Usuario, bool) {
+	usuario, ok := auth.UsuarioActual(r.Context())
+	if !ok {
+		noAutorizado(w, r)
+	}
+	return usuario, ok
+}
+
+func esPeticionAPI(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") || quiereJSON(r)
+}
+
+// RequirePermiso deja pasar solo a usuarios cuyo rol incluye el permiso.
+// Debe ir dentro de RequireAuth para que el usuario ya esté en el contexto.
+func RequirePermiso(permiso auth.Permiso, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		if !auth.Tiene(usuario, permiso) {
+			prohibido(w, r, "No tienes permisos para realizar esta acción")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// prohibido responde 403 en el formato que espera el cliente
+func prohibido(w http.ResponseWriter, r *http.Request, mensaje string) {
+	switch {
+	case esPeticionAPI(r):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": mensaje})
+	case r.Header.Get("HX-Request") == "true":
+		// HTMX no intercambia respuestas 4xx por defecto, así que el
+		// alert se manda con 200 para que el usuario lo vea.
+		views.AlertError(mensaje).Render(r.Context(), w)
+	default:
+		http.Error(w, mensaje, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,196 @@
+package handle
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+	"carrito.com/views"
+)
+
+// MonedaHandler: POST /moneda guarda en la sesión la moneda en la que se ven
+// los precios (el selector del header) y recarga la página para redibujarlos
+func MonedaHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		codigo := r.FormValue("moneda")
+		if _, ok := monedas.De(r.Context()).Buscar(codigo); !ok {
+			http.Error(w, "Moneda desconocida", http.StatusBadRequest)
+			return
+		}
+
+		err := queries.UpdateSesionMoneda(r.Context(), sqlc.UpdateSesionMonedaParams{
+			TokenHash: hashToken(tokenDeSesion(r)),
+			Moneda:    codigo,
+		})
+		if err != nil {
+			http.Error(w, "Error al guardar la moneda: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if r.Header.Get("HX-Request") == "true" {
+			w.Header().Set("HX-Refresh", "true")
+			return
+		}
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// AdminMonedasHandler maneja /admin/monedas: listado y alta o actualización de una moneda
+func AdminMonedasHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			views.MonedasAdmin().Render(r.Context(), w) // GET /admin/monedas
+		case http.MethodPost:
+			guardarMonedaHandler(db, queries)(w, r) // POST /admin/monedas
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminMonedaHandler maneja /admin/monedas/{codigo} (PUT con la nueva tasa)
+// y /admin/monedas/importar (POST con un archivo CSV)
+func AdminMonedaHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/admin/monedas/importar":
+			importarMonedasHandler(db, queries)(w, r) // POST /admin/monedas/importar
+		case r.Method == http.MethodPut:
+			actualizarTasaHandler(queries)(w, r) // PUT /admin/monedas/{codigo}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func guardarMonedaHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		moneda, err := monedas.Validar(r.FormValue("codigo"), r.FormValue("nombre"), r.FormValue("simbolo"), r.FormValue("tasa"))
+		if err != nil {
+			views.MonedasAdminTabla(err.Error(), true).Render(r.Context(), w)
+			return
+		}
+		guardarYRecargar(w, r, db, queries, []sqlc.Moneda{moneda}, "Moneda "+moneda.Codigo+" guardada")
+	}
+}
+
+// importarMonedasHandler carga un CSV codigo,nombre,simbolo,tasa subido desde el admin
+func importarMonedasHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		archivo, _, err := r.FormFile("archivo")
+		if err != nil {
+			views.MonedasAdminTabla("Elegí un archivo CSV para importar", true).Render(r.Context(), w)
+			return
+		}
+		defer archivo.Close()
+
+		lista, err := monedas.LeerCSV(archivo)
+		if err != nil {
+			views.MonedasAdminTabla("No se pudo importar: "+err.Error(), true).Render(r.Context(), w)
+			return
+		}
+		guardarYRecargar(w, r, db, queries, lista, "Monedas importadas")
+	}
+}
+
+func actualizarTasaHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		codigo := strings.TrimPrefix(r.URL.Path, "/admin/monedas/")
+		moneda, err := actualizarTasa(r, queries, codigo, r.FormValue("tasa"))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Moneda no encontrada", http.StatusNotFound)
+				return
+			}
+			views.MonedasAdminTabla(err.Error(), true).Render(r.Context(), w)
+			return
+		}
+		views.MonedasAdminTabla("Tasa de "+moneda.Codigo+" actualizada", false).Render(r.Context(), w)
+	}
+}
+
+// actualizarTasa valida y guarda la tasa de una moneda y recarga las cotizaciones
+func actualizarTasa(r *http.Request, queries *sqlc.Queries, codigo, tasa string) (sqlc.Moneda, error) {
+	if codigo == monedas.Base {
+		return sqlc.Moneda{}, errors.New("la tasa de " + monedas.Base + " es fija")
+	}
+	t, err := dinero.ParseTasa(tasa)
+	if err != nil {
+		return sqlc.Moneda{}, err
+	}
+	moneda, err := queries.UpdateMonedaTasa(r.Context(), sqlc.UpdateMonedaTasaParams{Codigo: codigo, Tasa: t})
+	if err != nil {
+		return sqlc.Moneda{}, err
+	}
+	return moneda, monedas.De(r.Context()).Recargar(r.Context(), queries)
+}
+
+// guardarYRecargar guarda las monedas, recarga las cotizaciones en memoria y
+// vuelve a dibujar la tabla. El contexto ya apunta a las cotizaciones
+// recargadas, así que la tabla muestra los valores nuevos.
+func guardarYRecargar(w http.ResponseWriter, r *http.Request, db *sql.DB, queries *sqlc.Queries, lista []sqlc.Moneda, mensaje string) {
+	ctx := r.Context()
+	if err := monedas.Guardar(ctx, db, queries, lista); err != nil {
+		views.MonedasAdminTabla("No se pudo guardar: "+err.Error(), true).Render(ctx, w)
+		return
+	}
+	if err := monedas.De(ctx).Recargar(ctx, queries); err != nil {
+		http.Error(w, "Error al recargar las monedas: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	views.MonedasAdminTabla(mensaje, false).Render(ctx, w)
+}
+
+// APIMonedasHandler: GET /api/v1/monedas lista las monedas con sus tasas
+func APIMonedasHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		escribirJSON(w, http.StatusOK, monedas.De(r.Context()).Lista())
+	}
+}
+
+// tasaRequest es el body de PUT /api/v1/moneda/{codigo}
+type tasaRequest struct {
+	Tasa string `json:"tasa"`
+}
+
+// APIMonedaHandler: PUT /api/v1/moneda/{codigo} actualiza la tasa de una moneda
+func APIMonedaHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req tasaRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+
+		codigo := strings.TrimPrefix(r.URL.Path, "/api/v1/moneda/")
+		moneda, err := actualizarTasa(r, queries, codigo, req.Tasa)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				errorDB(w, err, "moneda")
+				return
+			}
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		escribirJSON(w, http.StatusOK, moneda)
+	}
+}
@@ -0,0 +1,640 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"carrito.com/auth"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/pagos"
+	"carrito.com/pedidos"
+	"carrito.com/views"
+)
+
+// maxWebhookBytes limita el cuerpo que se acepta de una pasarela
+const maxWebhookBytes = 64 << 10
+
+var (
+	errSinPasarela     = errors.New("no hay una pasarela de pagos configurada")
+	errPagoDesconocido = errors.New("pago desconocido")
+)
+
+// iniciarPago abre un cobro en la pasarela predeterminada por el total del
+// pedido y lo pasa a esperando_pago. Si el pedido ya espera un pago devuelve
+// el cobro abierto en lugar de crear otro, salvo que la pasarela ya no lo
+// conozca: entonces lo anula y abre uno nuevo. Un pedido sin nada que cobrar
+// (por ejemplo, con un cupón del 100%) pasa directo a pagado y devuelve nil.
+// El intento se crea en la pasarela con el pedido bloqueado, así dos clics
+// seguidos no abren dos cobros.
+func iniciarPago(ctx context.Context, db *sql.DB, queries *sqlc.Queries, idPedido, autor int32) (*sqlc.Pago, error) {
+	proveedor, ok := pagos.Predeterminado()
+	if !ok {
+		return nil, errSinPasarela
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	pedido, err := qtx.GetPedidoForUpdate(ctx, idPedido)
+	if err != nil {
+		return nil, err
+	}
+	if pedidos.Estado(pedido.Estado) == pedidos.EstadoEsperandoPago {
+		abierto, err := qtx.GetPagoAbierto(ctx, idPedido)
+		switch {
+		case err == nil:
+			if abierto.Estado == pagos.EstadoCapturando {
+				// Ya se autorizó y falta la respuesta de la captura
+				return &abierto, nil
+			}
+			vigente, err := intentoVigente(ctx, abierto)
+			if err != nil {
+				return nil, err
+			}
+			if vigente {
+				return &abierto, nil
+			}
+			// Por ejemplo un cobro del proveedor falso de antes de reiniciar:
+			// nunca se va a poder pagar, así que se reemplaza
+			_, err = qtx.UpdatePagoEstado(ctx, sqlc.UpdatePagoEstadoParams{
+				IDPago: abierto.IDPago,
+				Estado: pagos.EstadoAnulado,
+			})
+			if err != nil {
+				return nil, err
+			}
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, err
+		}
+	} else if err := pedidos.Transicion(pedidos.Estado(pedido.Estado), pedidos.EstadoEsperandoPago); err != nil {
+		return nil, err
+	}
+
+	if pedido.Total == 0 {
+		if _, err := moverPedido(ctx, qtx, pedido, pedidos.EstadoPagado, &autor); err != nil {
+			return nil, err
+		}
+		return nil, tx.Commit()
+	}
+
+	intento, err := proveedor.CrearIntento(ctx, pagos.Cobro{
+		IDPedido: pedido.IDPedido,
+		Monto:    pedido.Total,
+		Moneda:   pedido.Moneda,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pago, err := qtx.CreatePago(ctx, sqlc.CreatePagoParams{
+		IDPedido:   pedido.IDPedido,
+		Proveedor:  proveedor.Nombre(),
+		Referencia: intento.Referencia,
+		Monto:      pedido.Total,
+		Moneda:     pedido.Moneda,
+		Url:        intento.URL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pedidos.Estado(pedido.Estado) != pedidos.EstadoEsperandoPago {
+		if _, err := moverPedido(ctx, qtx, pedido, pedidos.EstadoEsperandoPago, &autor); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &pago, nil
+}
+
+// intentoVigente indica si la pasarela del pago todavía conoce su intento. Si
+// la pasarela ya no está configurada el pago tampoco se puede completar.
+func intentoVigente(ctx context.Context, pago sqlc.Pago) (bool, error) {
+	proveedor, ok := pagos.Buscar(pago.Proveedor)
+	if !ok {
+		return false, nil
+	}
+	return proveedor.Existe(ctx, pago.Referencia)
+}
+
+// cobrarCompra abre el cobro de un pedido recién creado y lo devuelve
+// actualizado. Si la pasarela falla la compra igual queda registrada como
+// pendiente y el cliente puede reintentar el pago desde su historial.
+func cobrarCompra(ctx context.Context, db *sql.DB, queries *sqlc.Queries, pedido pedidos.Detalle, userID int32) (pedidos.Detalle, *sqlc.Pago) {
+	pago, err := iniciarPago(ctx, db, queries, pedido.IDPedido, userID)
+	if err != nil {
+		log.Printf("No se pudo iniciar el pago del pedido %d: %v", pedido.IDPedido, err)
+		return pedido, nil
+	}
+	actual, err := queries.GetPedido(ctx, pedido.IDPedido)
+	if err != nil {
+		log.Printf("Error al releer el pedido %d: %v", pedido.IDPedido, err)
+		return pedido, pago
+	}
+	detalles, err := detallesPedidos(ctx, queries, []sqlc.Pedido{actual})
+	if err != nil {
+		log.Printf("Error al releer el pedido %d: %v", pedido.IDPedido, err)
+		return pedido, pago
+	}
+	return detalles[0], pago
+}
+
+// claveCaptura y claveReembolso son las claves de idempotencia que se mandan
+// a la pasarela: siempre la misma para el mismo pago o reembolso
+func claveCaptura(idPago int32) string {
+	return "captura-" + strconv.Itoa(int(idPago))
+}
+
+func claveReembolso(idReembolso int32) string {
+	return "reembolso-" + strconv.Itoa(int(idReembolso))
+}
+
+// reservarReembolso deja pendiente la devolución del reembolso por la
+// pasarela si el pedido se cobró por ahí; los pedidos marcados como pagados a
+// mano no tienen cobro que devolver. El monto se suma a lo reembolsado del
+// pago en la misma transacción que el reembolso, así dos reembolsos
+// simultáneos no pasan de lo cobrado, pero a la pasarela se le pide recién
+// después del commit (ver enviarReembolsos). qtx tiene que estar en esa
+// transacción.
+func reservarReembolso(ctx context.Context, qtx *sqlc.Queries, reembolso sqlc.Reembolso) error {
+	pago, err := qtx.GetPagoCapturadoForUpdate(ctx, reembolso.IDPedido)
+	if errors.Is(err, sql.ErrNoRows) || reembolso.Monto == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := pagos.Buscar(pago.Proveedor); !ok {
+		return fmt.Errorf("el pago %d es de un proveedor no configurado: %s", pago.IDPago, pago.Proveedor)
+	}
+	if reembolso.Monto > pago.Monto-pago.Reembolsado {
+		return errReembolso{"el reembolso supera lo cobrado por la pasarela"}
+	}
+
+	err = qtx.SumarPagoReembolsado(ctx, sqlc.SumarPagoReembolsadoParams{
+		IDPago:      pago.IDPago,
+		Reembolsado: reembolso.Monto,
+	})
+	if err != nil {
+		return err
+	}
+	return qtx.MarcarReembolsoPendiente(ctx, sqlc.MarcarReembolsoPendienteParams{
+		IDReembolso: reembolso.IDReembolso,
+		IDPago:      &pago.IDPago,
+	})
+}
+
+// enviarReembolsos pide a la pasarela los reembolsos pendientes del pedido
+// (de todos los pedidos con idPedido 0). Cada uno ya está guardado con su
+// monto reservado en el pago, así que se pide fuera de la transacción y con
+// su propia clave: si se pierde la respuesta o falla el commit, el reintento
+// no devuelve dos veces. Si la pasarela no responde el reembolso sigue
+// pendiente y se vuelve a pedir con el próximo reembolso del pedido o al
+// reiniciar.
+func enviarReembolsos(ctx context.Context, db *sql.DB, queries *sqlc.Queries, idPedido int32) error {
+	pendientes, err := queries.ListReembolsosPendientes(ctx, idPedido)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, r := range pendientes {
+		if err := enviarReembolso(ctx, db, queries, r); err != nil {
+			errs = append(errs, fmt.Errorf("reembolso %d: %w", r.IDReembolso, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enviarReembolso pide un reembolso pendiente y guarda la respuesta. Si la
+// pasarela lo rechaza queda rechazado y se libera lo reservado en el pago; el
+// stock y el pedido ya se registraron, así que ese dinero hay que devolverlo
+// por otro lado.
+func enviarReembolso(ctx context.Context, db *sql.DB, queries *sqlc.Queries, r sqlc.ListReembolsosPendientesRow) error {
+	proveedor, ok := pagos.Buscar(r.Proveedor)
+	if !ok {
+		return fmt.Errorf("el pago %d es de un proveedor no configurado: %s", r.IDPago, r.Proveedor)
+	}
+	err := proveedor.Reembolsar(ctx, r.Referencia, r.Monto, claveReembolso(r.IDReembolso))
+	estado := pagos.ReembolsoDevuelto
+	var errPago pagos.ErrPago
+	switch {
+	case errors.As(err, &errPago):
+		log.Printf("La pasarela rechazó el reembolso %d: %v", r.IDReembolso, err)
+		estado = pagos.ReembolsoRechazado
+	case err != nil:
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	// Otro reintento pudo haber guardado la respuesta mientras tanto
+	actual, err := qtx.GetReembolsoForUpdate(ctx, r.IDReembolso)
+	if err != nil {
+		return err
+	}
+	if actual.EstadoPago != pagos.ReembolsoPendiente {
+		return nil
+	}
+	err = qtx.UpdateReembolsoEstadoPago(ctx, sqlc.UpdateReembolsoEstadoPagoParams{
+		IDReembolso: r.IDReembolso,
+		EstadoPago:  estado,
+	})
+	if err != nil {
+		return err
+	}
+	if estado == pagos.ReembolsoRechazado {
+		err := qtx.SumarPagoReembolsado(ctx, sqlc.SumarPagoReembolsadoParams{
+			IDPago:      r.IDPago,
+			Reembolsado: -r.Monto,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ReintentarReembolsos pide a la pasarela los reembolsos que quedaron
+// pendientes, por ejemplo porque se cortó la conexión. main lo llama al
+// arrancar.
+func ReintentarReembolsos(ctx context.Context, db *sql.DB, queries *sqlc.Queries) {
+	if err := enviarReembolsos(ctx, db, queries, 0); err != nil {
+		log.Printf("Reembolsos que siguen pendientes en la pasarela: %v", err)
+	}
+}
+
+// recibirWebhook verifica la firma de una notificación de la pasarela y la
+// aplica. Devuelve false si el evento ya se había procesado.
+//
+// Un pago autorizado se captura en dos pasos: primero queda capturando, y con
+// eso ya confirmado se le pide la captura a la pasarela; la respuesta se
+// guarda en otra transacción. Si algo falla en el medio el pago sigue
+// capturando, y el reintento del webhook, aunque traiga un evento repetido,
+// vuelve a pedir la captura con la misma clave, así la pasarela no cobra dos
+// veces.
+func recibirWebhook(ctx context.Context, db *sql.DB, queries *sqlc.Queries, proveedor pagos.Proveedor, cuerpo []byte, header http.Header) (bool, error) {
+	evento, err := proveedor.VerificarWebhook(cuerpo, header)
+	if err != nil {
+		return false, err
+	}
+	nuevo, pago, err := registrarEvento(ctx, db, queries, proveedor, evento)
+	if err != nil {
+		return false, err
+	}
+	if pago.Estado == pagos.EstadoCapturando {
+		if err := capturarPago(ctx, db, queries, proveedor, pago); err != nil {
+			return false, err
+		}
+	}
+	return nuevo, nil
+}
+
+// registrarEvento anota el evento y, si es nuevo, lo aplica al pago y al
+// pedido. Devuelve el pago como quedó; con un evento repetido, como estaba.
+func registrarEvento(ctx context.Context, db *sql.DB, queries *sqlc.Queries, proveedor pagos.Proveedor, evento pagos.Evento) (bool, sqlc.Pago, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, sqlc.Pago{}, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	// Las pasarelas reintentan hasta recibir un 2xx: el mismo evento puede
+	// llegar más de una vez y solo se aplica la primera
+	nuevo, err := qtx.CreatePagoEvento(ctx, sqlc.CreatePagoEventoParams{
+		Proveedor: proveedor.Nombre(),
+		IDEvento:  evento.ID,
+		Tipo:      evento.Tipo,
+	})
+	if err != nil {
+		return false, sqlc.Pago{}, err
+	}
+
+	pago, err := qtx.GetPagoPorReferenciaForUpdate(ctx, sqlc.GetPagoPorReferenciaForUpdateParams{
+		Proveedor:  proveedor.Nombre(),
+		Referencia: evento.Referencia,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, sqlc.Pago{}, errPagoDesconocido
+	}
+	if err != nil {
+		return false, sqlc.Pago{}, err
+	}
+	if nuevo == 0 {
+		return false, pago, nil
+	}
+	pedido, err := qtx.GetPedidoForUpdate(ctx, pago.IDPedido)
+	if err != nil {
+		return false, sqlc.Pago{}, err
+	}
+
+	if pago, err = aplicarEvento(ctx, qtx, evento, pago, pedido); err != nil {
+		return false, sqlc.Pago{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, sqlc.Pago{}, err
+	}
+	return true, pago, nil
+}
+
+// aplicarEvento lleva el pago y el pedido al estado que informa la pasarela.
+// Un pago autorizado queda capturando si el pedido sigue esperándolo (la
+// captura se pide después del commit, ver capturarPago); si se canceló o se
+// cobró por otro lado, el cobro se anula sin capturar. Si el pago se
+// rechaza, el pedido vuelve a pendiente para que el cliente pueda reintentar.
+func aplicarEvento(ctx context.Context, qtx *sqlc.Queries, evento pagos.Evento, pago sqlc.Pago, pedido sqlc.Pedido) (sqlc.Pago, error) {
+	if pago.Estado != pagos.EstadoCreado {
+		return pago, nil
+	}
+	esperando := pedidos.Estado(pedido.Estado) == pedidos.EstadoEsperandoPago
+
+	estado := ""
+	switch evento.Tipo {
+	case pagos.EventoAutorizado:
+		estado = pagos.EstadoCapturando
+		if !esperando {
+			estado = pagos.EstadoAnulado
+		}
+	case pagos.EventoRechazado:
+		estado = pagos.EstadoRechazado
+	default:
+		// Los tipos que no usamos quedan registrados y se ignoran
+		return pago, nil
+	}
+
+	pago, err := qtx.UpdatePagoEstado(ctx, sqlc.UpdatePagoEstadoParams{IDPago: pago.IDPago, Estado: estado})
+	if err != nil {
+		return sqlc.Pago{}, err
+	}
+	if estado == pagos.EstadoRechazado && esperando {
+		if _, err := moverPedido(ctx, qtx, pedido, pedidos.EstadoPendiente, nil); err != nil {
+			return sqlc.Pago{}, err
+		}
+	}
+	return pago, nil
+}
+
+// capturarPago pide a la pasarela la captura de un pago que quedó capturando
+// y guarda la respuesta: capturado y el pedido pagado, o rechazado y el
+// pedido de vuelta a pendiente. La clave es la misma en cada reintento, y un
+// intento que ya estaba capturado cuenta como capturado. Si la pasarela no
+// responde el pago sigue capturando hasta el próximo reintento del webhook.
+func capturarPago(ctx context.Context, db *sql.DB, queries *sqlc.Queries, proveedor pagos.Proveedor, pago sqlc.Pago) error {
+	err := proveedor.Capturar(ctx, pago.Referencia, claveCaptura(pago.IDPago))
+	estado, hacia := pagos.EstadoCapturado, pedidos.EstadoPagado
+	var errPago pagos.ErrPago
+	switch {
+	case err == nil || errors.Is(err, pagos.ErrCapturado):
+	case errors.As(err, &errPago):
+		log.Printf("No se pudo capturar el pago %d: %v", pago.IDPago, err)
+		estado, hacia = pagos.EstadoRechazado, pedidos.EstadoPendiente
+	default:
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	// Otro reintento pudo haber guardado la respuesta mientras tanto
+	pago, err = qtx.GetPagoPorReferenciaForUpdate(ctx, sqlc.GetPagoPorReferenciaForUpdateParams{
+		Proveedor:  pago.Proveedor,
+		Referencia: pago.Referencia,
+	})
+	if err != nil {
+		return err
+	}
+	if pago.Estado != pagos.EstadoCapturando {
+		return nil
+	}
+	pedido, err := qtx.GetPedidoForUpdate(ctx, pago.IDPedido)
+	if err != nil {
+		return err
+	}
+	if _, err := qtx.UpdatePagoEstado(ctx, sqlc.UpdatePagoEstadoParams{IDPago: pago.IDPago, Estado: estado}); err != nil {
+		return err
+	}
+	// Mientras el pago está capturando el pedido no se mueve a mano (ver
+	// cambiarEstadoPedido), así que sigue esperándolo
+	if pedidos.Estado(pedido.Estado) == pedidos.EstadoEsperandoPago {
+		if _, err := moverPedido(ctx, qtx, pedido, hacia, nil); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// WebhookPagosHandler: POST /api/v1/pagos/webhook/{proveedor} recibe las
+// notificaciones de la pasarela. Es pública: la autenticación es la firma.
+func WebhookPagosHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		proveedor, ok := pagos.Buscar(strings.TrimPrefix(r.URL.Path, "/api/v1/pagos/webhook/"))
+		if !ok {
+			errorJSON(w, http.StatusNotFound, "proveedor de pagos desconocido")
+			return
+		}
+		cuerpo, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, "cuerpo inválido")
+			return
+		}
+
+		procesado, err := recibirWebhook(r.Context(), db, queries, proveedor, cuerpo, r.Header)
+		if err != nil {
+			errorWebhook(w, err)
+			return
+		}
+		escribirJSON(w, http.StatusOK, map[string]bool{"procesado": procesado})
+	}
+}
+
+func errorWebhook(w http.ResponseWriter, err error) {
+	var errTransicion pedidos.ErrTransicion
+	switch {
+	case errors.Is(err, pagos.ErrFirma):
+		errorJSON(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, errPagoDesconocido):
+		errorJSON(w, http.StatusNotFound, err.Error())
+	case errors.As(err, &errTransicion):
+		errorJSON(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Error procesando el webhook de pagos: %v", err)
+		errorJSON(w, http.StatusInternalServerError, "error procesando el evento")
+	}
+}
+
+// pagarPedidoHandler: POST /sales/{id}/pagar manda al cliente a la pasarela
+// para pagar un pedido pendiente o que todavía espera el pago
+func pagarPedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+		r.URL.Path = strings.TrimSuffix(r.URL.Path, "/pagar")
+		id, err := idDeRuta(r, "/sales/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		pedido, err := queries.GetPedido(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && pedido.IDUsuario != usuario.IDUsuario) {
+			http.Error(w, "Pedido no encontrado", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error al leer el pedido: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		pago, err := iniciarPago(r.Context(), db, queries, id, usuario.IDUsuario)
+		var errTransicion pedidos.ErrTransicion
+		if errors.As(err, &errTransicion) {
+			http.Error(w, "Este pedido ya no se puede pagar", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "No se pudo iniciar el pago: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if pago == nil {
+			redirigir(w, r, "/sales")
+			return
+		}
+		redirigir(w, r, pago.Url)
+	}
+}
+
+// pagoFakeDelUsuario busca un cobro del proveedor falso y verifica que sea de
+// un pedido del usuario, salvo para quien administra ventas
+func pagoFakeDelUsuario(ctx context.Context, queries *sqlc.Queries, fake *pagos.Fake, referencia string) (sqlc.Pago, error) {
+	pago, err := queries.GetPagoPorReferencia(ctx, sqlc.GetPagoPorReferenciaParams{
+		Proveedor:  fake.Nombre(),
+		Referencia: referencia,
+	})
+	if err != nil {
+		return sqlc.Pago{}, err
+	}
+	if auth.Puede(ctx, auth.PermisoVentas) {
+		return pago, nil
+	}
+	pedido, err := queries.GetPedido(ctx, pago.IDPedido)
+	if err != nil {
+		return sqlc.Pago{}, err
+	}
+	if usuario, _ := auth.UsuarioActual(ctx); pedido.IDUsuario != usuario.IDUsuario {
+		return sqlc.Pago{}, sql.ErrNoRows
+	}
+	return pago, nil
+}
+
+// FakePagoHandler maneja /pagos/fake/{referencia}, la "pasarela" del
+// proveedor falso: GET muestra el cobro y POST con aprobar=1 o aprobar=0 lo
+// resuelve mandando el webhook firmado, como haría una pasarela real.
+func FakePagoHandler(db *sql.DB, queries *sqlc.Queries, fake *pagos.Fake) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		referencia := strings.TrimPrefix(r.URL.Path, "/pagos/fake/")
+		pago, err := pagoFakeDelUsuario(r.Context(), queries, fake, referencia)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Pago no encontrado", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error al leer el pago: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			views.PagoFake(pago, "").Render(r.Context(), w)
+		case http.MethodPost:
+			if err := simularPago(r.Context(), db, queries, fake, referencia, r.FormValue("aprobar") == "1"); err != nil {
+				views.PagoFake(pago, err.Error()).Render(r.Context(), w)
+				return
+			}
+			redirigir(w, r, "/sales")
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// fakePagoRequest es el body de POST /api/v1/pagos/fake/{referencia}
+type fakePagoRequest struct {
+	Aprobar bool `json:"aprobar"`
+}
+
+// APIFakePagoHandler: POST /api/v1/pagos/fake/{referencia} {"aprobar": true}
+// resuelve un cobro del proveedor falso y devuelve el pedido actualizado
+func APIFakePagoHandler(db *sql.DB, queries *sqlc.Queries, fake *pagos.Fake) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		referencia := strings.TrimPrefix(r.URL.Path, "/api/v1/pagos/fake/")
+		pago, err := pagoFakeDelUsuario(r.Context(), queries, fake, referencia)
+		if err != nil {
+			errorDB(w, err, "pago")
+			return
+		}
+		var req fakePagoRequest
+		if err := leerJSON(w, r, &req); err != nil {
+			errorLeerJSON(w, err)
+			return
+		}
+
+		err = simularPago(r.Context(), db, queries, fake, referencia, req.Aprobar)
+		var errPago pagos.ErrPago
+		if errors.As(err, &errPago) {
+			errorJSON(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			errorWebhook(w, err)
+			return
+		}
+
+		pedido, err := queries.GetPedido(r.Context(), pago.IDPedido)
+		if err != nil {
+			errorDB(w, err, "pedido")
+			return
+		}
+		responderPedidoJSON(w, r, queries, pedido, http.StatusOK)
+	}
+}
+
+// simularPago resuelve el intento en el proveedor falso y entrega su webhook
+// por el mismo camino que uno recibido por HTTP, con firma e idempotencia
+func simularPago(ctx context.Context, db *sql.DB, queries *sqlc.Queries, fake *pagos.Fake, referencia string, aprobar bool) error {
+	cuerpo, header, err := fake.Simular(referencia, aprobar)
+	if err != nil {
+		return err
+	}
+	_, err = recibirWebhook(ctx, db, queries, fake, cuerpo, header)
+	return err
+}
@@ -0,0 +1,320 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/pagos"
+	"carrito.com/pedidos"
+	"carrito.com/views"
+)
+
+// AdminPedidosHandler: GET /admin/pedidos?estado=... lista los pedidos de todos
+// los usuarios, opcionalmente filtrados por estado. Las requests de HTMX reciben
+// solo la lista para reemplazarla al cambiar el filtro.
+func AdminPedidosHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		filtro := pedidos.Estado(r.URL.Query().Get("estado"))
+		if filtro != "" && !filtro.Valido() {
+			http.Error(w, "Estado inválido", http.StatusBadRequest)
+			return
+		}
+
+		var lista []sqlc.Pedido
+		var err error
+		if filtro == "" {
+			lista, err = queries.ListPedidos(r.Context())
+		} else {
+			lista, err = queries.ListPedidosPorEstado(r.Context(), string(filtro))
+		}
+		if err != nil {
+			http.Error(w, "Error al listar pedidos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		detalles, err := detallesPedidos(r.Context(), queries, lista)
+		if err != nil {
+			http.Error(w, "Error al listar pedidos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if quiereJSON(r) {
+			escribirJSON(w, http.StatusOK, detalles)
+			return
+		}
+		if r.Header.Get("HX-Request") == "true" {
+			views.PedidosAdminLista(detalles).Render(r.Context(), w)
+			return
+		}
+		views.PedidosAdmin(detalles, filtro).Render(r.Context(), w)
+	}
+}
+
+// AdminPedidoHandler maneja las acciones sobre un pedido desde /admin/pedidos.
+// Todas devuelven la tarjeta del pedido actualizada para reemplazarla con HTMX.
+func AdminPedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/estado"):
+			adminEstadoPedidoHandler(db, queries)(w, r) // POST /admin/pedidos/{id}/estado
+		case strings.HasSuffix(r.URL.Path, "/reembolso"):
+			adminReembolsoPedidoHandler(db, queries)(w, r) // POST /admin/pedidos/{id}/reembolso
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// adminEstadoPedidoHandler avanza el pedido al valor del campo "estado"
+func adminEstadoPedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		r.URL.Path = strings.TrimSuffix(r.URL.Path, "/estado")
+		id, err := idDeRuta(r, "/admin/pedidos/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hacia := pedidos.Estado(r.FormValue("estado"))
+		_, err = cambiarEstadoPedido(r.Context(), db, queries, id, hacia, usuario.IDUsuario)
+		var errTransicion pedidos.ErrTransicion
+		if errors.As(err, &errTransicion) {
+			// La tarjeta se vuelve a dibujar con el estado real y el motivo del rechazo
+			renderTarjetaAdmin(w, r, queries, id, "No se pudo actualizar: "+err.Error())
+			return
+		}
+		if err != nil {
+			errorPedidoAdmin(w, err)
+			return
+		}
+		renderTarjetaAdmin(w, r, queries, id, "")
+	}
+}
+
+// adminReembolsoPedidoHandler reembolsa las cantidades de los campos
+// cantidad_{id_item}, o todo lo pendiente si viene el campo "todo"
+func adminReembolsoPedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+
+		r.URL.Path = strings.TrimSuffix(r.URL.Path, "/reembolso")
+		id, err := idDeRuta(r, "/admin/pedidos/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error leyendo formulario", http.StatusBadRequest)
+			return
+		}
+
+		var lineas []lineaReembolso
+		if r.FormValue("todo") == "" {
+			lineas, err = lineasReembolsoDeForm(r)
+			if err != nil {
+				renderTarjetaAdmin(w, r, queries, id, err.Error())
+				return
+			}
+			if len(lineas) == 0 {
+				renderTarjetaAdmin(w, r, queries, id, "Indicá cuántas unidades reembolsar")
+				return
+			}
+		}
+
+		_, err = reembolsarPedido(r.Context(), db, queries, id, lineas, r.FormValue("motivo"), usuario.IDUsuario)
+		var errR errReembolso
+		if errors.As(err, &errR) {
+			renderTarjetaAdmin(w, r, queries, id, "No se pudo reembolsar: "+err.Error())
+			return
+		}
+		if err != nil {
+			errorPedidoAdmin(w, err)
+			return
+		}
+		renderTarjetaAdmin(w, r, queries, id, "")
+	}
+}
+
+// lineasReembolsoDeForm lee los campos cantidad_{id_item}; los vacíos o en 0 se ignoran
+func lineasReembolsoDeForm(r *http.Request) ([]lineaReembolso, error) {
+	var lineas []lineaReembolso
+	for campo, valores := range r.PostForm {
+		idStr, ok := strings.CutPrefix(campo, "cantidad_")
+		if !ok || len(valores) == 0 || valores[0] == "" {
+			continue
+		}
+		idItem, err := strconv.ParseInt(idStr, 10, 32)
+		if err != nil {
+			return nil, errors.New("línea inválida")
+		}
+		cantidad, err := strconv.ParseInt(valores[0], 10, 32)
+		if err != nil || cantidad < 0 {
+			return nil, errors.New("cantidad inválida")
+		}
+		if cantidad > 0 {
+			lineas = append(lineas, lineaReembolso{IDItem: int32(idItem), Cantidad: int32(cantidad)})
+		}
+	}
+	return lineas, nil
+}
+
+// renderTarjetaAdmin vuelve a leer el pedido y dibuja su tarjeta con el mensaje
+func renderTarjetaAdmin(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, id int32, mensaje string) {
+	pedido, err := queries.GetPedido(r.Context(), id)
+	if err != nil {
+		errorPedidoAdmin(w, err)
+		return
+	}
+	detalles, err := detallesPedidos(r.Context(), queries, []sqlc.Pedido{pedido})
+	if err != nil {
+		errorPedidoAdmin(w, err)
+		return
+	}
+	views.PedidoAdminTarjeta(detalles[0], mensaje).Render(r.Context(), w)
+}
+
+func errorPedidoAdmin(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Pedido no encontrado", http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Error al actualizar el pedido: "+err.Error(), http.StatusInternalServerError)
+}
+
+// cambiarEstadoPedido pasa el pedido al estado hacia si la máquina de estados
+// lo permite y deja el cambio en el historial. La fila del pedido se bloquea
+// para que dos administradores no lo avancen a la vez desde el mismo estado.
+// Cancelar devuelve al stock todo lo que no se haya reembolsado antes y, si el
+// pedido estaba pagado, registra el reembolso de ese resto y después del
+// commit se lo pide a la pasarela. Si el pedido esperaba el pago, el cobro
+// abierto en la pasarela se anula; si ese cobro ya se está capturando, el
+// pedido no se puede mover hasta que la pasarela responda.
+func cambiarEstadoPedido(ctx context.Context, db *sql.DB, queries *sqlc.Queries, idPedido int32, hacia pedidos.Estado, autor int32) (sqlc.Pedido, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqlc.Pedido{}, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	pedido, err := qtx.GetPedidoForUpdate(ctx, idPedido)
+	if err != nil {
+		return sqlc.Pedido{}, err
+	}
+	if err := pedidos.TransicionManual(pedidos.Estado(pedido.Estado), hacia); err != nil {
+		return sqlc.Pedido{}, err
+	}
+	if pedidos.Estado(pedido.Estado) == pedidos.EstadoEsperandoPago {
+		abierto, err := qtx.GetPagoAbierto(ctx, idPedido)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return sqlc.Pedido{}, err
+		}
+		if err == nil && abierto.Estado == pagos.EstadoCapturando {
+			return sqlc.Pedido{}, pedidos.ErrTransicion{
+				Desde:  pedidos.Estado(pedido.Estado),
+				Hacia:  hacia,
+				Motivo: "la pasarela está capturando el pago",
+			}
+		}
+	}
+
+	if hacia == pedidos.EstadoCancelado {
+		if err := devolverPedido(ctx, qtx, pedido, autor); err != nil {
+			return sqlc.Pedido{}, err
+		}
+	}
+	if pedidos.Estado(pedido.Estado) == pedidos.EstadoEsperandoPago {
+		// Cancelado o cobrado por fuera, el cobro abierto ya no corresponde
+		if err := qtx.AnularPagosAbiertos(ctx, idPedido); err != nil {
+			return sqlc.Pedido{}, err
+		}
+	}
+
+	pedido, err = moverPedido(ctx, qtx, pedido, hacia, &autor)
+	if err != nil {
+		return sqlc.Pedido{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return sqlc.Pedido{}, err
+	}
+
+	if hacia == pedidos.EstadoCancelado {
+		if err := enviarReembolsos(ctx, db, queries, idPedido); err != nil {
+			log.Printf("Reembolsos del pedido %d pendientes en la pasarela: %v", idPedido, err)
+		}
+	}
+	return pedido, nil
+}
+
+// moverPedido cambia el estado de un pedido ya bloqueado y lo anota en el
+// historial; autor es nil cuando el cambio lo origina la pasarela de pagos.
+// qtx tiene que estar en una transacción.
+func moverPedido(ctx context.Context, qtx *sqlc.Queries, pedido sqlc.Pedido, hacia pedidos.Estado, autor *int32) (sqlc.Pedido, error) {
+	desde := pedido.Estado
+	if err := pedidos.Transicion(pedidos.Estado(desde), hacia); err != nil {
+		return sqlc.Pedido{}, err
+	}
+	pedido, err := qtx.UpdatePedidoEstado(ctx, sqlc.UpdatePedidoEstadoParams{
+		IDPedido: pedido.IDPedido,
+		Estado:   string(hacia),
+	})
+	if err != nil {
+		return sqlc.Pedido{}, err
+	}
+	_, err = qtx.CreatePedidoHistorial(ctx, sqlc.CreatePedidoHistorialParams{
+		IDPedido:       pedido.IDPedido,
+		EstadoAnterior: &desde,
+		Estado:         pedido.Estado,
+		IDUsuario:      autor,
+	})
+	if err != nil {
+		return sqlc.Pedido{}, err
+	}
+	return pedido, nil
+}
+
+// devolverPedido devuelve las unidades pendientes de un pedido que se cancela,
+// junto con el costo de envío. Si todavía no se cobró no hay dinero que
+// devolver y solo se repone el stock.
+func devolverPedido(ctx context.Context, qtx *sqlc.Queries, pedido sqlc.Pedido, autor int32) error {
+	items, err := qtx.ListItemsPedidoForUpdate(ctx, pedido.IDPedido)
+	if err != nil {
+		return err
+	}
+	lineas := lineasPendientes(items)
+
+	if pedidos.Estado(pedido.Estado).Cobrado() {
+		if len(lineas) == 0 && pedido.CostoEnvio == 0 {
+			return nil
+		}
+		_, err := registrarReembolso(ctx, qtx, pedido, items, lineas, pedido.CostoEnvio, "Pedido cancelado", autor)
+		return err
+	}
+	if len(lineas) == 0 {
+		return nil
+	}
+	return devolverStock(ctx, qtx, items, cantidadesPorItem(lineas))
+}
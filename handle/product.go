@@ -1,16 +1,22 @@
 package handle
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"carrito.com/catalogo"
 	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
 	"carrito.com/views"
 )
 
-// Handler principal con proteccion de login
-func IndexPageHandler(queries *sqlc.Queries) http.HandlerFunc {
+// Handler principal (protegido con RequireAuth en main.go)
+func IndexPageHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		if r.URL.Path != "/" {
@@ -18,36 +24,42 @@ func IndexPageHandler(queries *sqlc.Queries) http.HandlerFunc {
 			return
 		}
 
-		cookie, err := r.Cookie("session_token")
-		if err != nil {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+		// Los filtros viajan en la URL: la página se dibuja ya filtrada y el
+		// panel pide esta misma URL por HTMX, que solo devuelve la lista y los
+		// conteos por categoría
+		f := catalogo.Leer(r.URL.Query())
+		listado, err := listarProductos(r.Context(), db, f)
+		if errors.Is(err, catalogo.ErrCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		idInt, err := strconv.Atoi(cookie.Value)
 		if err != nil {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		_, err = queries.GetUser(r.Context(), int32(idInt))
+		facetas, err := contarFacetas(r.Context(), queries, f)
 		if err != nil {
-			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		if r.Header.Get("HX-Request") == "true" {
+			views.ProductosTienda(listado).Render(r.Context(), w)
+			views.PanelFacetas(f, facetas, true).Render(r.Context(), w)
+			return
+		}
 		// Renderizar vista lista
-		views.Layout().Render(r.Context(), w)
+		views.Layout(f, facetas, listado).Render(r.Context(), w)
 	}
 }
 
-func ProductsHandler(queries *sqlc.Queries) http.HandlerFunc {
+func ProductsHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			listProdHandler(queries)(w, r) // GET /products
+			listProdHandler(db)(w, r) // GET /products
 		case http.MethodPost:
-			createProdHandler(queries)(w, r) // POST /products
+			createProdHandler(db, queries)(w, r) // POST /products
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
@@ -55,168 +67,386 @@ func ProductsHandler(queries *sqlc.Queries) http.HandlerFunc {
 }
 
 // Producto: POST /products
-func createProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+func createProdHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		if err := r.ParseForm(); err != nil {
-			http.Error(w, "Error leyendo formulario", http.StatusBadRequest)
-			return
+		var req sqlc.CreateProdParams
+		if esJSON(r) {
+			if err := leerJSON(w, r, &req); err != nil {
+				http.Error(w, "JSON inválido", http.StatusBadRequest)
+				return
+			}
+		} else {
+			var err error
+			if req, err = productoDelForm(r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 
-		// Obtener valores del formulario
-		nombre := r.FormValue("nombre_producto")
-		descripcion := r.FormValue("descripcion")
-		precio := r.FormValue("precio")
-		stockStr := r.FormValue("stock")
-		categoria := r.FormValue("categoria")
-		imagen := r.FormValue("imagen")
-
 		// Validación básica
-		if nombre == "" || precio == "" {
-			http.Error(w, "Nombre y Precio son requeridos", http.StatusBadRequest)
+		if err := validarProducto(req.NombreProducto, req.Precio, req.Stock, req.Peso); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		stock, err := strconv.Atoi(stockStr)
-		if err != nil {
-			http.Error(w, "Stock inválido", http.StatusBadRequest)
+		if err := monedaProducto(r.Context(), &req.Moneda); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		// Crear parámetros para sqlc
-		req := sqlc.CreateProdParams{
-			NombreProducto: nombre,
-			Descripcion:    descripcion,
-			Precio:         precio,
-			Stock:          int32(stock),
-			Categoria:      categoria,
-			Imagen:         imagen,
+		var err error
+		if req.IDCategoria, req.Categoria, err = categoriaProducto(r.Context(), queries, req.IDCategoria, req.Categoria); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
 		// Crear producto en DB
-		_, err = queries.CreateProd(r.Context(), req)
+		producto, err := queries.CreateProd(r.Context(), req)
 		if err != nil {
 			http.Error(w, "Error al crear producto: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		if quiereJSON(r) {
+			escribirJSON(w, http.StatusCreated, producto)
+			return
+		}
+
 		// Recargar la lista de productos luego de crear uno
-		productos, err := queries.ListProd(r.Context())
+		productos, despues, err := listarAdmin(r.Context(), db, catalogo.Filtro{}, false)
 		if err != nil {
 			http.Error(w, "Error cargando productos: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
 		w.WriteHeader(http.StatusCreated)
-		views.ProductListDelete(productos).Render(r.Context(), w)
+		views.ProductListDelete(productos, siguienteAdmin(catalogo.Filtro{}, false, despues)).Render(r.Context(), w)
 	}
 }
 
+// productoDelForm lee los campos del formulario de alta y edición
+func productoDelForm(r *http.Request) (sqlc.CreateProdParams, error) {
+	if err := r.ParseForm(); err != nil {
+		return sqlc.CreateProdParams{}, errors.New("error leyendo formulario")
+	}
+
+	stock, err := strconv.Atoi(r.FormValue("stock"))
+	if err != nil {
+		return sqlc.CreateProdParams{}, errors.New("stock inválido")
+	}
+	// El peso es opcional: sin peso el producto no suma al envío
+	peso := 0
+	if v := r.FormValue("peso"); v != "" {
+		if peso, err = strconv.Atoi(v); err != nil {
+			return sqlc.CreateProdParams{}, errors.New("peso inválido")
+		}
+	}
+	precio, err := dinero.Parse(r.FormValue("precio"))
+	if err != nil {
+		return sqlc.CreateProdParams{}, errors.New("precio inválido: " + err.Error())
+	}
+
+	return sqlc.CreateProdParams{
+		NombreProducto: r.FormValue("nombre_producto"),
+		Descripcion:    r.FormValue("descripcion"),
+		Precio:         precio,
+		Stock:          int32(stock),
+		Categoria:      r.FormValue("categoria"),
+		Imagen:         r.FormValue("imagen"),
+		Moneda:         r.FormValue("moneda"),
+		Peso:           int32(peso),
+	}, nil
+}
+
 // Producto: GET /products
-func listProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+func listProdHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if quiereJSON(r) {
+			ListProductsViewHandler(db)(w, r)
+			return
+		}
 		views.ProductView().Render(r.Context(), w)
 	}
 }
 
 // PRODUCTOS INDIVIDAULES
-func ProductHandler(queries *sqlc.Queries) http.HandlerFunc {
+func ProductHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/restaurar") {
+			restaurarProdHandler(queries)(w, r) // POST /products/{id}/restaurar
+			return
+		}
 		switch r.Method {
+		case http.MethodGet:
+			getProdHandler(queries)(w, r) // GET /products/{id}
+		case http.MethodPut:
+			updateProdHandler(queries)(w, r) // PUT /products/{id}
 		case http.MethodDelete:
-			deleteProdHandler(queries)(w, r) // DELETE /products/{id}
+			deleteProdHandler(db, queries)(w, r) // DELETE /products/{id}
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
 	}
 }
 
-// Producto: DELETE /products/{id}
-func deleteProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+// Producto: GET /products/{id} devuelve el formulario de edición en lugar de
+// la tarjeta del producto (HTMX), la página de edición o el producto en JSON.
+// Con ?cancelar=1 devuelve la tarjeta, para descartar la edición.
+func getProdHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := r.URL.Path[len("/products/"):]
-		id, err := strconv.Atoi(idStr)
+		id, err := idDeRuta(r, "/products/")
 		if err != nil {
 			http.Error(w, "ID de producto inválido", http.StatusBadRequest)
 			return
 		}
 
-		err = queries.DeleteProd(r.Context(), int32(id))
+		producto, err := queries.GetProd(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Producto no encontrado", http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Error al eliminar producto: "+err.Error(), http.StatusInternalServerError)
+			http.Error(w, "Error al leer el producto: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		productos, err := queries.ListProd(r.Context())
+		switch {
+		case quiereJSON(r):
+			escribirJSON(w, http.StatusOK, producto)
+		case r.URL.Query().Get("cancelar") != "":
+			views.ProductoAdmin(producto).Render(r.Context(), w)
+		case r.Header.Get("HX-Request") == "true":
+			views.ProductoEditar(producto, "").Render(r.Context(), w)
+		default:
+			views.ProductEditView(producto).Render(r.Context(), w)
+		}
+	}
+}
+
+// Producto: PUT /products/{id}. Desde el formulario devuelve la tarjeta
+// actualizada; si los datos no son válidos vuelve a mostrar el formulario con
+// lo que se cargó y el motivo.
+func updateProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/products/")
 		if err != nil {
-			http.Error(w, "Error cargando productos: "+err.Error(), http.StatusInternalServerError)
+			http.Error(w, "ID de producto inválido", http.StatusBadRequest)
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
-		views.ProductListDelete(productos).Render(r.Context(), w)
+		var req sqlc.UpdateProductoParams
+		if esJSON(r) {
+			if err := leerJSON(w, r, &req); err != nil {
+				errorLeerJSON(w, err)
+				return
+			}
+		} else {
+			form, err := productoDelForm(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			req = sqlc.UpdateProductoParams{
+				NombreProducto: form.NombreProducto,
+				Descripcion:    form.Descripcion,
+				Stock:          form.Stock,
+				Precio:         form.Precio,
+				Categoria:      form.Categoria,
+				Imagen:         form.Imagen,
+				Moneda:         form.Moneda,
+				Peso:           form.Peso,
+			}
+		}
+		req.IDProducto = id
+
+		err = validarProducto(req.NombreProducto, req.Precio, req.Stock, req.Peso)
+		if err == nil {
+			err = monedaProducto(r.Context(), &req.Moneda)
+		}
+		if err == nil {
+			req.IDCategoria, req.Categoria, err = categoriaProducto(r.Context(), queries, req.IDCategoria, req.Categoria)
+		}
+		if err != nil {
+			if quiereJSON(r) {
+				errorJSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			views.ProductoEditar(sqlc.Producto{
+				IDProducto:     req.IDProducto,
+				NombreProducto: req.NombreProducto,
+				Descripcion:    req.Descripcion,
+				Precio:         req.Precio,
+				Moneda:         req.Moneda,
+				Stock:          req.Stock,
+				Categoria:      req.Categoria,
+				IDCategoria:    req.IDCategoria,
+				Imagen:         req.Imagen,
+				Peso:           req.Peso,
+			}, err.Error()).Render(r.Context(), w)
+			return
+		}
+
+		producto, err := queries.UpdateProducto(r.Context(), req)
+		if err != nil {
+			if quiereJSON(r) {
+				errorDB(w, err, "producto")
+				return
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Producto no encontrado", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Error al actualizar el producto: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if quiereJSON(r) {
+			escribirJSON(w, http.StatusOK, producto)
+			return
+		}
+		views.ProductoAdmin(producto).Render(r.Context(), w)
 	}
 }
 
-// handler para  /list-products (HTMX/templ)
+// archivarProducto oculta el producto de la tienda y lo saca de los carritos
+// en una transacción. Devuelve false si el producto no existe.
+func archivarProducto(ctx context.Context, db *sql.DB, queries *sqlc.Queries, id int32) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
 
-func ListProductsHandler(queries *sqlc.Queries) http.HandlerFunc {
+	filas, err := qtx.ArchivarProd(ctx, id)
+	if err != nil || filas == 0 {
+		return false, err
+	}
+	if err := qtx.DeleteProdDeCarritos(ctx, id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Producto: DELETE /products/{id} archiva el producto; los pedidos que lo
+// incluyen lo siguen mostrando con el nombre con que se vendió
+func deleteProdHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sortBy := r.URL.Query().Get("sort")
-		var (
-			productos []sqlc.Producto
-			err       error
-		)
-
-		switch sortBy {
-		case "price-asc":
-			productos, err = queries.ListProductsByPriceAsc(r.Context())
-		case "price-desc":
-			productos, err = queries.ListProductsByPriceDesc(r.Context())
-		default:
-			productos, err = queries.ListProd(r.Context())
+		id, err := idDeRuta(r, "/products/")
+		if err != nil {
+			http.Error(w, "ID de producto inválido", http.StatusBadRequest)
+			return
+		}
+
+		existe, err := archivarProducto(r.Context(), db, queries, id)
+		if err != nil {
+			http.Error(w, "Error al archivar el producto: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !existe {
+			http.Error(w, "Producto no encontrado", http.StatusNotFound)
+			return
+		}
+		if quiereJSON(r) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		productos, despues, err := listarAdmin(r.Context(), db, catalogo.Filtro{}, false)
+		if err != nil {
+			http.Error(w, "Error cargando productos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		views.ProductListDelete(productos, siguienteAdmin(catalogo.Filtro{}, false, despues)).Render(r.Context(), w)
+	}
+}
+
+// Producto: POST /products/{id}/restaurar vuelve a publicar un producto
+// archivado. Desde la lista de archivados la tarjeta desaparece.
+func restaurarProdHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.TrimSuffix(r.URL.Path, "/restaurar")
+		id, err := idDeRuta(r, "/products/")
+		if err != nil {
+			http.Error(w, "ID de producto inválido", http.StatusBadRequest)
+			return
 		}
 
+		producto, err := queries.RestaurarProd(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Producto no encontrado", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error al restaurar el producto: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if quiereJSON(r) {
+			escribirJSON(w, http.StatusOK, producto)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// handler para  /list-products (HTMX/templ). Acepta los filtros de la tienda
+// (q, min, max, categoria, stock y sort, ver catalogo.Leer) y devuelve una
+// página: despues y limite eligen cuál
+
+func ListProductsHandler(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		listado, err := listarProductos(r.Context(), db, catalogo.Leer(r.URL.Query()))
+		if errors.Is(err, catalogo.ErrCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Printf("Error al obtener productos para templ: %v", err)
 			http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		componente := views.ProductList(productos)
+		if quiereJSON(r) {
+			enlaceSiguiente(w, r, listado.Despues)
+			escribirJSON(w, http.StatusOK, listadoJSON(listado))
+			return
+		}
+
+		componente := views.ProductosTienda(listado)
 		componente.Render(r.Context(), w)
 	}
 }
 
-func ListProductsViewHandler(queries *sqlc.Queries) http.HandlerFunc {
+// ListProductsViewHandler es la lista del admin, con los mismos filtros que
+// la tienda (categoría, rango de precio y stock) salvo el buscador; con ?archivados=1 muestra los productos
+// archivados para restaurarlos. También pagina, y el final de cada página
+// pide la siguiente al aparecer en pantalla.
+func ListProductsViewHandler(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sortBy := r.URL.Query().Get("sort")
-		var (
-			productos []sqlc.Producto
-			err       error
-		)
-
-		switch sortBy {
-		case "price-asc":
-			productos, err = queries.ListProductsByPriceAsc(r.Context())
-		case "price-desc":
-			productos, err = queries.ListProductsByPriceDesc(r.Context())
-		default:
-			productos, err = queries.ListProd(r.Context())
+		f := catalogo.Leer(r.URL.Query())
+		archivados := r.URL.Query().Get("archivados") != ""
+		productos, despues, err := listarAdmin(r.Context(), db, f, archivados)
+		if errors.Is(err, catalogo.ErrCursor) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-
 		if err != nil {
 			log.Printf("Error al obtener productos para templ: %v", err)
 			http.Error(w, "Error al obtener productos: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		componente := views.ProductListDelete(productos)
+		if quiereJSON(r) {
+			enlaceSiguiente(w, r, despues)
+			escribirJSON(w, http.StatusOK, productos)
+			return
+		}
+
+		componente := views.ProductListDelete(productos, siguienteAdmin(f, archivados, despues))
 		componente.Render(r.Context(), w)
 	}
 }
 
 func LayoutHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		views.Layout().Render(r.Context(), w)
+		views.Layout(catalogo.Filtro{}, nil, views.Listado{}).Render(r.Context(), w)
 	}
 }
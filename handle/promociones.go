@@ -0,0 +1,296 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"carrito.com/categorias"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+	"carrito.com/promociones"
+	"carrito.com/views"
+)
+
+// promocionesCarrito evalúa las promociones activas sobre el carrito, en la
+// moneda de la sesión
+func promocionesCarrito(ctx context.Context, queries *sqlc.Queries, items []sqlc.GetCartItemsRow) (promociones.Resultado, error) {
+	lineas, err := promociones.DesdeCarrito(monedas.De(ctx), categorias.De(ctx), monedas.Actual(ctx), items)
+	if err != nil {
+		return promociones.Resultado{}, err
+	}
+	promos, err := queries.ListPromocionesActivas(ctx)
+	if err != nil {
+		return promociones.Resultado{}, err
+	}
+	return promociones.Evaluar(monedas.De(ctx), monedas.Actual(ctx), promos, lineas)
+}
+
+// AdminPromocionesHandler maneja /admin/promociones: listado y alta desde el formulario
+func AdminPromocionesHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			lista, err := queries.ListPromociones(r.Context()) // GET /admin/promociones
+			if err != nil {
+				http.Error(w, "Error al listar promociones: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			views.PromocionesAdmin(lista).Render(r.Context(), w)
+		case http.MethodPost:
+			createPromocionHandler(queries)(w, r) // POST /admin/promociones
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminPromocionHandler maneja /admin/promociones/{id}: POST .../activa
+// prende o apaga la promoción y DELETE la borra
+func AdminPromocionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/activa"):
+			r.URL.Path = strings.TrimSuffix(r.URL.Path, "/activa")
+			activarPromocionHandler(queries)(w, r) // POST /admin/promociones/{id}/activa
+		case r.Method == http.MethodDelete:
+			deletePromocionHandler(queries)(w, r) // DELETE /admin/promociones/{id}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func createPromocionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := promocionDelFormulario(r)
+		if err == nil {
+			err = promociones.Validar(monedas.De(r.Context()), &req)
+		}
+		if err != nil {
+			renderPromocionesAdmin(w, r, queries, err.Error())
+			return
+		}
+
+		if _, err := queries.CreatePromocion(r.Context(), req); err != nil {
+			renderPromocionesAdmin(w, r, queries, "No se pudo crear la promoción: "+err.Error())
+			return
+		}
+		renderPromocionesAdmin(w, r, queries, "")
+	}
+}
+
+// promocionDelFormulario lee los campos del alta; los que el tipo no usa
+// pueden venir vacíos
+func promocionDelFormulario(r *http.Request) (sqlc.CreatePromocionParams, error) {
+	p := sqlc.CreatePromocionParams{
+		Nombre: r.FormValue("nombre"),
+		Tipo:   r.FormValue("tipo"),
+		Moneda: r.FormValue("moneda"),
+		Activa: true,
+	}
+	if id, _ := strconv.Atoi(r.FormValue("id_producto")); id > 0 {
+		idProducto := int32(id)
+		p.IDProducto = &idProducto
+	}
+	idCategoria, err := categoriaRegla(r.Context(), idCategoriaDelForm(r), "")
+	if err != nil {
+		return p, err
+	}
+	p.IDCategoria = idCategoria
+	cantidad, _ := strconv.Atoi(r.FormValue("cantidad"))
+	paga, _ := strconv.Atoi(r.FormValue("paga"))
+	porcentaje, _ := strconv.Atoi(r.FormValue("porcentaje"))
+	p.Cantidad, p.Paga, p.Porcentaje = int32(cantidad), int32(paga), int32(porcentaje)
+
+	if minimo := r.FormValue("minimo"); minimo != "" {
+		m, err := dinero.Parse(minimo)
+		if err != nil {
+			return p, err
+		}
+		p.Minimo = m
+	}
+	return p, nil
+}
+
+func activarPromocionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/admin/promociones/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		promocion, err := queries.GetPromocion(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Promoción no encontrada", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error al leer la promoción: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, err = queries.UpdatePromocionActiva(r.Context(), sqlc.UpdatePromocionActivaParams{
+			IDPromocion: id,
+			Activa:      !promocion.Activa,
+		})
+		if err != nil {
+			http.Error(w, "Error al actualizar la promoción: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderPromocionesAdmin(w, r, queries, "")
+	}
+}
+
+func deletePromocionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/admin/promociones/")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := queries.DeletePromocion(r.Context(), id); err != nil {
+			http.Error(w, "Error al borrar la promoción: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderPromocionesAdmin(w, r, queries, "")
+	}
+}
+
+// renderPromocionesAdmin vuelve a dibujar la tabla después de un cambio
+func renderPromocionesAdmin(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, mensaje string) {
+	lista, err := queries.ListPromociones(r.Context())
+	if err != nil {
+		http.Error(w, "Error al listar promociones: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	views.PromocionesAdminTabla(lista, mensaje).Render(r.Context(), w)
+}
+
+// APIPromocionesHandler maneja /api/v1/promociones: GET lista y POST crea
+func APIPromocionesHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			lista, err := queries.ListPromociones(r.Context()) // GET /api/v1/promociones
+			if err != nil {
+				errorDB(w, err, "promoción")
+				return
+			}
+			escribirJSON(w, http.StatusOK, lista)
+		case http.MethodPost:
+			apiCreatePromocionHandler(queries)(w, r) // POST /api/v1/promociones
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APIPromocionHandler maneja /api/v1/promocion/{id}
+func APIPromocionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := idDeRuta(r, "/api/v1/promocion/")
+		if err != nil {
+			errorJSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			promocion, err := queries.GetPromocion(r.Context(), id) // GET /api/v1/promocion/{id}
+			if err != nil {
+				errorDB(w, err, "promoción")
+				return
+			}
+			escribirJSON(w, http.StatusOK, promocion)
+		case http.MethodPut:
+			apiUpdatePromocionHandler(queries, id)(w, r) // PUT /api/v1/promocion/{id}
+		case http.MethodDelete:
+			filas, err := queries.DeletePromocion(r.Context(), id) // DELETE /api/v1/promocion/{id}
+			if err != nil {
+				errorDB(w, err, "promoción")
+				return
+			}
+			if filas == 0 {
+				errorJSON(w, http.StatusNotFound, "promoción no encontrada")
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// promocionRequest es el body de POST /promociones y PUT /promocion/{id}: la
+// categoría va por id_categoria o por su slug o nombre en categoria
+type promocionRequest struct {
+	sqlc.CreatePromocionParams
+	Categoria string `json:"categoria"`
+}
+
+// leerPromocion lee y valida el body de un alta o modificación. Si el body no
+// dice "activa", la promoción queda activa.
+func leerPromocion(w http.ResponseWriter, r *http.Request) (sqlc.CreatePromocionParams, bool) {
+	req := promocionRequest{CreatePromocionParams: sqlc.CreatePromocionParams{Activa: true}}
+	if err := leerJSON(w, r, &req); err != nil {
+		errorLeerJSON(w, err)
+		return req.CreatePromocionParams, false
+	}
+	var err error
+	if req.IDCategoria, err = categoriaRegla(r.Context(), req.IDCategoria, req.Categoria); err == nil {
+		err = promociones.Validar(monedas.De(r.Context()), &req.CreatePromocionParams)
+	}
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err.Error())
+		return req.CreatePromocionParams, false
+	}
+	return req.CreatePromocionParams, true
+}
+
+func apiCreatePromocionHandler(queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := leerPromocion(w, r)
+		if !ok {
+			return
+		}
+
+		promocion, err := queries.CreatePromocion(r.Context(), req)
+		if err != nil {
+			errorDB(w, err, "promoción")
+			return
+		}
+		escribirJSON(w, http.StatusCreated, promocion)
+	}
+}
+
+func apiUpdatePromocionHandler(queries *sqlc.Queries, id int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := leerPromocion(w, r)
+		if !ok {
+			return
+		}
+
+		promocion, err := queries.UpdatePromocion(r.Context(), sqlc.UpdatePromocionParams{
+			IDPromocion: id,
+			Nombre:      req.Nombre,
+			Tipo:        req.Tipo,
+			IDCategoria: req.IDCategoria,
+			IDProducto:  req.IDProducto,
+			Cantidad:    req.Cantidad,
+			Paga:        req.Paga,
+			Porcentaje:  req.Porcentaje,
+			Minimo:      req.Minimo,
+			Moneda:      req.Moneda,
+			Activa:      req.Activa,
+		})
+		if err != nil {
+			errorDB(w, err, "promoción")
+			return
+		}
+		escribirJSON(w, http.StatusOK, promocion)
+	}
+}
@@ -0,0 +1,210 @@
+package handle
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/pedidos"
+)
+
+// lineaReembolso indica cuántas unidades de una línea del pedido se devuelven
+type lineaReembolso struct {
+	IDItem   int32 `json:"id_item"`
+	Cantidad int32 `json:"cantidad"`
+}
+
+// errReembolso es un reembolso que no se puede aplicar sobre el pedido tal como está
+type errReembolso struct {
+	motivo string
+}
+
+func (e errReembolso) Error() string {
+	return e.motivo
+}
+
+// reembolsarPedido devuelve dinero y stock de las líneas indicadas. Sin líneas
+// reembolsa todo lo que quede pendiente del pedido. Solo se puede reembolsar
+// un pedido cobrado; para uno pendiente corresponde cancelarlo. Si el pedido
+// se cobró por la pasarela, la devolución se le pide con el reembolso ya
+// guardado, y el reembolso devuelto dice en estado_pago cómo quedó.
+func reembolsarPedido(ctx context.Context, db *sql.DB, queries *sqlc.Queries, idPedido int32, lineas []lineaReembolso, motivo string, autor int32) (sqlc.Reembolso, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return sqlc.Reembolso{}, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	pedido, err := qtx.GetPedidoForUpdate(ctx, idPedido)
+	if err != nil {
+		return sqlc.Reembolso{}, err
+	}
+	if !pedidos.Estado(pedido.Estado).Cobrado() {
+		return sqlc.Reembolso{}, errReembolso{fmt.Sprintf("un pedido %s no admite reembolsos", pedido.Estado)}
+	}
+
+	items, err := qtx.ListItemsPedidoForUpdate(ctx, idPedido)
+	if err != nil {
+		return sqlc.Reembolso{}, err
+	}
+	if len(lineas) == 0 {
+		lineas = lineasPendientes(items)
+	}
+
+	reembolso, err := registrarReembolso(ctx, qtx, pedido, items, lineas, 0, motivo, autor)
+	if err != nil {
+		return sqlc.Reembolso{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return sqlc.Reembolso{}, err
+	}
+
+	if err := enviarReembolsos(ctx, db, queries, idPedido); err != nil {
+		log.Printf("Reembolsos del pedido %d pendientes en la pasarela: %v", idPedido, err)
+	}
+	return queries.GetReembolso(ctx, reembolso.IDReembolso)
+}
+
+// registrarReembolso valida las líneas contra los items bloqueados del pedido,
+// guarda el reembolso con su detalle, suma las unidades devueltas a cada línea
+// y al stock, y acumula el monto en el pedido. envio es el costo de envío que
+// se devuelve además de las líneas (solo al cancelar; si no, 0). Si el pedido
+// se cobró por la pasarela, la devolución queda pendiente y hay que pedirla
+// con enviarReembolsos después del commit. qtx tiene que estar en una
+// transacción.
+func registrarReembolso(ctx context.Context, qtx *sqlc.Queries, pedido sqlc.Pedido, items []sqlc.PedidoItem, lineas []lineaReembolso, envio dinero.Monto, motivo string, autor int32) (sqlc.Reembolso, error) {
+	porID := make(map[int32]sqlc.PedidoItem, len(items))
+	for _, item := range items {
+		porID[item.IDItem] = item
+	}
+
+	for _, l := range lineas {
+		if l.Cantidad < 1 {
+			return sqlc.Reembolso{}, errReembolso{"las cantidades a reembolsar deben ser positivas"}
+		}
+	}
+	// Juntamos líneas repetidas antes de validar contra lo que queda por reembolsar
+	cantidades := cantidadesPorItem(lineas)
+	if len(cantidades) == 0 && envio == 0 {
+		return sqlc.Reembolso{}, errReembolso{"no hay unidades para reembolsar"}
+	}
+
+	montos := make(map[int32]dinero.Monto, len(cantidades))
+	total := envio
+	for id, cantidad := range cantidades {
+		item, ok := porID[id]
+		if !ok {
+			return sqlc.Reembolso{}, errReembolso{fmt.Sprintf("la línea %d no pertenece al pedido", id)}
+		}
+		if cantidad > pedidos.Reembolsable(item) {
+			return sqlc.Reembolso{}, errReembolso{fmt.Sprintf("%s: quedan %d unidades por reembolsar", item.NombreProducto, pedidos.Reembolsable(item))}
+		}
+		montos[id] = pedidos.MontoReembolso(item, cantidad)
+		total += montos[id]
+	}
+
+	reembolso, err := qtx.CreateReembolso(ctx, sqlc.CreateReembolsoParams{
+		IDPedido:  pedido.IDPedido,
+		Monto:     total,
+		Motivo:    motivo,
+		IDUsuario: &autor,
+	})
+	if err != nil {
+		return sqlc.Reembolso{}, err
+	}
+
+	for _, item := range items {
+		cantidad, ok := cantidades[item.IDItem]
+		if !ok {
+			continue
+		}
+		err := qtx.CreateReembolsoItem(ctx, sqlc.CreateReembolsoItemParams{
+			IDReembolso: reembolso.IDReembolso,
+			IDItem:      item.IDItem,
+			Cantidad:    cantidad,
+			Monto:       montos[item.IDItem],
+		})
+		if err != nil {
+			return sqlc.Reembolso{}, err
+		}
+		err = qtx.SumarItemReembolsado(ctx, sqlc.SumarItemReembolsadoParams{
+			IDItem:              item.IDItem,
+			CantidadReembolsada: cantidad,
+		})
+		if err != nil {
+			return sqlc.Reembolso{}, err
+		}
+	}
+
+	if err := devolverStock(ctx, qtx, items, cantidades); err != nil {
+		return sqlc.Reembolso{}, err
+	}
+
+	_, err = qtx.SumarReembolsoPedido(ctx, sqlc.SumarReembolsoPedidoParams{
+		IDPedido:         pedido.IDPedido,
+		TotalReembolsado: reembolso.Monto,
+	})
+	if err != nil {
+		return sqlc.Reembolso{}, err
+	}
+	if err := reservarReembolso(ctx, qtx, reembolso); err != nil {
+		return sqlc.Reembolso{}, err
+	}
+	return reembolso, nil
+}
+
+// devolverStock suma al stock de cada producto las unidades devueltas de sus
+// líneas (cantidades va por id_item). Se actualiza en orden de producto, el
+// mismo en que el checkout los bloquea. Las líneas cuyo producto ya no existe
+// no devuelven nada.
+func devolverStock(ctx context.Context, qtx *sqlc.Queries, items []sqlc.PedidoItem, cantidades map[int32]int32) error {
+	porProducto := make(map[int32]int32)
+	ids := make([]int32, 0, len(items))
+	for _, item := range items {
+		cantidad := cantidades[item.IDItem]
+		if item.IDProducto == nil || cantidad == 0 {
+			continue
+		}
+		if _, ok := porProducto[*item.IDProducto]; !ok {
+			ids = append(ids, *item.IDProducto)
+		}
+		porProducto[*item.IDProducto] += cantidad
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		err := qtx.SumarStockProducto(ctx, sqlc.SumarStockProductoParams{
+			IDProducto: id,
+			Stock:      porProducto[id],
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineasPendientes arma un reembolso por todas las unidades que todavía no se devolvieron
+func lineasPendientes(items []sqlc.PedidoItem) []lineaReembolso {
+	lineas := make([]lineaReembolso, 0, len(items))
+	for _, item := range items {
+		if pendiente := pedidos.Reembolsable(item); pendiente > 0 {
+			lineas = append(lineas, lineaReembolso{IDItem: item.IDItem, Cantidad: pendiente})
+		}
+	}
+	return lineas
+}
+
+// cantidadesPorItem pasa las líneas a un mapa id_item -> cantidad
+func cantidadesPorItem(lineas []lineaReembolso) map[int32]int32 {
+	cantidades := make(map[int32]int32, len(lineas))
+	for _, l := range lineas {
+		cantidades[l.IDItem] += l.Cantidad
+	}
+	return cantidades
+}
@@ -0,0 +1,160 @@
+package handle
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	sqlc "carrito.com/db/sqlc"
+)
+
+const (
+	sessionCookie   = "session_token"
+	sesionDuracion  = 24 * time.Hour
+	tokenBytesLargo = 32
+)
+
+var errSinSesion = errors.New("no hay sesión activa")
+
+// CrearSesion genera un token aleatorio, guarda su hash en la tabla sesion
+// y lo envía al navegador en la cookie session_token.
+func CrearSesion(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries, usuario sqlc.Usuario) error {
+	token, expiration, err := nuevaSesion(r.Context(), queries, usuario)
+	if err != nil {
+		return err
+	}
+
+	cookie := http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Expires:  expiration,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	http.SetCookie(w, &cookie)
+	return nil
+}
+
+// nuevaSesion registra una sesión para el usuario y devuelve el token en claro.
+// Se usa tanto para la cookie del navegador como para el Bearer token de la API.
+func nuevaSesion(ctx context.Context, queries *sqlc.Queries, usuario sqlc.Usuario) (string, time.Time, error) {
+	token, err := generarToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	csrf, err := generarToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiration := time.Now().Add(sesionDuracion)
+
+	// Aprovechamos el login para limpiar sesiones vencidas
+	if err := queries.DeleteSesionesExpiradas(ctx); err != nil {
+		log.Printf("Error limpiando sesiones expiradas: %v", err)
+	}
+
+	err = queries.CreateSesion(ctx, sqlc.CreateSesionParams{
+		TokenHash: hashToken(token),
+		IDUsuario: usuario.IDUsuario,
+		CsrfToken: csrf,
+		Expira:    expiration,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiration, nil
+}
+
+// sesionActual resuelve el token (Bearer o cookie session_token) en el usuario dueño de la sesión
+// y su token CSRF. Devuelve errSinSesion si no hay cookie o la sesión no existe / expiró.
+func sesionActual(r *http.Request, queries *sqlc.Queries) (sqlc.GetUsuarioSesionRow, error) {
+	token := tokenDeSesion(r)
+	if token == "" {
+		return sqlc.GetUsuarioSesionRow{}, errSinSesion
+	}
+
+	sesion, err := queries.GetUsuarioSesion(r.Context(), hashToken(token))
+	if err != nil {
+		return sqlc.GetUsuarioSesionRow{}, errSinSesion
+	}
+	return sesion, nil
+}
+
+// cerrarSesion revoca la sesión actual en la base de datos y borra la cookie
+func cerrarSesion(w http.ResponseWriter, r *http.Request, queries *sqlc.Queries) error {
+	defer borrarCookieSesion(w)
+
+	token := tokenDeSesion(r)
+	if token == "" {
+		return nil
+	}
+	return queries.DeleteSesion(r.Context(), hashToken(token))
+}
+
+// tokenDeSesion prioriza el header Authorization: Bearer (clientes de la API)
+// y si no está usa la cookie del navegador.
+func tokenDeSesion(r *http.Request) string {
+	if token := tokenBearer(r); token != "" {
+		return token
+	}
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func tokenBearer(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func borrarCookieSesion(w http.ResponseWriter) {
+	cookie := http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Expires:  time.Now().Add(-1 * time.Hour),
+		Path:     "/",
+		HttpOnly: true,
+	}
+	http.SetCookie(w, &cookie)
+}
+
+// redirigir usa HX-Redirect para peticiones HTMX y un 303 para el resto
+func redirigir(w http.ResponseWriter, r *http.Request, url string) {
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", url)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
+
+func generarToken() (string, error) {
+	b := make([]byte, tokenBytesLargo)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// En la base solo se guarda el SHA-256 del token: si se filtra la tabla
+// sesion no se pueden reutilizar las cookies.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,92 +2,474 @@ package handle
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
-	"strconv"
+	"sort"
+	"strings"
 
+	"carrito.com/categorias"
+	"carrito.com/cupones"
 	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/envios"
+	"carrito.com/monedas"
+	"carrito.com/pedidos"
+	"carrito.com/promociones"
 	"carrito.com/views"
 )
 
-func SalesHandler(queries *sqlc.Queries) http.HandlerFunc {
+func SalesHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			listVentasHandler(queries)(w, r) // GET
 		case http.MethodPost:
-			createVentaHandler(queries)(w, r) // POST
+			createVentaHandler(db, queries)(w, r) // POST
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
 	}
 }
 
-func createVentaHandler(queries *sqlc.Queries) http.HandlerFunc {
+func createVentaHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_token")
-		if err != nil {
-			views.AlertError("Debes iniciar sesión para comprar").Render(r.Context(), w)
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
 			return
 		}
-		userID, err := strconv.Atoi(cookie.Value)
+		userID := usuario.IDUsuario
+
+		ctx := r.Context()
+		pedido, err := registrarCompra(ctx, db, queries, userID)
 		if err != nil {
-			views.AlertError("Debes iniciar sesión para comprar").Render(r.Context(), w)
+			var (
+				errStock errStockInsuficiente
+				errCupon cupones.ErrCupon
+				errEnvio envios.ErrEnvio
+			)
+			status, mensaje := http.StatusInternalServerError, "Error procesando la compra"
+			switch {
+			case errors.Is(err, errCarritoVacio):
+				status, mensaje = http.StatusBadRequest, "El carrito está vacío"
+			case errors.Is(err, errMonedaDesconocida):
+				status, mensaje = http.StatusBadRequest, "La moneda elegida ya no está disponible"
+			case errors.As(err, &errStock):
+				status, mensaje = http.StatusConflict, "No se pudo completar la compra: "+errStock.Error()
+			case errors.As(err, &errCupon):
+				status, mensaje = http.StatusConflict, "No se pudo completar la compra: "+errCupon.Error()
+			case errors.As(err, &errEnvio):
+				status, mensaje = http.StatusConflict, "No se pudo completar la compra: "+errEnvio.Error()
+			default:
+				log.Printf("Error procesando la compra: %v", err)
+			}
+
+			if quiereJSON(r) {
+				errorJSON(w, status, mensaje)
+				return
+			}
+			views.AlertError(mensaje).Render(ctx, w)
 			return
 		}
 
-		ctx := r.Context()
-		cartItems, err := queries.GetCartItems(ctx, int32(userID))
-		if err != nil || len(cartItems) == 0 {
-			views.AlertError("El carrito está vacío o hubo un error").Render(ctx, w)
+		pedido, pago := cobrarCompra(ctx, db, queries, pedido, userID)
+		if quiereJSON(r) {
+			escribirJSON(w, http.StatusCreated, pedido)
 			return
 		}
+		if pago != nil {
+			views.AlertPago(pago.Url).Render(ctx, w)
+			return
+		}
+		views.AlertSuccess("¡Compra realizada con éxito!").Render(ctx, w)
+	}
+}
 
-		for _, item := range cartItems {
-			precioFloat, _ := strconv.ParseFloat(item.Precio, 64)
-			totalLinea := precioFloat * float64(item.Cantidad)
+var (
+	errCarritoVacio      = errors.New("el carrito está vacío")
+	errMonedaDesconocida = errors.New("moneda desconocida")
+)
 
-			// Creamos la venta usando los parámetros de TU query
-			ventaParams := sqlc.CreateVentaParams{
-				IDProducto: item.IDProducto,
-				IDUsuario:  int32(userID),
-				Cantidad:   item.Cantidad,
-				Total:      fmt.Sprintf("%.2f", totalLinea),
-			}
+// errStockInsuficiente indica que una línea del carrito supera el stock disponible
+type errStockInsuficiente struct {
+	producto   string
+	disponible int32
+}
 
-			_, err := queries.CreateVenta(ctx, ventaParams)
-			if err != nil {
-				views.AlertError("Error procesando la compra").Render(ctx, w)
-				return
-			}
+func (e errStockInsuficiente) Error() string {
+	return fmt.Sprintf("stock insuficiente para %s (disponible: %d)", e.producto, e.disponible)
+}
+
+// lineaPedido es un producto y la cantidad pedida, antes de fijar su precio
+type lineaPedido struct {
+	IDProducto int32 `json:"id_producto"`
+	Cantidad   int32 `json:"cantidad"`
+}
+
+// registrarCompra convierte el carrito del usuario en un pedido y vacía el
+// carrito en una única transacción. Se cobra en la moneda que la sesión eligió
+// para ver los precios y se aplican el cupón y el envío que el usuario haya
+// elegido en el carrito. La usan tanto el checkout HTMX como la API JSON.
+// El comprador y su carrito quedan bloqueados hasta el commit: un checkout
+// repetido espera al primero y encuentra el carrito vacío, y solo se borran
+// las líneas que entraron en el pedido.
+func registrarCompra(ctx context.Context, db *sql.DB, queries *sqlc.Queries, userID int32) (pedidos.Detalle, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	if _, err := qtx.BloquearUsuario(ctx, userID); err != nil {
+		return pedidos.Detalle{}, err
+	}
+	cartItems, err := qtx.GetCartItemsForUpdate(ctx, userID)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+	lineas := make([]lineaPedido, len(cartItems))
+	comprados := make([]int32, len(cartItems))
+	for i, item := range cartItems {
+		lineas[i] = lineaPedido{IDProducto: item.IDProducto, Cantidad: item.Cantidad}
+		comprados[i] = item.IDItem
+	}
+
+	codigoCupon := ""
+	cupon, err := qtx.GetCarritoCupon(ctx, userID)
+	switch {
+	case err == nil:
+		codigoCupon = cupon.Codigo
+	case !errors.Is(err, sql.ErrNoRows):
+		return pedidos.Detalle{}, err
+	}
+
+	envio, err := seleccionDelCarrito(ctx, qtx, userID)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+
+	pedido, err := crearPedido(ctx, qtx, userID, monedas.Actual(ctx), codigoCupon, envio, lineas)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+	err = qtx.DeleteCartItems(ctx, sqlc.DeleteCartItemsParams{IDUsuario: userID, Ids: comprados})
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+	if err := qtx.DeleteCarritoCupon(ctx, userID); err != nil {
+		return pedidos.Detalle{}, err
+	}
+	if err := qtx.DeleteCarritoEnvio(ctx, userID); err != nil {
+		return pedidos.Detalle{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return pedidos.Detalle{}, err
+	}
+	return pedido, nil
+}
+
+// crearPedido guarda un pedido con una línea por producto y descuenta el stock.
+// Nombre y precio se copian a cada línea para que el historial no cambie si el
+// producto se edita después. Las filas de producto se bloquean con
+// SELECT ... FOR UPDATE para que dos compras simultáneas no vendan de más, así
+// que qtx tiene que estar dentro de una transacción.
+// Los precios se pasan a la moneda del pedido con las tasas vigentes, que
+// quedan guardadas en el pedido junto con la moneda. Después se aplican las
+// promociones activas y, si se indica un cupón, se bloquea su fila para contar
+// los usos sin carreras; es el mismo cálculo que muestra el carrito. Los
+// descuentos se restan del total y cada línea guarda su parte. El envío se
+// cotiza con el peso de los productos y su costo también va al total; el
+// pedido guarda el método y la dirección como texto, como hace con los
+// nombres de producto. Por último se calculan los impuestos según la
+// provincia de entrega, o la región del perfil si se retira: cada línea
+// guarda el suyo y los que no están incluidos en el precio se suman al total.
+func crearPedido(ctx context.Context, qtx *sqlc.Queries, userID int32, moneda, codigoCupon string, envio seleccionEnvio, lineas []lineaPedido) (pedidos.Detalle, error) {
+	if len(lineas) == 0 {
+		return pedidos.Detalle{}, errCarritoVacio
+	}
+	cotizaciones := monedas.De(ctx)
+	cobro, ok := cotizaciones.Buscar(moneda)
+	if !ok {
+		return pedidos.Detalle{}, errMonedaDesconocida
+	}
+
+	// Juntamos líneas repetidas y bloqueamos siempre en el mismo orden para
+	// evitar deadlocks entre compras
+	cantidades := make(map[int32]int32, len(lineas))
+	ids := make([]int32, 0, len(lineas))
+	for _, l := range lineas {
+		if _, ok := cantidades[l.IDProducto]; !ok {
+			ids = append(ids, l.IDProducto)
 		}
+		cantidades[l.IDProducto] += l.Cantidad
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
 
-		_ = queries.DeleteCart(ctx, int32(userID))
-		views.AlertSuccess("¡Compra realizada con éxito!").Render(ctx, w)
+	productos := make([]sqlc.Producto, 0, len(ids))
+	lineasPromo := make([]promociones.Linea, 0, len(ids))
+	var peso int64
+	for _, id := range ids {
+		producto, err := qtx.GetProdForUpdate(ctx, id)
+		if err != nil {
+			return pedidos.Detalle{}, err
+		}
+		if producto.Archivado {
+			// Se archivó después de cargarlo: para el comprador es lo mismo que quedarse sin stock
+			return pedidos.Detalle{}, errStockInsuficiente{producto: producto.NombreProducto}
+		}
+		if cantidades[id] > producto.Stock {
+			return pedidos.Detalle{}, errStockInsuficiente{producto: producto.NombreProducto, disponible: producto.Stock}
+		}
+		precio, err := cotizaciones.Convertir(producto.Precio, producto.Moneda, cobro.Codigo)
+		if err != nil {
+			return pedidos.Detalle{}, err
+		}
+		lineasPromo = append(lineasPromo, promociones.Linea{
+			IDProducto: id,
+			Nombre:     producto.NombreProducto,
+			Categorias: categorias.De(ctx).Ancestros(producto.IDCategoria),
+			Cantidad:   cantidades[id],
+			Precio:     precio,
+		})
+		productos = append(productos, producto)
+		peso += int64(producto.Peso) * int64(cantidades[id])
+	}
+
+	promos, err := qtx.ListPromocionesActivas(ctx)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+	conPromos, err := promociones.Evaluar(cotizaciones, cobro.Codigo, promos, lineasPromo)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+
+	aplicacion := cupones.Aplicacion{Subtotal: conPromos.Total(), PorLinea: make([]dinero.Monto, len(productos))}
+	var cuponPedido *string
+	if codigoCupon != "" {
+		cupon, err := qtx.GetCuponForUpdate(ctx, codigoCupon)
+		if errors.Is(err, sql.ErrNoRows) {
+			return pedidos.Detalle{}, cupones.ErrCupon{Motivo: "el cupón " + codigoCupon + " no existe"}
+		}
+		if err != nil {
+			return pedidos.Detalle{}, err
+		}
+		if aplicacion, err = aplicarCupon(ctx, qtx, cupon, userID, cobro.Codigo, lineasCupon(conPromos)); err != nil {
+			return pedidos.Detalle{}, err
+		}
+		cuponPedido = &cupon.Codigo
+	}
+
+	cotizacion, err := cotizarEnvio(ctx, qtx, userID, envio, peso, cobro.Codigo)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+
+	comprador, err := qtx.GetUser(ctx, userID)
+	if err != nil {
+		return pedidos.Detalle{}, err
 	}
+	region := regionFiscal(cotizacion, comprador.Region)
+	conImpuestos, err := impuestosCarrito(ctx, qtx, region, conPromos, &aplicacion)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+
+	params := sqlc.CreatePedidoParams{
+		IDUsuario:            userID,
+		Total:                aplicacion.Total() + conImpuestos.Adicional(),
+		Moneda:               cobro.Codigo,
+		Tasa:                 cobro.Tasa,
+		Cupon:                cuponPedido,
+		Descuento:            aplicacion.Descuento,
+		DescuentoPromociones: conPromos.Descuento(),
+		Region:               region,
+		Impuestos:            conImpuestos.Adicional(),
+	}
+	if cotizacion != nil {
+		params.Envio = cotizacion.Metodo.Nombre
+		params.DireccionEnvio = cotizacion.Destino()
+		params.CostoEnvio = cotizacion.Costo
+		params.Total += cotizacion.Costo
+	}
+	pedido, err := qtx.CreatePedido(ctx, params)
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+
+	items := make([]sqlc.PedidoItem, 0, len(productos))
+	for i, producto := range productos {
+		linea := conPromos.Lineas[i]
+		cantidad := linea.Cantidad
+
+		params := sqlc.CreatePedidoItemParams{
+			IDPedido:         pedido.IDPedido,
+			IDProducto:       &producto.IDProducto,
+			NombreProducto:   producto.NombreProducto,
+			PrecioUnitario:   linea.Precio,
+			Cantidad:         cantidad,
+			Subtotal:         linea.Subtotal(),
+			Descuento:        linea.Descuento + aplicacion.PorLinea[i],
+			Promociones:      promociones.Etiquetas(linea),
+			ImpuestoIncluido: true,
+		}
+		if calculo := conImpuestos.PorLinea[i]; calculo.Regla != nil {
+			params.ImpuestoNombre = calculo.Regla.Nombre
+			params.Alicuota = calculo.Regla.Alicuota
+			params.Impuesto = calculo.Monto
+			params.ImpuestoIncluido = calculo.Regla.Incluido
+		}
+		item, err := qtx.CreatePedidoItem(ctx, params)
+		if err != nil {
+			return pedidos.Detalle{}, err
+		}
+		items = append(items, item)
+
+		err = qtx.UpdateProductoStock(ctx, sqlc.UpdateProductoStockParams{
+			IDProducto: producto.IDProducto,
+			Stock:      producto.Stock - cantidad,
+		})
+		if err != nil {
+			return pedidos.Detalle{}, err
+		}
+	}
+
+	alta, err := qtx.CreatePedidoHistorial(ctx, sqlc.CreatePedidoHistorialParams{
+		IDPedido:  pedido.IDPedido,
+		Estado:    pedido.Estado,
+		IDUsuario: &userID,
+	})
+	if err != nil {
+		return pedidos.Detalle{}, err
+	}
+	return pedidos.Detalle{
+		Pedido:     pedido,
+		Items:      items,
+		Historial:  []sqlc.PedidoHistorial{alta},
+		Reembolsos: []sqlc.Reembolso{},
+		Pagos:      []sqlc.Pago{},
+	}, nil
+}
+
+// detallesPedidos completa una lista de pedidos con sus líneas, su historial,
+// sus reembolsos y sus pagos, con una query para cada uno en lugar de una por
+// pedido
+func detallesPedidos(ctx context.Context, queries *sqlc.Queries, lista []sqlc.Pedido) ([]pedidos.Detalle, error) {
+	ids := pedidos.IDs(lista)
+	items, err := queries.ListItemsDePedidos(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	historial, err := queries.ListHistorialDePedidos(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	reembolsos, err := queries.ListReembolsosDePedidos(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	pagos, err := queries.ListPagosDePedidos(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return pedidos.Agrupar(lista, items, historial, reembolsos, pagos), nil
 }
 
-// Venta: GET /sales (Lista TODAS las ventas)
+// Venta: GET /sales (pedidos del usuario con sus líneas)
 func listVentasHandler(queries *sqlc.Queries) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		cookie, err := r.Cookie("session_token")
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
+			return
+		}
+		userID := usuario.IDUsuario
+
+		lista, err := queries.ListPedidosUsuario(r.Context(), userID)
 		if err != nil {
-			views.AlertError("Debes iniciar sesión para comprar").Render(r.Context(), w)
+			http.Error(w, "Error al listar pedidos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ventas, err := detallesPedidos(r.Context(), queries, lista)
+		if err != nil {
+			http.Error(w, "Error al listar pedidos: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if quiereJSON(r) {
+			escribirJSON(w, http.StatusOK, ventas)
+			return
+		}
+		views.SalesList(ventas).Render(r.Context(), w)
+	}
+}
+
+// SaleHandler maneja las acciones del cliente sobre uno de sus pedidos
+func SaleHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/cancelar") {
+			cancelarPedidoHandler(db, queries)(w, r) // POST /sales/{id}/cancelar
+			return
+		}
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/pagar") {
+			pagarPedidoHandler(db, queries)(w, r) // POST /sales/{id}/pagar
+			return
+		}
+		http.NotFound(w, r)
+	}
+}
+
+// cancelarPedidoHandler cancela un pedido propio que todavía no se envió y
+// devuelve su tarjeta actualizada
+func cancelarPedidoHandler(db *sql.DB, queries *sqlc.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		usuario, ok := usuarioActual(w, r)
+		if !ok {
 			return
 		}
-		userID, err := strconv.Atoi(cookie.Value)
+
+		r.URL.Path = strings.TrimSuffix(r.URL.Path, "/cancelar")
+		id, err := idDeRuta(r, "/sales/")
 		if err != nil {
-			views.AlertError("Debes iniciar sesión para comprar").Render(r.Context(), w)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		ventas, err := queries.ListVentasUsuario(context.Background(), int32(userID))
+		ctx := r.Context()
+		pedido, err := queries.GetPedido(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && pedido.IDUsuario != usuario.IDUsuario) {
+			// Un pedido ajeno se trata igual que uno inexistente
+			http.Error(w, "Pedido no encontrado", http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Error al listar ventas: "+err.Error(), http.StatusInternalServerError)
+			http.Error(w, "Error al leer el pedido: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		views.SalesList(ventas).Render(r.Context(), w)
+		mensaje := ""
+		pedido, err = cambiarEstadoPedido(ctx, db, queries, id, pedidos.EstadoCancelado, usuario.IDUsuario)
+		if err != nil {
+			var errTransicion pedidos.ErrTransicion
+			if !errors.As(err, &errTransicion) {
+				http.Error(w, "Error al cancelar el pedido: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			mensaje = "No se pudo cancelar: " + err.Error()
+			if pedido, err = queries.GetPedido(ctx, id); err != nil {
+				http.Error(w, "Error al leer el pedido: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		detalles, err := detallesPedidos(ctx, queries, []sqlc.Pedido{pedido})
+		if err != nil {
+			http.Error(w, "Error al leer el pedido: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		views.PedidoCliente(detalles[0], mensaje).Render(ctx, w)
 	}
 }
@@ -0,0 +1,178 @@
+// Package impuestos calcula los impuestos (IVA) de cada línea según la
+// categoría del producto y la región del comprador, y arma el reporte por
+// período para la contabilidad.
+package impuestos
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+)
+
+// Linea es lo que se cobra por una línea, ya con promociones y cupón.
+// Categorias son la categoría del producto y sus ancestros, como los devuelve
+// categorias.Arbol.Ancestros.
+type Linea struct {
+	Categorias []int32
+	Total      dinero.Monto
+}
+
+// Calculo es el impuesto de una línea. Regla es nil si ninguna alícuota aplica.
+type Calculo struct {
+	Regla *sqlc.Impuesto
+	Monto dinero.Monto
+}
+
+// Adicional es lo que el impuesto suma al total: cero si ya estaba incluido en el precio
+func (c Calculo) Adicional() dinero.Monto {
+	if c.Regla == nil || c.Regla.Incluido {
+		return 0
+	}
+	return c.Monto
+}
+
+// Resumen es una línea de impuestos del carrito o del pedido: lo que suma un
+// mismo impuesto entre todas las líneas que lo llevan
+type Resumen struct {
+	Nombre   string            `json:"nombre"`
+	Alicuota dinero.Porcentaje `json:"alicuota"`
+	Incluido bool              `json:"incluido"`
+	Base     dinero.Monto      `json:"base"`
+	Monto    dinero.Monto      `json:"monto"`
+}
+
+// Resultado son los impuestos de un carrito: uno por línea, en el mismo
+// orden, y el resumen por impuesto
+type Resultado struct {
+	PorLinea []Calculo
+	Resumen  []Resumen
+}
+
+// Adicional suma los impuestos que no estaban incluidos en los precios
+func (r Resultado) Adicional() dinero.Monto {
+	var total dinero.Monto
+	for _, c := range r.PorLinea {
+		total += c.Adicional()
+	}
+	return total
+}
+
+// Buscar devuelve la regla más específica para un producto, dado por su
+// categoría y sus ancestros, y una región, o nil si no hay ninguna. Una regla
+// de categoría vale para sus subcategorías y pesa más cuanto más cerca esté
+// de la del producto; cualquier regla con categoría pesa más que una con
+// solo región, y a igual categoría la que también tiene región gana.
+func Buscar(reglas []sqlc.Impuesto, categorias []int32, region string) *sqlc.Impuesto {
+	var (
+		elegida *sqlc.Impuesto
+		puntaje = -1
+	)
+	for i, r := range reglas {
+		p := 0
+		if r.IDCategoria != nil {
+			nivel := slices.Index(categorias, *r.IDCategoria)
+			if nivel < 0 {
+				continue
+			}
+			p += 2 * (len(categorias) - nivel)
+		}
+		if r.Region != nil {
+			if !strings.EqualFold(*r.Region, region) {
+				continue
+			}
+			p++
+		}
+		if p > puntaje {
+			elegida, puntaje = &reglas[i], p
+		}
+	}
+	return elegida
+}
+
+// Calcular aplica a cada línea la regla que le corresponde. Si la alícuota
+// está incluida, el impuesto se discrimina del total de la línea; si no, se
+// calcula sobre ese total y se suma aparte.
+func Calcular(reglas []sqlc.Impuesto, region string, lineas []Linea) Resultado {
+	res := Resultado{PorLinea: make([]Calculo, len(lineas)), Resumen: []Resumen{}}
+	for i, l := range lineas {
+		regla := Buscar(reglas, l.Categorias, region)
+		if regla == nil {
+			continue
+		}
+		c := Calculo{Regla: regla}
+		base := l.Total
+		if regla.Incluido {
+			c.Monto = l.Total.Incluido(regla.Alicuota)
+			base -= c.Monto
+		} else {
+			c.Monto = l.Total.Aplicar(regla.Alicuota)
+		}
+		res.PorLinea[i] = c
+		res.Resumen = sumar(res.Resumen, Resumen{
+			Nombre:   regla.Nombre,
+			Alicuota: regla.Alicuota,
+			Incluido: regla.Incluido,
+			Base:     base,
+			Monto:    c.Monto,
+		})
+	}
+	return res
+}
+
+// DePedido arma el resumen de impuestos a partir de lo guardado en cada línea
+func DePedido(items []sqlc.PedidoItem) []Resumen {
+	resumen := []Resumen{}
+	for _, item := range items {
+		if item.ImpuestoNombre == "" {
+			continue
+		}
+		base := item.Subtotal - item.Descuento
+		if item.ImpuestoIncluido {
+			base -= item.Impuesto
+		}
+		resumen = sumar(resumen, Resumen{
+			Nombre:   item.ImpuestoNombre,
+			Alicuota: item.Alicuota,
+			Incluido: item.ImpuestoIncluido,
+			Base:     base,
+			Monto:    item.Impuesto,
+		})
+	}
+	return resumen
+}
+
+// sumar acumula r en la línea del mismo impuesto o la agrega al final
+func sumar(resumen []Resumen, r Resumen) []Resumen {
+	for i := range resumen {
+		if resumen[i].Nombre == r.Nombre && resumen[i].Alicuota == r.Alicuota && resumen[i].Incluido == r.Incluido {
+			resumen[i].Base += r.Base
+			resumen[i].Monto += r.Monto
+			return resumen
+		}
+	}
+	return append(resumen, r)
+}
+
+// Validar normaliza y revisa una alícuota antes de guardarla. Sin categoría o
+// con región vacía se guardan como NULL, es decir, valen para cualquiera.
+func Validar(p *sqlc.CreateImpuestoParams) error {
+	p.Nombre = strings.TrimSpace(p.Nombre)
+	if p.Nombre == "" {
+		return errors.New("el nombre es requerido: es la línea que ve el cliente (IVA 21%)")
+	}
+	if p.Alicuota <= 0 || p.Alicuota > dinero.Cien {
+		return errors.New("la alícuota tiene que ser mayor a 0 y hasta 100")
+	}
+	if p.IDCategoria != nil && *p.IDCategoria <= 0 {
+		p.IDCategoria = nil
+	}
+	if p.Region != nil {
+		if *p.Region = strings.TrimSpace(*p.Region); *p.Region == "" {
+			p.Region = nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package impuestos
+
+import (
+	"slices"
+	"testing"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+)
+
+func regla(id int32, alicuota dinero.Porcentaje, categoria int32, region string, incluido bool) sqlc.Impuesto {
+	r := sqlc.Impuesto{IDImpuesto: id, Nombre: "impuesto", Alicuota: alicuota, Incluido: incluido}
+	if categoria != 0 {
+		r.IDCategoria = &categoria
+	}
+	if region != "" {
+		r.Region = &region
+	}
+	return r
+}
+
+// Periféricos (2) tiene como subcategoría a mouses (5)
+var reglas = []sqlc.Impuesto{
+	regla(1, 2100, 0, "", true),
+	regla(2, 500, 0, "Tierra del Fuego", true),
+	regla(3, 1050, 2, "", true),
+	regla(4, 800, 5, "", true),
+	regla(5, 900, 2, "Córdoba", true),
+}
+
+func TestBuscar(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		categorias []int32
+		region     string
+		regla      int32
+	}{
+		{"sin categoría ni región usa la general", nil, "", 1},
+		{"una categoría sin regla usa la general", []int32{7}, "Salta", 1},
+		{"regla de solo región, sin importar mayúsculas", nil, "tierra del fuego", 2},
+		{"la categoría pesa más que la región sola", []int32{2}, "Tierra del Fuego", 3},
+		{"una subcategoría hereda la regla de la categoría", []int32{6, 2}, "", 3},
+		{"la categoría más cercana gana a la más lejana", []int32{5, 2}, "", 4},
+		{"categoría y región ganan a la categoría sola", []int32{2}, "Córdoba", 5},
+		{"la categoría más cercana gana aunque la lejana tenga región", []int32{5, 2}, "Córdoba", 4},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			r := Buscar(reglas, c.categorias, c.region)
+			if r == nil || r.IDImpuesto != c.regla {
+				t.Fatalf("Buscar = %v; se esperaba la regla %d", r, c.regla)
+			}
+		})
+	}
+
+	if r := Buscar(reglas[2:], nil, "Salta"); r != nil {
+		t.Errorf("sin regla general Buscar = %d; se esperaba nil", r.IDImpuesto)
+	}
+}
+
+func TestCalcular(t *testing.T) {
+	iva := regla(1, 2100, 0, "", true)
+	iva.Nombre = "IVA 21%"
+	agregado := regla(2, 2100, 2, "", false)
+	agregado.Nombre = "IVA 21% agregado"
+	todas := []sqlc.Impuesto{iva, agregado}
+
+	res := Calcular(todas, "", []Linea{
+		{Total: 12100},
+		{Categorias: []int32{2}, Total: 10000},
+		{Total: 1000},
+		{Categorias: []int32{2}, Total: 0},
+	})
+
+	montos := make([]dinero.Monto, len(res.PorLinea))
+	for i, c := range res.PorLinea {
+		montos[i] = c.Monto
+	}
+	// Incluido: se discrimina de 121 el 21 que ya tenía; agregado: 21 sobre 100
+	if esp := []dinero.Monto{2100, 2100, 174, 0}; !slices.Equal(montos, esp) {
+		t.Fatalf("montos %v; se esperaba %v", montos, esp)
+	}
+	if res.Adicional() != 2100 {
+		t.Errorf("Adicional = %d; se esperaba solo el impuesto agregado (2100)", res.Adicional())
+	}
+
+	esp := []Resumen{
+		{Nombre: "IVA 21%", Alicuota: 2100, Incluido: true, Base: 10000 + 826, Monto: 2100 + 174},
+		{Nombre: "IVA 21% agregado", Alicuota: 2100, Incluido: false, Base: 10000, Monto: 2100},
+	}
+	if !slices.Equal(res.Resumen, esp) {
+		t.Errorf("resumen %+v; se esperaba %+v", res.Resumen, esp)
+	}
+
+	if sin := Calcular(nil, "", []Linea{{Total: 1000}}); sin.PorLinea[0].Regla != nil || len(sin.Resumen) != 0 {
+		t.Errorf("sin reglas Calcular = %+v; se esperaba sin impuestos", sin)
+	}
+}
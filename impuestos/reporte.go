@@ -0,0 +1,148 @@
+package impuestos
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+// FilaReporte es lo que suma un impuesto en una región y una moneda durante
+// el período. Los importes descuentan las unidades reembolsadas; BasePesos e
+// ImpuestoPesos están pasados a la moneda base con la tasa de cada pedido.
+type FilaReporte struct {
+	Region        string            `json:"region"`
+	Nombre        string            `json:"nombre"`
+	Alicuota      dinero.Porcentaje `json:"alicuota"`
+	Incluido      bool              `json:"incluido"`
+	Moneda        string            `json:"moneda"`
+	Pedidos       int               `json:"pedidos"`
+	Base          dinero.Monto      `json:"base"`
+	Impuesto      dinero.Monto      `json:"impuesto"`
+	BasePesos     dinero.Monto      `json:"base_pesos"`
+	ImpuestoPesos dinero.Monto      `json:"impuesto_pesos"`
+}
+
+// Reporte es el resumen de impuestos de los pedidos cobrados entre Desde y
+// Hasta, ambos días incluidos
+type Reporte struct {
+	Desde         time.Time     `json:"desde"`
+	Hasta         time.Time     `json:"hasta"`
+	Filas         []FilaReporte `json:"filas"`
+	BasePesos     dinero.Monto  `json:"base_pesos"`
+	ImpuestoPesos dinero.Monto  `json:"impuesto_pesos"`
+}
+
+// FormatoFecha es el formato de los parámetros desde y hasta
+const FormatoFecha = "2006-01-02"
+
+// Periodo interpreta las fechas del reporte. Sin desde arranca el primer día
+// del mes de ahora y sin hasta termina hoy.
+func Periodo(desde, hasta string, ahora time.Time) (time.Time, time.Time, error) {
+	d := time.Date(ahora.Year(), ahora.Month(), 1, 0, 0, 0, 0, ahora.Location())
+	h := time.Date(ahora.Year(), ahora.Month(), ahora.Day(), 0, 0, 0, 0, ahora.Location())
+	var err error
+	if desde != "" {
+		if d, err = time.ParseInLocation(FormatoFecha, desde, ahora.Location()); err != nil {
+			return d, h, errors.New("desde inválido: se espera AAAA-MM-DD")
+		}
+	}
+	if hasta != "" {
+		if h, err = time.ParseInLocation(FormatoFecha, hasta, ahora.Location()); err != nil {
+			return d, h, errors.New("hasta inválido: se espera AAAA-MM-DD")
+		}
+	}
+	if h.Before(d) {
+		return d, h, errors.New("hasta no puede ser anterior a desde")
+	}
+	return d, h, nil
+}
+
+// Params devuelve el rango para ListItemsImpuestos: hasta se corre al día
+// siguiente porque la query lo excluye
+func (r Reporte) Params() sqlc.ListItemsImpuestosParams {
+	return sqlc.ListItemsImpuestosParams{Desde: r.Desde, Hasta: r.Hasta.AddDate(0, 0, 1)}
+}
+
+// Armar agrupa las líneas por región, impuesto y moneda
+func (r *Reporte) Armar(items []sqlc.ListItemsImpuestosRow) {
+	type clave struct {
+		region, nombre string
+		alicuota       dinero.Porcentaje
+		incluido       bool
+		moneda         string
+	}
+	filas := map[clave]*FilaReporte{}
+	pedidos := map[clave]map[int32]bool{}
+
+	for _, row := range items {
+		item := row.PedidoItem
+		k := clave{row.Region, item.ImpuestoNombre, item.Alicuota, item.ImpuestoIncluido, row.Moneda}
+		f, ok := filas[k]
+		if !ok {
+			f = &FilaReporte{Region: k.region, Nombre: k.nombre, Alicuota: k.alicuota, Incluido: k.incluido, Moneda: k.moneda}
+			filas[k], pedidos[k] = f, map[int32]bool{}
+		}
+
+		base := item.Subtotal - item.Descuento
+		if item.ImpuestoIncluido {
+			base -= item.Impuesto
+		}
+		// Lo reembolsado se descuenta igual que lo calcula MontoReembolso
+		base -= base.Proporcion(int64(item.CantidadReembolsada), int64(item.Cantidad))
+		impuesto := item.Impuesto - item.Impuesto.Proporcion(int64(item.CantidadReembolsada), int64(item.Cantidad))
+
+		f.Base += base
+		f.Impuesto += impuesto
+		f.BasePesos += dinero.Convertir(base, row.Tasa, dinero.TasaUno)
+		f.ImpuestoPesos += dinero.Convertir(impuesto, row.Tasa, dinero.TasaUno)
+		pedidos[k][item.IDPedido] = true
+	}
+
+	r.Filas = make([]FilaReporte, 0, len(filas))
+	r.BasePesos, r.ImpuestoPesos = 0, 0
+	for k, f := range filas {
+		f.Pedidos = len(pedidos[k])
+		r.Filas = append(r.Filas, *f)
+		r.BasePesos += f.BasePesos
+		r.ImpuestoPesos += f.ImpuestoPesos
+	}
+	sort.Slice(r.Filas, func(i, j int) bool {
+		a, b := r.Filas[i], r.Filas[j]
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.Nombre != b.Nombre {
+			return a.Nombre < b.Nombre
+		}
+		if a.Incluido != b.Incluido {
+			return a.Incluido
+		}
+		return a.Moneda < b.Moneda
+	})
+}
+
+// EscribirCSV vuelca el reporte con una fila por impuesto, región y moneda,
+// con punto decimal para que lo lea cualquier planilla
+func EscribirCSV(w io.Writer, r Reporte) error {
+	escritor := csv.NewWriter(w)
+	escritor.Write([]string{
+		"region", "impuesto", "alicuota", "incluido", "moneda", "pedidos",
+		"base", "impuesto", "base_" + monedas.Base, "impuesto_" + monedas.Base,
+	})
+	for _, f := range r.Filas {
+		escritor.Write([]string{
+			f.Region, f.Nombre, f.Alicuota.String(), strconv.FormatBool(f.Incluido), f.Moneda,
+			strconv.Itoa(f.Pedidos), f.Base.String(), f.Impuesto.String(),
+			f.BasePesos.String(), f.ImpuestoPesos.String(),
+		})
+	}
+	escritor.Flush()
+	return escritor.Error()
+}
@@ -1,21 +1,38 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 
+	"carrito.com/auth"
+	"carrito.com/categorias"
 	sqlc "carrito.com/db/sqlc" // generado por sqlc
 	"carrito.com/handle"
+	"carrito.com/monedas"
+	"carrito.com/pagos"
 	_ "github.com/lib/pq"
 )
 
 func main() {
+	adminEmail := flag.String("crear-admin", "", "email del usuario a crear o promover como administrador")
+	adminPassword := flag.String("password", "", "contraseña para el administrador (obligatoria si el usuario no existe)")
+	adminNombre := flag.String("nombre", "Administrador", "nombre del administrador si hay que crearlo")
+	csvMonedas := flag.String("importar-monedas", "", "archivo CSV (codigo,nombre,simbolo,tasa) con monedas a crear o actualizar")
+	flag.Parse()
 
 	mux := http.NewServeMux()
 
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	// Frontend de prueba de la API JSON (mismo origen, sin CORS)
+	mux.Handle("/prueba/", http.StripPrefix("/prueba/", http.FileServer(http.Dir("Prueba"))))
 
 	// Página /about
 
@@ -29,27 +46,234 @@ func main() {
 
 	queries := sqlc.New(db)
 
+	// Alta del primer administrador: ./carrito -crear-admin email -password xxx
+	if *adminEmail != "" {
+		if err := crearAdmin(context.Background(), queries, *adminEmail, *adminPassword, *adminNombre); err != nil {
+			log.Fatalf("no se pudo crear el administrador: %v", err)
+		}
+		fmt.Printf("Usuario %s ahora es administrador\n", *adminEmail)
+		return
+	}
+
+	// Carga de tipos de cambio: ./carrito -importar-monedas tasas.csv
+	if *csvMonedas != "" {
+		n, err := importarMonedas(context.Background(), db, queries, *csvMonedas)
+		if err != nil {
+			log.Fatalf("no se pudieron importar las monedas: %v", err)
+		}
+		fmt.Printf("%d monedas importadas\n", n)
+		return
+	}
+
+	// Las tasas se leen una vez y quedan en memoria; el admin las recarga al editarlas
+	cotizaciones, err := monedas.Cargar(context.Background(), queries)
+	if err != nil {
+		log.Fatalf("no se pudieron cargar las monedas: %v", err)
+	}
+	// Lo mismo con el árbol de categorías, que se recarga al cambiar una categoría
+	arbol, err := categorias.Cargar(context.Background(), queries)
+	if err != nil {
+		log.Fatalf("no se pudieron cargar las categorías: %v", err)
+	}
+
+	// Pasarelas de pago; PAGOS_PROVEEDOR elige con cuál se cobra. La falsa
+	// deja que el comprador apruebe su propio pago, así que ella y sus rutas
+	// solo existen con PAGOS_FAKE=1, para probar el circuito en local.
+	var fake *pagos.Fake
+	if os.Getenv("PAGOS_FAKE") == "1" {
+		secretoFake := os.Getenv("PAGOS_FAKE_SECRETO")
+		if secretoFake == "" {
+			if secretoFake, err = secretoAleatorio(); err != nil {
+				log.Fatalf("no se pudo generar el secreto del proveedor de pagos: %v", err)
+			}
+		}
+		fake = pagos.NuevoFake(secretoFake)
+		pagos.Registrar(fake)
+		log.Println("PAGOS_FAKE=1: los pedidos se cobran con el proveedor falso, que no cobra nada")
+	}
+	if nombre := os.Getenv("PAGOS_PROVEEDOR"); nombre != "" {
+		if err := pagos.Usar(nombre); err != nil {
+			log.Fatalf("PAGOS_PROVEEDOR: %v", err)
+		}
+	}
+	if _, ok := pagos.Predeterminado(); !ok {
+		log.Fatal("no hay ningún proveedor de pagos configurado; para pruebas locales se puede usar PAGOS_FAKE=1")
+	}
+	// Los reembolsos que quedaron sin respuesta de la pasarela se vuelven a pedir
+	handle.ReintentarReembolsos(context.Background(), db, queries)
+
+	// Rutas públicas: no requieren sesión
+	publica := mux.HandleFunc
+	// Rutas protegidas: RequireAuth carga el usuario en el contexto o corta con 401,
+	// y RequireCSRF valida el token en POST/PUT/DELETE
+	protegida := func(patron string, h http.HandlerFunc) {
+		mux.Handle(patron, handle.RequireAuth(queries, handle.RequireCSRF(h)))
+	}
+	// Rutas de administración: además de sesión exigen un permiso del rol
+	admin := func(patron string, permiso auth.Permiso, h http.HandlerFunc) {
+		mux.Handle(patron, handle.RequireAuth(queries, handle.RequireCSRF(handle.RequirePermiso(permiso, h))))
+	}
+
 	//Rutas
-	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+	publica("/about", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "about.html")
 	})
-	mux.HandleFunc("/", handle.IndexPageHandler(queries))
-	mux.HandleFunc("/login", handle.LoginHandler(queries))
-	mux.HandleFunc("/register", handle.RegisterHandler(queries))
-	mux.HandleFunc("/logout", handle.LogoutHandler())
-	mux.HandleFunc("/products", handle.ProductsHandler(queries))
-	mux.HandleFunc("/products/", handle.ProductHandler(queries))
-	mux.HandleFunc("/carrito", handle.CartHandler(queries))
-	mux.HandleFunc("/carrito/items/", handle.CartItemHandler(queries))
-	mux.HandleFunc("/list-products", handle.ListProductsHandler(queries))
-	mux.HandleFunc("/list-products-view", handle.ListProductsViewHandler(queries))
-	mux.HandleFunc("/sales", handle.SalesHandler(queries))
+	publica("/login", handle.LoginHandler(queries))
+	publica("/register", handle.RegisterHandler(queries))
+	publica("/logout", handle.LogoutHandler(queries))
+	// El listado es público pero, si hay sesión, muestra los precios en su moneda
+	mux.Handle("/list-products", handle.SesionOpcional(queries, handle.ListProductsHandler(db)))
+
+	protegida("/", handle.IndexPageHandler(db, queries))
+	protegida("/logout/todas", handle.LogoutAllHandler(queries))
+	protegida("/carrito", handle.CartHandler(queries))
+	protegida("/carrito/items/", handle.CartItemHandler(queries))
+	protegida("/carrito/cupon", handle.CartCuponHandler(queries))
+	protegida("/carrito/region", handle.CartRegionHandler(queries))
+	protegida("/carrito/envio", handle.CartEnvioHandler(queries))
+	protegida("/direcciones", handle.DireccionesHandler(queries))
+	protegida("/direcciones/", handle.DireccionHandler(queries))
+	protegida("/sales", handle.SalesHandler(db, queries))
+	protegida("/sales/", handle.SaleHandler(db, queries))
+	protegida("/moneda", handle.MonedaHandler(queries))
+	protegida("/categoria/", handle.CategoriaHandler(db))
+
+	admin("/products", auth.PermisoProductos, handle.ProductsHandler(db, queries))
+	admin("/products/", auth.PermisoProductos, handle.ProductHandler(db, queries))
+	admin("/list-products-view", auth.PermisoProductos, handle.ListProductsViewHandler(db))
+	admin("/admin/pedidos", auth.PermisoVentas, handle.AdminPedidosHandler(queries))
+	admin("/admin/pedidos/", auth.PermisoVentas, handle.AdminPedidoHandler(db, queries))
+	admin("/admin/promociones", auth.PermisoVentas, handle.AdminPromocionesHandler(queries))
+	admin("/admin/promociones/", auth.PermisoVentas, handle.AdminPromocionHandler(queries))
+	admin("/admin/impuestos", auth.PermisoImpuestos, handle.AdminImpuestosHandler(queries))
+	admin("/admin/impuestos/", auth.PermisoImpuestos, handle.AdminImpuestoHandler(queries))
+	admin("/admin/impuestos/reporte", auth.PermisoVentas, handle.ReporteImpuestosHandler(queries))
+	admin("/admin/envios", auth.PermisoVentas, handle.AdminEnviosHandler(queries))
+	admin("/admin/envios/", auth.PermisoVentas, handle.AdminEnvioHandler(queries))
+	admin("/admin/categorias", auth.PermisoProductos, handle.AdminCategoriasHandler(db, queries))
+	admin("/admin/categorias/", auth.PermisoProductos, handle.AdminCategoriaHandler(db, queries))
+	admin("/admin/monedas", auth.PermisoMonedas, handle.AdminMonedasHandler(db, queries))
+	admin("/admin/monedas/", auth.PermisoMonedas, handle.AdminMonedaHandler(db, queries))
+
+	// API JSON versionada. Los clientes se autentican con POST /api/v1/login
+	// y mandan Authorization: Bearer <token>.
+	publica("/api/v1/login", handle.APILoginHandler(queries))
+	// Las pasarelas no tienen sesión: el webhook se autentica con su firma
+	publica("/api/v1/pagos/webhook/", handle.WebhookPagosHandler(db, queries))
+	protegida("/api/v1/logout", handle.APILogoutHandler(queries))
+	protegida("/api/v1/products", handle.APIProductsHandler(db, queries))
+	protegida("/api/v1/product/", handle.APIProductHandler(db, queries))
+	protegida("/api/v1/cart", handle.APICartHandler(queries))
+	protegida("/api/v1/cart/items", handle.APICartItemsHandler(queries))
+	protegida("/api/v1/cart/items/", handle.APICartItemsHandler(queries))
+	protegida("/api/v1/cart/checkout", handle.APICheckoutHandler(db, queries))
+	protegida("/api/v1/cart/cupon", handle.APICartCuponHandler(queries))
+	protegida("/api/v1/cart/envio", handle.APICartEnvioHandler(queries))
+	protegida("/api/v1/monedas", handle.APIMonedasHandler())
+	protegida("/api/v1/categorias", handle.APICategoriasHandler(db, queries))
+	protegida("/api/v1/categoria/", handle.APICategoriaHandler(db, queries))
+	protegida("/api/v1/direcciones", handle.APIDireccionesHandler(queries))
+	protegida("/api/v1/direccion/", handle.APIDireccionHandler(queries))
+	protegida("/api/v1/envios", handle.APIEnviosHandler(db, queries))
+	protegida("/api/v1/envio/", handle.APIEnvioHandler(queries))
+	admin("/api/v1/users", auth.PermisoUsuarios, handle.APIUsersHandler(queries))
+	admin("/api/v1/user/", auth.PermisoUsuarios, handle.APIUserHandler(db, queries))
+	admin("/api/v1/sales", auth.PermisoVentas, handle.APISalesHandler(db, queries))
+	admin("/api/v1/sale/", auth.PermisoVentas, handle.APISaleHandler(db, queries))
+	admin("/api/v1/cupones", auth.PermisoVentas, handle.APICuponesHandler(queries))
+	admin("/api/v1/cupon/", auth.PermisoVentas, handle.APICuponHandler(queries))
+	admin("/api/v1/promociones", auth.PermisoVentas, handle.APIPromocionesHandler(queries))
+	admin("/api/v1/promocion/", auth.PermisoVentas, handle.APIPromocionHandler(queries))
+	admin("/api/v1/moneda/", auth.PermisoMonedas, handle.APIMonedaHandler(queries))
+	admin("/api/v1/impuestos", auth.PermisoImpuestos, handle.APIImpuestosHandler(queries))
+	admin("/api/v1/impuesto/", auth.PermisoImpuestos, handle.APIImpuestoHandler(queries))
+	admin("/api/v1/impuestos/reporte", auth.PermisoVentas, handle.ReporteImpuestosHandler(queries))
+
+	// La "pasarela" del proveedor falso, solo con PAGOS_FAKE=1
+	if fake != nil {
+		protegida("/pagos/fake/", handle.FakePagoHandler(db, queries, fake))
+		protegida("/api/v1/pagos/fake/", handle.APIFakePagoHandler(db, queries, fake))
+	}
 
 	port := ":8080"
 	fmt.Printf("Servidor escuchando en http://localhost%s\n", port)
 
-	err := http.ListenAndServe(port, mux)
+	err = http.ListenAndServe(port, handle.ConCotizaciones(cotizaciones, handle.ConCategorias(arbol, mux)))
 	if err != nil {
 		fmt.Printf("Error al iniciar el servidor: %s\n", err)
 	}
 }
+
+// crearAdmin promueve a admin al usuario con ese email, creándolo si no existe.
+// Si se pasa password también se la reemplaza.
+func crearAdmin(ctx context.Context, queries *sqlc.Queries, email, password, nombre string) error {
+	var hash string
+	if password != "" {
+		if len(password) < auth.PasswordMinLen {
+			return fmt.Errorf("la contraseña debe tener al menos %d caracteres", auth.PasswordMinLen)
+		}
+		var err error
+		if hash, err = auth.HashPassword(password); err != nil {
+			return err
+		}
+	}
+
+	usuario, err := queries.GetUserByEmail(ctx, email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if hash == "" {
+			return errors.New("el usuario no existe: indica -password para crearlo")
+		}
+		usuario, err = queries.CreateUser(ctx, sqlc.CreateUserParams{
+			NombreUsuario: nombre,
+			Email:         email,
+			PasswordHash:  hash,
+		})
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case hash != "":
+		err = queries.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{
+			IDUsuario:    usuario.IDUsuario,
+			PasswordHash: hash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return queries.UpdateUserRol(ctx, sqlc.UpdateUserRolParams{
+		IDUsuario: usuario.IDUsuario,
+		Rol:       auth.RolAdmin,
+	})
+}
+
+// importarMonedas crea o actualiza las monedas listadas en un CSV
+func importarMonedas(ctx context.Context, db *sql.DB, queries *sqlc.Queries, ruta string) (int, error) {
+	archivo, err := os.Open(ruta)
+	if err != nil {
+		return 0, err
+	}
+	defer archivo.Close()
+
+	lista, err := monedas.LeerCSV(archivo)
+	if err != nil {
+		return 0, err
+	}
+	if err := monedas.Guardar(ctx, db, queries, lista); err != nil {
+		return 0, err
+	}
+	return len(lista), nil
+}
+
+// secretoAleatorio firma los webhooks del proveedor falso cuando no se
+// configuró uno; alcanza porque el propio servidor firma y verifica
+func secretoAleatorio() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
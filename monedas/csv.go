@@ -0,0 +1,65 @@
+package monedas
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+)
+
+// LeerCSV interpreta un archivo de tasas con las columnas
+// codigo,nombre,simbolo,tasa. La primera línea puede ser ese encabezado.
+func LeerCSV(r io.Reader) ([]sqlc.Moneda, error) {
+	lector := csv.NewReader(r)
+	lector.FieldsPerRecord = 4
+	lector.TrimLeadingSpace = true
+
+	var monedas []sqlc.Moneda
+	for linea := 1; ; linea++ {
+		registro, err := lector.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if linea == 1 && strings.EqualFold(registro[0], "codigo") {
+			continue
+		}
+
+		m, err := Validar(registro[0], registro[1], registro[2], registro[3])
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %w", linea, err)
+		}
+		monedas = append(monedas, m)
+	}
+	if len(monedas) == 0 {
+		return nil, errors.New("el archivo no tiene monedas")
+	}
+	return monedas, nil
+}
+
+// Validar arma una moneda a partir de sus campos en texto
+func Validar(codigo, nombre, simbolo, tasa string) (sqlc.Moneda, error) {
+	codigo = strings.ToUpper(strings.TrimSpace(codigo))
+	if len(codigo) != 3 {
+		return sqlc.Moneda{}, fmt.Errorf("código de moneda inválido: %q", codigo)
+	}
+	nombre = strings.TrimSpace(nombre)
+	simbolo = strings.TrimSpace(simbolo)
+	if nombre == "" || simbolo == "" {
+		return sqlc.Moneda{}, errors.New("nombre y símbolo son requeridos")
+	}
+	t, err := dinero.ParseTasa(tasa)
+	if err != nil {
+		return sqlc.Moneda{}, err
+	}
+	if codigo == Base && t != dinero.TasaUno {
+		return sqlc.Moneda{}, fmt.Errorf("la tasa de %s tiene que ser 1", Base)
+	}
+	return sqlc.Moneda{Codigo: codigo, Nombre: nombre, Simbolo: simbolo, Tasa: t}, nil
+}
@@ -0,0 +1,183 @@
+// Package monedas mantiene en memoria las monedas y sus tasas, convierte
+// precios entre ellas y guarda en el contexto la moneda elegida por la sesión.
+package monedas
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+)
+
+// Base es la moneda contra la que se expresan todas las tasas
+const Base = "ARS"
+
+// Cotizaciones es una copia en memoria de la tabla moneda. Se carga al
+// arrancar y se recarga cada vez que un admin cambia una tasa.
+type Cotizaciones struct {
+	mu        sync.RWMutex
+	lista     []sqlc.Moneda
+	porCodigo map[string]sqlc.Moneda
+}
+
+// Cargar lee todas las monedas de la base
+func Cargar(ctx context.Context, queries *sqlc.Queries) (*Cotizaciones, error) {
+	c := &Cotizaciones{}
+	if err := c.Recargar(ctx, queries); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Recargar vuelve a leer las monedas de la base
+func (c *Cotizaciones) Recargar(ctx context.Context, queries *sqlc.Queries) error {
+	lista, err := queries.ListMonedas(ctx)
+	if err != nil {
+		return err
+	}
+	c.usar(lista)
+	return nil
+}
+
+// Fijas arma las cotizaciones a partir de una lista en lugar de leerlas de
+// la base, para las pruebas
+func Fijas(lista []sqlc.Moneda) *Cotizaciones {
+	c := &Cotizaciones{}
+	c.usar(lista)
+	return c
+}
+
+func (c *Cotizaciones) usar(lista []sqlc.Moneda) {
+	porCodigo := make(map[string]sqlc.Moneda, len(lista))
+	for _, m := range lista {
+		porCodigo[m.Codigo] = m
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lista = lista
+	c.porCodigo = porCodigo
+}
+
+// Lista devuelve las monedas ordenadas por código
+func (c *Cotizaciones) Lista() []sqlc.Moneda {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lista
+}
+
+// Buscar devuelve la moneda con ese código
+func (c *Cotizaciones) Buscar(codigo string) (sqlc.Moneda, bool) {
+	if c == nil {
+		return sqlc.Moneda{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	m, ok := c.porCodigo[codigo]
+	return m, ok
+}
+
+// Convertir pasa un monto de la moneda desde a la moneda hacia
+func (c *Cotizaciones) Convertir(m dinero.Monto, desde, hacia string) (dinero.Monto, error) {
+	if desde == hacia {
+		return m, nil
+	}
+	origen, ok := c.Buscar(desde)
+	if !ok {
+		return 0, fmt.Errorf("moneda desconocida: %s", desde)
+	}
+	destino, ok := c.Buscar(hacia)
+	if !ok {
+		return 0, fmt.Errorf("moneda desconocida: %s", hacia)
+	}
+	return dinero.Convertir(m, origen.Tasa, destino.Tasa), nil
+}
+
+// Simbolo devuelve el símbolo de la moneda, o el código si no se conoce
+func (c *Cotizaciones) Simbolo(codigo string) string {
+	if m, ok := c.Buscar(codigo); ok {
+		return m.Simbolo
+	}
+	return codigo
+}
+
+type claveContexto int
+
+const (
+	claveCotizaciones claveContexto = iota
+	claveMoneda
+)
+
+// ConCotizaciones devuelve un contexto con las cotizaciones cargadas
+func ConCotizaciones(ctx context.Context, c *Cotizaciones) context.Context {
+	return context.WithValue(ctx, claveCotizaciones, c)
+}
+
+// De devuelve las cotizaciones del contexto, o nil si no hay
+func De(ctx context.Context) *Cotizaciones {
+	c, _ := ctx.Value(claveCotizaciones).(*Cotizaciones)
+	return c
+}
+
+// ConMoneda devuelve un contexto con la moneda en la que la sesión ve los precios
+func ConMoneda(ctx context.Context, codigo string) context.Context {
+	return context.WithValue(ctx, claveMoneda, codigo)
+}
+
+// Actual devuelve la moneda elegida por la sesión, o la base si no eligió
+func Actual(ctx context.Context) string {
+	if codigo, ok := ctx.Value(claveMoneda).(string); ok && codigo != "" {
+		return codigo
+	}
+	return Base
+}
+
+// Mostrar pasa el monto a la moneda de la sesión y lo formatea con su símbolo.
+// Si no se puede convertir lo muestra en su moneda original.
+func Mostrar(ctx context.Context, m dinero.Monto, desde string) string {
+	c := De(ctx)
+	hacia := Actual(ctx)
+	convertido, err := c.Convertir(m, desde, hacia)
+	if err != nil {
+		return m.FormatoCon(c.Simbolo(desde))
+	}
+	return convertido.FormatoCon(c.Simbolo(hacia))
+}
+
+// FormatoEn formatea un monto que ya está en la moneda indicada, como los de un pedido
+func FormatoEn(ctx context.Context, m dinero.Monto, codigo string) string {
+	if codigo == Base {
+		return m.Formato()
+	}
+	return m.FormatoCon(De(ctx).Simbolo(codigo))
+}
+
+// Guardar crea o actualiza todas las monedas en una sola transacción, así una
+// importación con errores no deja tasas a medio cargar
+func Guardar(ctx context.Context, db *sql.DB, queries *sqlc.Queries, lista []sqlc.Moneda) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := queries.WithTx(tx)
+
+	for _, m := range lista {
+		_, err := qtx.UpsertMoneda(ctx, sqlc.UpsertMonedaParams{
+			Codigo:  m.Codigo,
+			Nombre:  m.Nombre,
+			Simbolo: m.Simbolo,
+			Tasa:    m.Tasa,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
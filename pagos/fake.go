@@ -0,0 +1,187 @@
+package pagos
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"carrito.com/dinero"
+)
+
+// HeaderFirmaFake es el header con la firma de los webhooks del proveedor falso
+const HeaderFirmaFake = "X-Fake-Firma"
+
+// Fake es un proveedor local para probar el circuito completo sin pasarela
+// real. Guarda los intentos en memoria (se pierden al reiniciar) y no cobra
+// nada: el resultado se decide con Simular, que arma el webhook firmado que
+// mandaría una pasarela.
+type Fake struct {
+	secreto []byte
+
+	mu       sync.Mutex
+	intentos map[string]*intentoFake
+	// claves guarda la respuesta de cada captura o reembolso por su clave de
+	// idempotencia
+	claves map[string]error
+}
+
+type intentoFake struct {
+	cobro       Cobro
+	estado      string
+	reembolsado dinero.Monto
+}
+
+// Estados propios de los intentos del proveedor falso
+const (
+	fakeAbierto    = "abierto"
+	fakeAutorizado = "autorizado"
+	fakeCapturado  = "capturado"
+	fakeRechazado  = "rechazado"
+)
+
+// NuevoFake crea el proveedor falso; secreto firma sus webhooks
+func NuevoFake(secreto string) *Fake {
+	return &Fake{secreto: []byte(secreto), intentos: map[string]*intentoFake{}, claves: map[string]error{}}
+}
+
+func (f *Fake) Nombre() string { return "fake" }
+
+func (f *Fake) CrearIntento(_ context.Context, c Cobro) (Intento, error) {
+	id, err := aleatorio()
+	if err != nil {
+		return Intento{}, err
+	}
+	ref := "fake_" + id
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.intentos[ref] = &intentoFake{cobro: c, estado: fakeAbierto}
+	return Intento{Referencia: ref, URL: "/pagos/fake/" + ref}, nil
+}
+
+// Existe es false para los intentos creados antes de reiniciar
+func (f *Fake) Existe(_ context.Context, referencia string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.intentos[referencia]
+	return ok, nil
+}
+
+func (f *Fake) Capturar(_ context.Context, referencia, clave string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.claves[clave]; ok {
+		return err
+	}
+	err := f.capturar(referencia)
+	f.claves[clave] = err
+	return err
+}
+
+func (f *Fake) capturar(referencia string) error {
+	intento, ok := f.intentos[referencia]
+	if !ok {
+		return ErrPago{Motivo: "intento de pago desconocido: " + referencia}
+	}
+	if intento.estado == fakeCapturado {
+		return ErrCapturado
+	}
+	if intento.estado != fakeAutorizado {
+		return ErrPago{Motivo: fmt.Sprintf("un intento %s no se puede capturar", intento.estado)}
+	}
+	intento.estado = fakeCapturado
+	return nil
+}
+
+func (f *Fake) Reembolsar(_ context.Context, referencia string, monto dinero.Monto, clave string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.claves[clave]; ok {
+		return err
+	}
+	err := f.reembolsar(referencia, monto)
+	f.claves[clave] = err
+	return err
+}
+
+func (f *Fake) reembolsar(referencia string, monto dinero.Monto) error {
+	intento, ok := f.intentos[referencia]
+	if !ok {
+		return ErrPago{Motivo: "intento de pago desconocido: " + referencia}
+	}
+	if intento.estado != fakeCapturado {
+		return ErrPago{Motivo: fmt.Sprintf("un intento %s no se puede reembolsar", intento.estado)}
+	}
+	if intento.reembolsado+monto > intento.cobro.Monto {
+		return ErrPago{Motivo: "el reembolso supera lo cobrado"}
+	}
+	intento.reembolsado += monto
+	return nil
+}
+
+func (f *Fake) VerificarWebhook(cuerpo []byte, header http.Header) (Evento, error) {
+	firma, err := hex.DecodeString(header.Get(HeaderFirmaFake))
+	if err != nil || !hmac.Equal(firma, f.firmar(cuerpo)) {
+		return Evento{}, ErrFirma
+	}
+	var e Evento
+	if err := json.Unmarshal(cuerpo, &e); err != nil {
+		return Evento{}, fmt.Errorf("evento inválido: %w", err)
+	}
+	return e, nil
+}
+
+// Simular resuelve un intento abierto como lo haría el cliente en la pasarela
+// (aprobar en true autoriza, en false rechaza) y devuelve el webhook firmado
+// que hay que entregar al endpoint de webhooks
+func (f *Fake) Simular(referencia string, aprobar bool) ([]byte, http.Header, error) {
+	id, err := aleatorio()
+	if err != nil {
+		return nil, nil, err
+	}
+	f.mu.Lock()
+	intento, ok := f.intentos[referencia]
+	if !ok {
+		f.mu.Unlock()
+		return nil, nil, ErrPago{Motivo: "intento de pago desconocido: " + referencia}
+	}
+	if intento.estado != fakeAbierto {
+		f.mu.Unlock()
+		return nil, nil, ErrPago{Motivo: fmt.Sprintf("el intento ya está %s", intento.estado)}
+	}
+	evento := Evento{ID: "evt_" + id, Tipo: EventoRechazado, Referencia: referencia}
+	intento.estado = fakeRechazado
+	if aprobar {
+		evento.Tipo = EventoAutorizado
+		intento.estado = fakeAutorizado
+	}
+	f.mu.Unlock()
+
+	cuerpo, err := json.Marshal(evento)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(HeaderFirmaFake, hex.EncodeToString(f.firmar(cuerpo)))
+	return cuerpo, header, nil
+}
+
+func (f *Fake) firmar(cuerpo []byte) []byte {
+	mac := hmac.New(sha256.New, f.secreto)
+	mac.Write(cuerpo)
+	return mac.Sum(nil)
+}
+
+func aleatorio() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,78 @@
+package pagos
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"carrito.com/dinero"
+)
+
+// autorizado crea un intento por monto y lo aprueba como lo haría el cliente
+func autorizado(t *testing.T, f *Fake, monto dinero.Monto) string {
+	t.Helper()
+	intento, err := f.CrearIntento(context.Background(), Cobro{IDPedido: 1, Monto: monto, Moneda: "ARS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.Simular(intento.Referencia, true); err != nil {
+		t.Fatal(err)
+	}
+	return intento.Referencia
+}
+
+func TestFakeCapturarConClave(t *testing.T) {
+	ctx := context.Background()
+	f := NuevoFake("secreto")
+	ref := autorizado(t, f, 1000)
+
+	if err := f.Capturar(ctx, ref, "captura-1"); err != nil {
+		t.Fatalf("primera captura: %v", err)
+	}
+	// El reintento con la misma clave responde lo mismo que la primera vez
+	if err := f.Capturar(ctx, ref, "captura-1"); err != nil {
+		t.Fatalf("captura repetida: %v", err)
+	}
+	if err := f.Capturar(ctx, ref, "captura-2"); !errors.Is(err, ErrCapturado) {
+		t.Fatalf("captura con otra clave = %v; se esperaba ErrCapturado", err)
+	}
+
+	abierto, err := f.CrearIntento(ctx, Cobro{IDPedido: 2, Monto: 500, Moneda: "ARS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errPago ErrPago
+	if err := f.Capturar(ctx, abierto.Referencia, "captura-3"); !errors.As(err, &errPago) {
+		t.Fatalf("captura sin autorizar = %v; se esperaba ErrPago", err)
+	}
+}
+
+func TestFakeReembolsarConClave(t *testing.T) {
+	ctx := context.Background()
+	f := NuevoFake("secreto")
+	ref := autorizado(t, f, 1000)
+	if err := f.Capturar(ctx, ref, "captura-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	casos := []struct {
+		nombre string
+		monto  dinero.Monto
+		clave  string
+		err    bool
+	}{
+		{"parcial", 300, "reembolso-1", false},
+		{"repetido no devuelve dos veces", 300, "reembolso-1", false},
+		{"más de lo que queda", 800, "reembolso-2", true},
+		{"el rechazo también se repite", 800, "reembolso-2", true},
+		{"el resto", 700, "reembolso-3", false},
+		{"nada más por devolver", 1, "reembolso-4", true},
+	}
+	for _, c := range casos {
+		err := f.Reembolsar(ctx, ref, c.monto, c.clave)
+		var errPago ErrPago
+		if c.err != errors.As(err, &errPago) || (!c.err && err != nil) {
+			t.Errorf("%s: Reembolsar = %v", c.nombre, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package pagos abstrae la pasarela que cobra los pedidos. Cada pasarela es un
+// Proveedor registrado por nombre; el checkout cobra con el predeterminado y
+// los webhooks y reembolsos buscan el proveedor que se guardó en cada pago.
+package pagos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+
+	"carrito.com/dinero"
+)
+
+// Estados de un pago (ver CHECK en schema.sql). Capturando es un pago
+// autorizado cuya captura ya se pidió a la pasarela y falta su respuesta.
+const (
+	EstadoCreado     = "creado"
+	EstadoCapturando = "capturando"
+	EstadoCapturado  = "capturado"
+	EstadoRechazado  = "rechazado"
+	EstadoAnulado    = "anulado"
+)
+
+// Estados de la devolución de un reembolso por la pasarela (reembolso.estado_pago)
+const (
+	ReembolsoPendiente = "pendiente"
+	ReembolsoDevuelto  = "devuelto"
+	ReembolsoRechazado = "rechazado"
+)
+
+// Tipos de evento que los proveedores informan por webhook
+const (
+	// EventoAutorizado: el cliente pagó y el monto quedó reservado; falta capturarlo
+	EventoAutorizado = "pago.autorizado"
+	// EventoRechazado: el cobro no se pudo hacer
+	EventoRechazado = "pago.rechazado"
+)
+
+// ErrFirma indica un webhook cuya firma no corresponde al proveedor
+var ErrFirma = errors.New("firma del webhook inválida")
+
+// ErrCapturado indica que el intento ya estaba capturado: para quien pidió la
+// captura es lo mismo que haberlo capturado ahora
+var ErrCapturado = errors.New("el intento ya estaba capturado")
+
+// ErrPago es un rechazo del proveedor (intento inexistente, monto mayor al
+// capturado, etc.). Motivo se puede mostrar.
+type ErrPago struct {
+	Motivo string
+}
+
+func (e ErrPago) Error() string {
+	return e.Motivo
+}
+
+// Cobro es lo que se le pide al proveedor para un pedido
+type Cobro struct {
+	IDPedido int32
+	Monto    dinero.Monto
+	Moneda   string
+}
+
+// Intento es un cobro creado en el proveedor: Referencia lo identifica allá y
+// URL es adonde se manda al cliente para pagar
+type Intento struct {
+	Referencia string
+	URL        string
+}
+
+// Evento es una notificación del proveedor ya verificada. ID sirve para no
+// procesar dos veces el mismo evento.
+type Evento struct {
+	ID         string `json:"id"`
+	Tipo       string `json:"tipo"`
+	Referencia string `json:"referencia"`
+}
+
+// Proveedor es una pasarela de pagos. Capturar y Reembolsar reciben una
+// clave de idempotencia: si llega una que ya se usó, la pasarela no repite la
+// operación y responde lo mismo que la primera vez. Así se puede reintentar
+// una captura o un reembolso cuya respuesta se perdió sin cobrar ni devolver
+// dos veces.
+type Proveedor interface {
+	// Nombre es como se guarda en pago.proveedor y la ruta de su webhook
+	Nombre() string
+	// CrearIntento abre un cobro por el monto del pedido
+	CrearIntento(ctx context.Context, c Cobro) (Intento, error)
+	// Existe indica si la pasarela conoce el intento; false si se perdió
+	Existe(ctx context.Context, referencia string) (bool, error)
+	// Capturar cobra un intento autorizado. Si ya estaba capturado devuelve
+	// ErrCapturado.
+	Capturar(ctx context.Context, referencia, clave string) error
+	// Reembolsar devuelve monto de un intento capturado
+	Reembolsar(ctx context.Context, referencia string, monto dinero.Monto, clave string) error
+	// VerificarWebhook valida la firma del cuerpo recibido y lo interpreta;
+	// devuelve ErrFirma si no es del proveedor
+	VerificarWebhook(cuerpo []byte, header http.Header) (Evento, error)
+}
+
+var (
+	proveedores    = map[string]Proveedor{}
+	predeterminado string
+)
+
+// Registrar agrega un proveedor. El primero que se registra es el
+// predeterminado hasta que se llame a Usar.
+func Registrar(p Proveedor) {
+	if len(proveedores) == 0 {
+		predeterminado = p.Nombre()
+	}
+	proveedores[p.Nombre()] = p
+}
+
+// Usar elige con qué proveedor se cobran los pedidos nuevos
+func Usar(nombre string) error {
+	if _, ok := proveedores[nombre]; !ok {
+		return errors.New("proveedor de pagos desconocido: " + nombre)
+	}
+	predeterminado = nombre
+	return nil
+}
+
+// Buscar devuelve el proveedor registrado con ese nombre
+func Buscar(nombre string) (Proveedor, bool) {
+	p, ok := proveedores[nombre]
+	return p, ok
+}
+
+// Predeterminado devuelve el proveedor con el que se cobran los pedidos
+// nuevos, o false si no hay ninguno registrado
+func Predeterminado() (Proveedor, bool) {
+	return Buscar(predeterminado)
+}
+
+// Nombres devuelve los proveedores registrados
+func Nombres() []string {
+	lista := make([]string, 0, len(proveedores))
+	for n := range proveedores {
+		lista = append(lista, n)
+	}
+	sort.Strings(lista)
+	return lista
+}
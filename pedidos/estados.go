@@ -0,0 +1,110 @@
+package pedidos
+
+import "fmt"
+
+// Estado es el estado de un pedido. Se guarda tal cual en pedido.estado.
+type Estado string
+
+const (
+	EstadoPendiente     Estado = "pendiente"
+	EstadoEsperandoPago Estado = "esperando_pago"
+	EstadoPagado        Estado = "pagado"
+	EstadoEnviado       Estado = "enviado"
+	EstadoEntregado     Estado = "entregado"
+	EstadoCancelado     Estado = "cancelado"
+)
+
+// Estados lista todos los estados en el orden en que avanza un pedido
+var Estados = []Estado{EstadoPendiente, EstadoEsperandoPago, EstadoPagado, EstadoEnviado, EstadoEntregado, EstadoCancelado}
+
+// transiciones define a qué estados se puede pasar desde cada uno.
+// Entregado y cancelado son finales.
+var transiciones = map[Estado][]Estado{
+	EstadoPendiente:     {EstadoEsperandoPago, EstadoPagado, EstadoCancelado},
+	EstadoEsperandoPago: {EstadoPagado, EstadoPendiente, EstadoCancelado},
+	EstadoPagado:        {EstadoEnviado, EstadoCancelado},
+	EstadoEnviado:       {EstadoEntregado},
+}
+
+// transicionesPago son las que solo hace el flujo de pago: abrir un cobro en
+// la pasarela y volver a pendiente si el cobro se rechaza
+var transicionesPago = map[Estado][]Estado{
+	EstadoPendiente:     {EstadoEsperandoPago},
+	EstadoEsperandoPago: {EstadoPendiente},
+}
+
+// ErrTransicion indica un cambio de estado que la máquina de estados no
+// permite, o que no se puede hacer ahora por Motivo
+type ErrTransicion struct {
+	Desde, Hacia Estado
+	Motivo       string
+}
+
+func (e ErrTransicion) Error() string {
+	if e.Motivo != "" {
+		return fmt.Sprintf("un pedido %s no puede pasar a %s: %s", e.Desde, e.Hacia, e.Motivo)
+	}
+	return fmt.Sprintf("un pedido %s no puede pasar a %s", e.Desde, e.Hacia)
+}
+
+// Valido indica si e es uno de los estados conocidos
+func (e Estado) Valido() bool {
+	for _, estado := range Estados {
+		if e == estado {
+			return true
+		}
+	}
+	return false
+}
+
+// Final indica si el pedido ya no puede cambiar de estado
+func (e Estado) Final() bool {
+	return len(transiciones[e]) == 0
+}
+
+// Cancelable indica si el pedido todavía no salió y se puede cancelar
+func (e Estado) Cancelable() bool {
+	return Transicion(e, EstadoCancelado) == nil
+}
+
+// Cobrado indica si el pedido ya se pagó y por lo tanto admite reembolsos
+func (e Estado) Cobrado() bool {
+	return e == EstadoPagado || e == EstadoEnviado || e == EstadoEntregado
+}
+
+// Pagable indica si el cliente todavía puede pagar el pedido por la pasarela
+func (e Estado) Pagable() bool {
+	return e == EstadoPendiente || e == EstadoEsperandoPago
+}
+
+// Siguientes devuelve los estados a los que se puede pasar a mano desde e
+func (e Estado) Siguientes() []Estado {
+	var lista []Estado
+	for _, siguiente := range transiciones[e] {
+		if TransicionManual(e, siguiente) == nil {
+			lista = append(lista, siguiente)
+		}
+	}
+	return lista
+}
+
+// Transicion devuelve nil si se puede pasar de desde a hacia, o un ErrTransicion
+func Transicion(desde, hacia Estado) error {
+	for _, siguiente := range transiciones[desde] {
+		if siguiente == hacia {
+			return nil
+		}
+	}
+	return ErrTransicion{Desde: desde, Hacia: hacia}
+}
+
+// TransicionManual es Transicion para los cambios que pide un usuario: las
+// transiciones del flujo de pago no se pueden forzar
+func TransicionManual(desde, hacia Estado) error {
+	for _, siguiente := range transicionesPago[desde] {
+		if siguiente == hacia {
+			return ErrTransicion{Desde: desde, Hacia: hacia}
+		}
+	}
+	return Transicion(desde, hacia)
+}
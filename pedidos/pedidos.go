@@ -0,0 +1,110 @@
+// Package pedidos agrupa la lógica de pedidos que comparten handlers y vistas.
+package pedidos
+
+import (
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+)
+
+// Detalle es un pedido junto con sus líneas, sus cambios de estado, sus
+// reembolsos y sus cobros por la pasarela. En JSON los campos del pedido
+// quedan al mismo nivel que el resto.
+type Detalle struct {
+	sqlc.Pedido
+	Items      []sqlc.PedidoItem      `json:"items"`
+	Historial  []sqlc.PedidoHistorial `json:"historial"`
+	Reembolsos []sqlc.Reembolso       `json:"reembolsos"`
+	Pagos      []sqlc.Pago            `json:"pagos"`
+}
+
+// EstadoActual devuelve el estado del pedido con su tipo
+func (d Detalle) EstadoActual() Estado {
+	return Estado(d.Estado)
+}
+
+// Neto es lo que el cliente terminó pagando: total menos lo reembolsado
+func (d Detalle) Neto() dinero.Monto {
+	return d.Total - d.TotalReembolsado
+}
+
+// Reembolsable devuelve cuántas unidades de la línea todavía se pueden reembolsar
+func Reembolsable(item sqlc.PedidoItem) int32 {
+	return item.Cantidad - item.CantidadReembolsada
+}
+
+// Pagado es lo que se cobró por la línea: subtotal menos su parte del
+// descuento, más el impuesto si no estaba incluido en el precio
+func Pagado(item sqlc.PedidoItem) dinero.Monto {
+	pagado := item.Subtotal - item.Descuento
+	if !item.ImpuestoIncluido {
+		pagado += item.Impuesto
+	}
+	return pagado
+}
+
+// MontoReembolso es lo que se devuelve por cantidad unidades más de la línea:
+// lo pagado por la línea repartido por unidad. Se calcula sobre lo acumulado
+// para que, al reembolsar todas las unidades, la suma sea exactamente lo
+// pagado aunque no divida justo.
+func MontoReembolso(item sqlc.PedidoItem, cantidad int32) dinero.Monto {
+	pagado := Pagado(item)
+	antes := int64(item.CantidadReembolsada)
+	return pagado.Proporcion(antes+int64(cantidad), int64(item.Cantidad)) - pagado.Proporcion(antes, int64(item.Cantidad))
+}
+
+// UnidadesReembolsables suma lo que todavía se puede reembolsar en todo el pedido
+func (d Detalle) UnidadesReembolsables() int32 {
+	var total int32
+	for _, item := range d.Items {
+		total += Reembolsable(item)
+	}
+	return total
+}
+
+// IDs devuelve los IDs de los pedidos, para buscar todas sus líneas en una sola query
+func IDs(pedidos []sqlc.Pedido) []int32 {
+	ids := make([]int32, len(pedidos))
+	for i, p := range pedidos {
+		ids[i] = p.IDPedido
+	}
+	return ids
+}
+
+// Agrupar reparte líneas, historial, reembolsos y pagos entre sus pedidos
+// respetando el orden de pedidos
+func Agrupar(pedidos []sqlc.Pedido, items []sqlc.PedidoItem, historial []sqlc.PedidoHistorial, reembolsos []sqlc.Reembolso, pagos []sqlc.Pago) []Detalle {
+	detalles := make([]Detalle, len(pedidos))
+	indice := make(map[int32]*Detalle, len(pedidos))
+	for i, p := range pedidos {
+		detalles[i] = Detalle{
+			Pedido:     p,
+			Items:      []sqlc.PedidoItem{},
+			Historial:  []sqlc.PedidoHistorial{},
+			Reembolsos: []sqlc.Reembolso{},
+			Pagos:      []sqlc.Pago{},
+		}
+		indice[p.IDPedido] = &detalles[i]
+	}
+
+	for _, item := range items {
+		if d, ok := indice[item.IDPedido]; ok {
+			d.Items = append(d.Items, item)
+		}
+	}
+	for _, h := range historial {
+		if d, ok := indice[h.IDPedido]; ok {
+			d.Historial = append(d.Historial, h)
+		}
+	}
+	for _, r := range reembolsos {
+		if d, ok := indice[r.IDPedido]; ok {
+			d.Reembolsos = append(d.Reembolsos, r)
+		}
+	}
+	for _, p := range pagos {
+		if d, ok := indice[p.IDPedido]; ok {
+			d.Pagos = append(d.Pagos, p)
+		}
+	}
+	return detalles
+}
@@ -0,0 +1,168 @@
+// Package promociones evalúa las promociones automáticas sobre un carrito.
+// Cada tipo de promoción es una Regla; el tipo guardado en la base elige el
+// constructor registrado en tipos, así que sumar un tipo nuevo es escribir su
+// Regla, registrarla y agregarlo al CHECK de promocion.tipo.
+package promociones
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"carrito.com/categorias"
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+// Linea es una línea del carrito con el precio ya pasado a la moneda del cobro.
+// Categorias son la categoría del producto y sus ancestros, como los devuelve
+// categorias.Arbol.Ancestros. Las reglas solo suman a Descuento y Etiquetas.
+type Linea struct {
+	IDProducto int32
+	Nombre     string
+	Categorias []int32
+	Cantidad   int32
+	Precio     dinero.Monto
+	Descuento  dinero.Monto
+	Etiquetas  []string
+}
+
+// Subtotal es precio por cantidad, sin descuentos
+func (l Linea) Subtotal() dinero.Monto {
+	return l.Precio.Por(l.Cantidad)
+}
+
+// Total es lo que se cobra por la línea después de las promociones
+func (l Linea) Total() dinero.Monto {
+	return l.Subtotal() - l.Descuento
+}
+
+// descontar suma a la línea hasta monto de descuento, sin pasar de su
+// subtotal, y anota la etiqueta si descontó algo
+func (l *Linea) descontar(monto dinero.Monto, etiqueta string) {
+	monto = min(monto, l.Total())
+	if monto <= 0 {
+		return
+	}
+	l.Descuento += monto
+	l.Etiquetas = append(l.Etiquetas, etiqueta)
+}
+
+// Carrito es lo que reciben las reglas: las líneas y la moneda en que están
+type Carrito struct {
+	Lineas       []Linea
+	Moneda       string
+	Cotizaciones *monedas.Cotizaciones
+}
+
+// Total suma lo que se cobra por todas las líneas con los descuentos aplicados hasta ahora
+func (c *Carrito) Total() dinero.Monto {
+	var total dinero.Monto
+	for _, l := range c.Lineas {
+		total += l.Total()
+	}
+	return total
+}
+
+// Regla es un tipo de promoción ya configurada
+type Regla interface {
+	Aplicar(c *Carrito) error
+}
+
+// Constructor arma la Regla de una fila de promocion
+type Constructor func(p sqlc.Promocion) Regla
+
+var tipos = map[string]Constructor{}
+
+// Registrar asocia un tipo de promocion.tipo con su constructor
+func Registrar(tipo string, constructor Constructor) {
+	tipos[tipo] = constructor
+}
+
+// Tipos devuelve los tipos registrados
+func Tipos() []string {
+	lista := make([]string, 0, len(tipos))
+	for t := range tipos {
+		lista = append(lista, t)
+	}
+	sort.Strings(lista)
+	return lista
+}
+
+// Resultado es el carrito con las promociones aplicadas
+type Resultado struct {
+	Lineas []Linea
+}
+
+// Subtotal suma las líneas sin descuentos
+func (r Resultado) Subtotal() dinero.Monto {
+	var total dinero.Monto
+	for _, l := range r.Lineas {
+		total += l.Subtotal()
+	}
+	return total
+}
+
+// Descuento suma lo que descontaron las promociones
+func (r Resultado) Descuento() dinero.Monto {
+	var total dinero.Monto
+	for _, l := range r.Lineas {
+		total += l.Descuento
+	}
+	return total
+}
+
+// Total es lo que queda por cobrar después de las promociones
+func (r Resultado) Total() dinero.Monto {
+	return r.Subtotal() - r.Descuento()
+}
+
+// Evaluar aplica las promociones en orden sobre las líneas, que no se
+// modifican. Cada regla descuenta sobre lo que dejaron las anteriores, así
+// que una línea nunca queda por debajo de cero.
+func Evaluar(cot *monedas.Cotizaciones, moneda string, promos []sqlc.Promocion, lineas []Linea) (Resultado, error) {
+	c := &Carrito{Lineas: make([]Linea, len(lineas)), Moneda: moneda, Cotizaciones: cot}
+	for i, l := range lineas {
+		l.Descuento = 0
+		l.Etiquetas = nil
+		c.Lineas[i] = l
+	}
+
+	for _, p := range promos {
+		constructor, ok := tipos[p.Tipo]
+		if !ok {
+			return Resultado{}, fmt.Errorf("promoción %d: tipo desconocido %q", p.IDPromocion, p.Tipo)
+		}
+		if err := constructor(p).Aplicar(c); err != nil {
+			return Resultado{}, fmt.Errorf("promoción %d: %w", p.IDPromocion, err)
+		}
+	}
+	return Resultado{Lineas: c.Lineas}, nil
+}
+
+// DesdeCarrito arma las líneas a partir del carrito guardado, pasando cada
+// precio a la moneda indicada y cada categoría a sus ancestros en el árbol
+func DesdeCarrito(cot *monedas.Cotizaciones, arbol *categorias.Arbol, moneda string, items []sqlc.GetCartItemsRow) ([]Linea, error) {
+	lineas := make([]Linea, len(items))
+	for i, item := range items {
+		precio, err := cot.Convertir(item.Precio, item.Moneda, moneda)
+		if err != nil {
+			return nil, err
+		}
+		lineas[i] = Linea{
+			IDProducto: item.IDProducto,
+			Nombre:     item.NombreProducto,
+			Categorias: arbol.Ancestros(item.IDCategoria),
+			Cantidad:   item.Cantidad,
+			Precio:     precio,
+		}
+	}
+	return lineas, nil
+}
+
+// Etiquetas une los nombres de las promociones de una línea, como se guardan
+// en pedido_item.promociones
+func Etiquetas(l Linea) string {
+	return strings.Join(l.Etiquetas, "; ")
+}
@@ -0,0 +1,172 @@
+package promociones
+
+import (
+	"slices"
+	"testing"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+var cotizaciones = monedas.Fijas([]sqlc.Moneda{
+	{Codigo: monedas.Base, Simbolo: "$", Tasa: dinero.TasaUno},
+	{Codigo: "USD", Simbolo: "US$", Tasa: 1000 * dinero.TasaUno},
+})
+
+var (
+	perifericos = int32(2)
+	teclado     = int32(10)
+	mouse       = int32(11)
+	pad         = int32(12)
+)
+
+func porCategoriaDe(id int32, cantidad, porc int32) sqlc.Promocion {
+	return sqlc.Promocion{IDPromocion: 1, Nombre: "categoría", Tipo: TipoCategoria, IDCategoria: &id, Cantidad: cantidad, Porcentaje: porc, Activa: true}
+}
+
+func nxmDe(id int32, cantidad, paga int32) sqlc.Promocion {
+	return sqlc.Promocion{IDPromocion: 2, Nombre: "nxm", Tipo: TipoNxM, IDProducto: &id, Cantidad: cantidad, Paga: paga, Activa: true}
+}
+
+func regaloDe(id int32, minimo dinero.Monto, moneda string) sqlc.Promocion {
+	return sqlc.Promocion{IDPromocion: 3, Nombre: "regalo", Tipo: TipoRegalo, IDProducto: &id, Minimo: minimo, Moneda: moneda, Activa: true}
+}
+
+// carrito: un teclado y un mouse de periféricos (el mouse en una
+// subcategoría) y un pad sin categoría
+func carrito(teclados, mouses int32) []Linea {
+	return []Linea{
+		{IDProducto: teclado, Categorias: []int32{perifericos}, Cantidad: teclados, Precio: 1000},
+		{IDProducto: mouse, Categorias: []int32{5, perifericos}, Cantidad: mouses, Precio: 500},
+		{IDProducto: pad, Cantidad: 1, Precio: 200},
+	}
+}
+
+func TestEvaluar(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		promos     []sqlc.Promocion
+		lineas     []Linea
+		descuentos []dinero.Monto
+		etiquetas  [][]string
+	}{
+		{
+			nombre:     "por categoría incluye las subcategorías",
+			promos:     []sqlc.Promocion{porCategoriaDe(perifericos, 3, 10)},
+			lineas:     carrito(2, 1),
+			descuentos: []dinero.Monto{200, 50, 0},
+			etiquetas:  [][]string{{"categoría"}, {"categoría"}, nil},
+		},
+		{
+			nombre:     "por categoría sin llegar a la cantidad",
+			promos:     []sqlc.Promocion{porCategoriaDe(perifericos, 3, 10)},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{0, 0, 0},
+			etiquetas:  [][]string{nil, nil, nil},
+		},
+		{
+			nombre:     "3x2 sobre siete unidades regala dos",
+			promos:     []sqlc.Promocion{nxmDe(teclado, 3, 2)},
+			lineas:     carrito(7, 1),
+			descuentos: []dinero.Monto{2000, 0, 0},
+			etiquetas:  [][]string{{"nxm"}, nil, nil},
+		},
+		{
+			nombre:     "2x1 con una sola unidad no descuenta",
+			promos:     []sqlc.Promocion{nxmDe(teclado, 2, 1)},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{0, 0, 0},
+			etiquetas:  [][]string{nil, nil, nil},
+		},
+		{
+			nombre:     "regalo por debajo del mínimo",
+			promos:     []sqlc.Promocion{regaloDe(pad, 1701, monedas.Base)},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{0, 0, 0},
+			etiquetas:  [][]string{nil, nil, nil},
+		},
+		{
+			nombre:     "regalo justo en el mínimo",
+			promos:     []sqlc.Promocion{regaloDe(pad, 1700, monedas.Base)},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{0, 0, 200},
+			etiquetas:  [][]string{nil, nil, {"regalo"}},
+		},
+		{
+			nombre:     "regalo con el mínimo en otra moneda",
+			promos:     []sqlc.Promocion{regaloDe(pad, 1, "USD")},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{0, 0, 200},
+			etiquetas:  [][]string{nil, nil, {"regalo"}},
+		},
+		{
+			nombre:     "regalo de un producto que no está en el carrito",
+			promos:     []sqlc.Promocion{regaloDe(99, 100, monedas.Base)},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{0, 0, 0},
+			etiquetas:  [][]string{nil, nil, nil},
+		},
+		{
+			nombre:     "el regalo mide el total después de las promociones anteriores",
+			promos:     []sqlc.Promocion{porCategoriaDe(perifericos, 2, 10), regaloDe(pad, 1700, monedas.Base)},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{100, 50, 0},
+			etiquetas:  [][]string{{"categoría"}, {"categoría"}, nil},
+		},
+		{
+			nombre:     "en el otro orden el regalo se aplica primero",
+			promos:     []sqlc.Promocion{regaloDe(pad, 1700, monedas.Base), porCategoriaDe(perifericos, 2, 10)},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{100, 50, 200},
+			etiquetas:  [][]string{{"categoría"}, {"categoría"}, {"regalo"}},
+		},
+		{
+			nombre:     "se acumulan sobre la misma línea: el porcentaje va sobre lo que dejó el 2x1",
+			promos:     []sqlc.Promocion{nxmDe(teclado, 2, 1), porCategoriaDe(perifericos, 1, 10)},
+			lineas:     carrito(2, 0),
+			descuentos: []dinero.Monto{1100, 0, 0},
+			etiquetas:  [][]string{{"nxm", "categoría"}, nil, nil},
+		},
+		{
+			nombre:     "con el porcentaje primero el 2x1 regala la unidad entera",
+			promos:     []sqlc.Promocion{porCategoriaDe(perifericos, 1, 10), nxmDe(teclado, 2, 1)},
+			lineas:     carrito(2, 0),
+			descuentos: []dinero.Monto{1200, 0, 0},
+			etiquetas:  [][]string{{"categoría", "nxm"}, nil, nil},
+		},
+		{
+			nombre:     "una línea ya gratis no baja de cero ni suma la etiqueta",
+			promos:     []sqlc.Promocion{regaloDe(pad, 100, monedas.Base), regaloDe(pad, 100, monedas.Base)},
+			lineas:     carrito(1, 1),
+			descuentos: []dinero.Monto{0, 0, 200},
+			etiquetas:  [][]string{nil, nil, {"regalo"}},
+		},
+	}
+	for _, c := range casos {
+		t.Run(c.nombre, func(t *testing.T) {
+			r, err := Evaluar(cotizaciones, monedas.Base, c.promos, c.lineas)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, l := range r.Lineas {
+				if l.Descuento != c.descuentos[i] || !slices.Equal(l.Etiquetas, c.etiquetas[i]) {
+					t.Errorf("línea %d: descuento %d %q; se esperaba %d %q", i, l.Descuento, l.Etiquetas, c.descuentos[i], c.etiquetas[i])
+				}
+				if l.Total() < 0 {
+					t.Errorf("línea %d quedó en %d", i, l.Total())
+				}
+			}
+			if c.lineas[0].Descuento != 0 || c.lineas[0].Etiquetas != nil {
+				t.Errorf("Evaluar modificó las líneas recibidas")
+			}
+		})
+	}
+}
+
+func TestEvaluarTipoDesconocido(t *testing.T) {
+	_, err := Evaluar(cotizaciones, monedas.Base, []sqlc.Promocion{{IDPromocion: 7, Tipo: "otro"}}, carrito(1, 1))
+	if err == nil {
+		t.Fatal("Evaluar aceptó un tipo desconocido")
+	}
+}
@@ -0,0 +1,134 @@
+package promociones
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	sqlc "carrito.com/db/sqlc"
+	"carrito.com/dinero"
+	"carrito.com/monedas"
+)
+
+// Tipos de promoción (ver CHECK en schema.sql)
+const (
+	TipoCategoria = "categoria"
+	TipoNxM       = "nxm"
+	TipoRegalo    = "regalo"
+)
+
+func init() {
+	Registrar(TipoCategoria, func(p sqlc.Promocion) Regla { return porCategoria{p} })
+	Registrar(TipoNxM, func(p sqlc.Promocion) Regla { return nxm{p} })
+	Registrar(TipoRegalo, func(p sqlc.Promocion) Regla { return regalo{p} })
+}
+
+// porCategoria: llevando al menos Cantidad unidades de la categoría o sus
+// subcategorías, esas líneas tienen Porcentaje de descuento ("3 periféricos,
+// 10% off")
+type porCategoria struct {
+	p sqlc.Promocion
+}
+
+func (r porCategoria) Aplicar(c *Carrito) error {
+	var unidades int32
+	for _, l := range c.Lineas {
+		if slices.Contains(l.Categorias, *r.p.IDCategoria) {
+			unidades += l.Cantidad
+		}
+	}
+	if unidades < r.p.Cantidad {
+		return nil
+	}
+	for i := range c.Lineas {
+		l := &c.Lineas[i]
+		if slices.Contains(l.Categorias, *r.p.IDCategoria) {
+			l.descontar(l.Total().Proporcion(int64(r.p.Porcentaje), 100), r.p.Nombre)
+		}
+	}
+	return nil
+}
+
+// nxm: cada Cantidad unidades del producto se pagan Paga ("2x1")
+type nxm struct {
+	p sqlc.Promocion
+}
+
+func (r nxm) Aplicar(c *Carrito) error {
+	for i := range c.Lineas {
+		l := &c.Lineas[i]
+		if l.IDProducto != *r.p.IDProducto {
+			continue
+		}
+		gratis := l.Cantidad / r.p.Cantidad * (r.p.Cantidad - r.p.Paga)
+		l.descontar(l.Precio.Por(gratis), r.p.Nombre)
+	}
+	return nil
+}
+
+// regalo: con un total de al menos Minimo, una unidad del producto va gratis
+// si está en el carrito. El total se mide después de las promociones anteriores.
+type regalo struct {
+	p sqlc.Promocion
+}
+
+func (r regalo) Aplicar(c *Carrito) error {
+	minimo, err := c.Cotizaciones.Convertir(r.p.Minimo, r.p.Moneda, c.Moneda)
+	if err != nil {
+		return err
+	}
+	if c.Total() < minimo {
+		return nil
+	}
+	for i := range c.Lineas {
+		l := &c.Lineas[i]
+		if l.IDProducto == *r.p.IDProducto {
+			l.descontar(l.Precio, r.p.Nombre)
+			return nil
+		}
+	}
+	return nil
+}
+
+// Validar normaliza y revisa una promoción antes de guardarla: cada tipo
+// lleva solo las columnas que usa. La moneda vacía se completa con la base.
+func Validar(cot *monedas.Cotizaciones, p *sqlc.CreatePromocionParams) error {
+	p.Nombre = strings.TrimSpace(p.Nombre)
+	if p.Nombre == "" {
+		return errors.New("el nombre es requerido: es la etiqueta que ve el cliente")
+	}
+	if p.Moneda == "" {
+		p.Moneda = monedas.Base
+	}
+	if _, ok := cot.Buscar(p.Moneda); !ok {
+		return errors.New("moneda desconocida: " + p.Moneda)
+	}
+	if p.IDCategoria != nil && *p.IDCategoria <= 0 {
+		p.IDCategoria = nil
+	}
+	if p.IDProducto != nil && *p.IDProducto <= 0 {
+		p.IDProducto = nil
+	}
+
+	switch p.Tipo {
+	case TipoCategoria:
+		if p.IDCategoria == nil || p.Cantidad < 1 || p.Porcentaje < 1 || p.Porcentaje > 100 {
+			return errors.New("una promoción por categoría lleva id_categoria, cantidad mínima y un porcentaje entre 1 y 100")
+		}
+		p.IDProducto, p.Paga, p.Minimo = nil, 0, 0
+	case TipoNxM:
+		if p.IDProducto == nil || p.Paga < 1 || p.Cantidad <= p.Paga {
+			return errors.New("una promoción NxM lleva id_producto, cantidad y paga, con cantidad mayor a paga")
+		}
+		p.IDCategoria, p.Porcentaje, p.Minimo = nil, 0, 0
+	case TipoRegalo:
+		if p.IDProducto == nil || p.Minimo <= 0 || p.Minimo > dinero.Maximo {
+			return errors.New("una promoción de regalo lleva id_producto y un mínimo mayor a cero")
+		}
+		p.IDCategoria, p.Cantidad, p.Paga, p.Porcentaje = nil, 0, 0, 0
+	default:
+		return fmt.Errorf("tipo de promoción inválido: use %s", strings.Join(Tipos(), ", "))
+	}
+	return nil
+}
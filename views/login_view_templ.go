@@ -29,7 +29,7 @@ func LoginPage() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"es\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>Iniciar Sesión - Carrito</title><link href=\"https://cdn.jsdelivr.net/npm/bootstrap@5.0.2/dist/css/bootstrap.min.css\" rel=\"stylesheet\" xintegrity=\"sha384-EVSTQN3/azprG1Anm3QDgpJLIm9Nao0Yz1ztcQTwFspd3yD65VohhpuuCOmLASjC\" crossorigin=\"anonymous\"><link rel=\"stylesheet\" href=\"static/style.css\"><!-- IMPORTANTE: Agregamos HTMX aquí también --><script src=\"https://unpkg.com/htmx.org@1.9.10\" defer></script><!-- Script de Bootstrap para que funcione el botón de cerrar la alerta --><script src=\"https://cdn.jsdelivr.net/npm/bootstrap@5.0.2/dist/js/bootstrap.bundle.min.js\"></script><style>\n\t\t\tbody.login-page {\n\t\t\t\tbackground-color: #f8f9fa;\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tjustify-content: center;\n\t\t\t\theight: 100vh;\n\t\t\t}\n\t\t\t.login-card {\n\t\t\t\tbackground: white;\n\t\t\t\tpadding: 2rem;\n\t\t\t\tborder-radius: 10px;\n\t\t\t\tbox-shadow: 0 4px 6px rgba(0,0,0,0.1);\n\t\t\t\twidth: 100%;\n\t\t\t\tmax-width: 400px;\n\t\t\t}\n\t\t</style></head><body class=\"login-page\"><div class=\"login-card\"><div class=\"text-center mb-4\"><h2 class=\"fw-bold\">Carrito Web App</h2><p class=\"text-muted\">Bienvenido</p></div><div id=\"login-error\"></div><form hx-post=\"/login\" hx-target=\"#login-error\" hx-swap=\"innerHTML\"><div class=\"mb-3\"><label for=\"email\" class=\"form-label\">Correo Electrónico</label> <input type=\"email\" class=\"form-control\" id=\"email\" name=\"email\" placeholder=\"juanperez@ejemplo.com\" required></div><div class=\"mb-3\"><label for=\"text\" class=\"form-label\">Usuario</label> <input type=\"text\" class=\"form-control\" id=\"usuario\" name=\"usuario\" placeholder=\"Ej: JuanPerez\" required></div><div class=\"d-grid gap-2\"><button type=\"submit\" class=\"btn btn-primary\">Iniciar Sesión</button></div></form><div class=\"mt-3 text-center\"><span>¿No tienes cuenta? </span> <a href=\"/register\" class=\"text-decoration-none text-primary fw-bold\">Registrarse</a></div></div></body></html>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"es\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>Iniciar Sesión - Carrito</title><link href=\"https://cdn.jsdelivr.net/npm/bootstrap@5.0.2/dist/css/bootstrap.min.css\" rel=\"stylesheet\" xintegrity=\"sha384-EVSTQN3/azprG1Anm3QDgpJLIm9Nao0Yz1ztcQTwFspd3yD65VohhpuuCOmLASjC\" crossorigin=\"anonymous\"><link rel=\"stylesheet\" href=\"static/style.css\"><!-- IMPORTANTE: Agregamos HTMX aquí también --><script src=\"https://unpkg.com/htmx.org@1.9.10\" defer></script><!-- Script de Bootstrap para que funcione el botón de cerrar la alerta --><script src=\"https://cdn.jsdelivr.net/npm/bootstrap@5.0.2/dist/js/bootstrap.bundle.min.js\"></script><style>\n\t\t\tbody.login-page {\n\t\t\t\tbackground-color: #f8f9fa;\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tjustify-content: center;\n\t\t\t\theight: 100vh;\n\t\t\t}\n\t\t\t.login-card {\n\t\t\t\tbackground: white;\n\t\t\t\tpadding: 2rem;\n\t\t\t\tborder-radius: 10px;\n\t\t\t\tbox-shadow: 0 4px 6px rgba(0,0,0,0.1);\n\t\t\t\twidth: 100%;\n\t\t\t\tmax-width: 400px;\n\t\t\t}\n\t\t</style></head><body class=\"login-page\"><div class=\"login-card\"><div class=\"text-center mb-4\"><h2 class=\"fw-bold\">Carrito Web App</h2><p class=\"text-muted\">Bienvenido</p></div><div id=\"login-error\"></div><form hx-post=\"/login\" hx-target=\"#login-error\" hx-swap=\"innerHTML\"><div class=\"mb-3\"><label for=\"email\" class=\"form-label\">Correo Electrónico</label> <input type=\"email\" class=\"form-control\" id=\"email\" name=\"email\" placeholder=\"juanperez@ejemplo.com\" required></div><div class=\"mb-3\"><label for=\"password\" class=\"form-label\">Contraseña</label> <input type=\"password\" class=\"form-control\" id=\"password\" name=\"password\" autocomplete=\"current-password\" required></div><div class=\"d-grid gap-2\"><button type=\"submit\" class=\"btn btn-primary\">Iniciar Sesión</button></div></form><div class=\"mt-3 text-center\"><span>¿No tienes cuenta? </span> <a href=\"/register\" class=\"text-decoration-none text-primary fw-bold\">Registrarse</a></div></div></body></html>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}